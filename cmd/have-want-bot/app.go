@@ -2,12 +2,25 @@ package main
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "net/http/pprof"
 
 	"github.com/go-kit/kit/log/level"
 	"github.com/gsmcwhirter/discord-bot-lib/bot"
 	"github.com/gsmcwhirter/go-util/pprofsidecar"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/grpcapi"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/health"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/httpapi"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/metrics"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
 )
 
 type config struct {
@@ -24,10 +37,28 @@ type config struct {
 	PProfHostPort string `mapstructure:"pprof_hostport"`
 	Version       string `mapstructure:"-"`
 	NumWorkers    int    `mapstructure:"num_workers"`
+
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	OwnerIDs        []string      `mapstructure:"owner_ids"`
+
+	DefaultCommandIndicator string `mapstructure:"default_command_indicator"`
+	SuccessColor            int    `mapstructure:"success_color"`
+	ErrorColor              int    `mapstructure:"error_color"`
+
+	MessageRateInterval time.Duration `mapstructure:"message_rate_interval"`
+	MessageRateBurst    int           `mapstructure:"message_rate_burst"`
+	ConnectRateInterval time.Duration `mapstructure:"connect_rate_interval"`
+
+	APIHostPort  string   `mapstructure:"api_hostport"`
+	GRPCHostPort string   `mapstructure:"grpc_hostport"`
+	APITokens    []string `mapstructure:"api_tokens"`
+
+	WebHostPort string `mapstructure:"web_hostport"`
+	WebURL      string `mapstructure:"web_url"`
 }
 
-func start(c config) error {
-	deps, err := createDependencies(c)
+func start(c config, load configLoader) error {
+	deps, err := createDependencies(c, load)
 	if err != nil {
 		return err
 	}
@@ -50,15 +81,91 @@ func start(c config) error {
 	if err != nil {
 		return err
 	}
-	defer bot.Disconnect() // nolint: errcheck
 
+	sd := newShutdown(deps, c.ShutdownTimeout)
+	err = serve(c, deps, bot, sd)
+
+	if sd.requested() {
+		sd.flush()
+	}
+
+	bot.Disconnect() // nolint: errcheck
+	deps.Close()
+
+	if sd.requested() {
+		sd.logSummary()
+		return nil
+	}
+
+	_ = level.Error(deps.Logger()).Log("message", "error in start; quitting", "err", err)
+	return err
+}
+
+// serve runs the bot until it fails or a signal shuts it down
+func serve(c config, deps *dependencies, bot bot.DiscordBot, sd *shutdown) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	defer signal.Stop(hups)
+
+	deps.HealthMonitor().ConnectToBot(bot)
 	deps.MessageHandler().ConnectToBot(bot)
+	deps.Interactions().ConnectToBot(bot)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err = pprofsidecar.Run(ctx, c.PProfHostPort, nil, bot.Run)
+	err := deps.Interactions().RegisterCommands(ctx)
+	if err != nil {
+		_ = level.Error(deps.Logger()).Log("message", "could not register slash commands", "err", err)
+	}
 
-	_ = level.Error(deps.Logger()).Log("message", "error in start; quitting", "err", err)
-	return err
+	err = metrics.RegisterCounts(func() (int, int, int, error) { return storage.Counts(deps.UserAPI()) })
+	if err != nil {
+		return err
+	}
+	// the sidecar serves the default mux, alongside the pprof handlers
+	http.Handle("/metrics", metrics.Handler())
+	http.Handle("/healthz", deps.HealthMonitor().HealthzHandler())
+	http.Handle("/readyz", deps.HealthMonitor().ReadyzHandler())
+
+	// the http api and dashboard are optional, and served separately from the sidecar
+	var api http.Handler
+	if c.APIHostPort != "" {
+		api = httpapi.NewHandler(deps)
+	}
+
+	// the grpc service takes the same tokens as the http api
+	var rpc *grpc.Server
+	if c.GRPCHostPort != "" {
+		rpc = grpcapi.NewServer(deps)
+	}
+
+	if err = health.Notify("READY=1"); err != nil {
+		_ = level.Error(deps.Logger()).Log("message", "could not tell systemd the bot is ready", "err", err)
+	}
+
+	return pprofsidecar.Run(ctx, c.PProfHostPort, nil, func(ctx context.Context) error {
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error { return bot.Run(ctx) })
+		g.Go(func() error { return deps.Scheduler().Run(ctx) })
+		g.Go(func() error { return deps.Notifier().Run(ctx) })
+		g.Go(func() error { return deps.Webhooks().Run(ctx) })
+		g.Go(func() error { return health.RunWatchdog(ctx, deps.HealthMonitor(), deps.Logger()) })
+		g.Go(func() error { return sd.wait(ctx, sigs, cancel) })
+		g.Go(func() error { return reloadOnHangup(ctx, deps.reloader, hups) })
+		if api != nil {
+			g.Go(func() error { return serveHTTP(ctx, deps, "http api", c.APIHostPort, api) })
+		}
+		if rpc != nil {
+			g.Go(func() error { return serveGRPC(ctx, deps, c.GRPCHostPort, rpc) })
+		}
+		if deps.web != nil {
+			g.Go(func() error { return serveHTTP(ctx, deps, "web dashboard", c.WebHostPort, deps.web) })
+		}
+		return g.Wait()
+	})
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/gsmcwhirter/go-util/cli"
 )
 
-func setup(start func(config) error) *cli.Command {
+func setup(start func(config, configLoader) error) *cli.Command {
 	c := cli.NewCLI(AppName, BuildVersion, BuildSHA, BuildDate, cli.CommandOptions{
 		ShortHelp:    "Manage the discord bot",
 		Args:         cli.NoArgs,
@@ -25,11 +25,23 @@ func setup(start func(config) error) *cli.Command {
 	c.Flags().String("log_level", "", "The minimum log level to show")
 	c.Flags().Int("num_workers", 0, "The number of worker goroutines to run")
 	c.Flags().String("pprof_hostport", "", "The host and port for the pprof http server to listen on")
+	c.Flags().String("api_hostport", "", "The host and port for the http api to listen on (empty to disable it)")
+	c.Flags().String("grpc_hostport", "", "The host and port for the grpc service to listen on (empty to disable it)")
+	c.Flags().String("web_hostport", "", "The host and port for the web dashboard to listen on (empty to disable it)")
+	c.Flags().String("web_url", "", "The address the web dashboard is reached at, for login links (defaults to http://[web_hostport])")
+	c.Flags().Duration("shutdown_timeout", 0, "How long to wait for running commands to finish when shutting down")
 
 	c.SetRunFunc(func(cmd *cli.Command, args []string) (err error) {
 		v := viper.New()
 
 		v.SetDefault("pprof_hostport", "127.0.0.1:6060")
+		v.SetDefault("shutdown_timeout", "30s")
+		v.SetDefault("default_command_indicator", "!")
+		v.SetDefault("success_color", 0x62aa00)
+		v.SetDefault("error_color", 0xff0000)
+		v.SetDefault("message_rate_interval", "60s")
+		v.SetDefault("message_rate_burst", 120)
+		v.SetDefault("connect_rate_interval", "5s")
 
 		if configFile != "" {
 			v.SetConfigFile(configFile)
@@ -46,20 +58,28 @@ func setup(start func(config) error) *cli.Command {
 			return errors.Wrap(err, "could not bind flags to viper")
 		}
 
-		err = v.ReadInConfig()
-		if err != nil {
-			return errors.Wrap(err, "could not read in config file")
+		// load is kept for reloading the config file while running
+		load := func() (conf config, err error) {
+			err = v.ReadInConfig()
+			if err != nil {
+				return conf, errors.Wrap(err, "could not read in config file")
+			}
+
+			err = v.Unmarshal(&conf)
+			if err != nil {
+				return conf, errors.Wrap(err, "could not unmarshal config into struct")
+			}
+
+			conf.Version = cmd.Version
+			return conf, nil
 		}
 
-		conf := config{}
-		err = v.Unmarshal(&conf)
+		conf, err := load()
 		if err != nil {
-			return errors.Wrap(err, "could not unmarshal config into struct")
+			return err
 		}
 
-		conf.Version = cmd.Version
-
-		return start(conf)
+		return start(conf, load)
 	})
 
 	return c
@@ -3,12 +3,11 @@ package main
 import (
 	"fmt"
 	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	bolt "github.com/coreos/bbolt"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/websocket"
 	"github.com/gsmcwhirter/discord-bot-lib/bot"
 	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
@@ -18,17 +17,32 @@ import (
 	"github.com/gsmcwhirter/discord-bot-lib/wsclient"
 	"golang.org/x/time/rate"
 
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/apiservice"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/commands"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/dm"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/health"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/inflight"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/interactions"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/members"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/msghandler"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/notify"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/scheduler"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/web"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
 )
 
 type dependencies struct {
-	logger log.Logger
+	logger  log.Logger
+	logSwap *swapLogger
 
 	db       *bolt.DB
 	userAPI  storage.UserAPI
 	guildAPI storage.GuildAPI
+	bankAPI  storage.BankAPI
+	lootAPI  storage.LootAPI
+	schedAPI storage.ScheduleAPI
+	hookAPI  storage.WebhookAPI
 
 	httpDoer   httpclient.Doer
 	httpClient httpclient.HTTPClient
@@ -43,45 +57,61 @@ type dependencies struct {
 	configHandler     *cmdhandler.CommandHandler
 	discordMsgHandler bot.DiscordMessageHandler
 	msgHandlers       msghandler.Handlers
+	interactions      interactions.Handlers
+
+	dmSender  dm.Sender
+	members   members.Lookup
+	scheduler scheduler.Scheduler
+	notifier  notify.Notifier
+	webhooks  webhook.Dispatcher
+	health    health.Monitor
+	inFlight  inflight.Tracker
+	reloader  *reloader
+	web       web.Dashboard
+	api       apiservice.Service
+
+	closeOnce sync.Once
 }
 
-func createDependencies(conf config) (d *dependencies, err error) {
+func createDependencies(conf config, load configLoader) (d *dependencies, err error) {
 	d = &dependencies{}
 
-	var logger log.Logger
-	if conf.LogFormat == "json" {
-		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
-	} else {
-		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+	// the level and format can be reloaded, so the caller is added above the swap
+	d.logSwap = newSwapLogger(newBaseLogger(conf))
+	d.logger = log.With(d.logSwap, "timestamp", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+	d.reloader = newReloader(d, conf, load)
+
+	d.db, err = bolt.Open(conf.Database, 0660, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return
 	}
 
-	switch conf.LogLevel {
-	case "debug":
-		logger = level.NewFilter(logger, level.AllowDebug())
-	case "info":
-		logger = level.NewFilter(logger, level.AllowInfo())
-	case "warn":
-		logger = level.NewFilter(logger, level.AllowWarn())
-	case "error":
-		logger = level.NewFilter(logger, level.AllowError())
-	default:
-		logger = level.NewFilter(logger, level.AllowAll())
+	d.userAPI, err = storage.NewBoltUserAPI(d.db)
+	if err != nil {
+		return
+	}
+
+	d.guildAPI, err = storage.NewBoltGuildAPI(d.db)
+	if err != nil {
+		return
 	}
 
-	logger = log.With(logger, "timestamp", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
-	d.logger = logger
+	d.bankAPI, err = storage.NewBoltBankAPI(d.db)
+	if err != nil {
+		return
+	}
 
-	d.db, err = bolt.Open(conf.Database, 0660, &bolt.Options{Timeout: 1 * time.Second})
+	d.lootAPI, err = storage.NewBoltLootAPI(d.db)
 	if err != nil {
 		return
 	}
 
-	d.userAPI, err = storage.NewBoltUserAPI(d.db)
+	d.schedAPI, err = storage.NewBoltScheduleAPI(d.db)
 	if err != nil {
 		return
 	}
 
-	d.guildAPI, err = storage.NewBoltGuildAPI(d.db)
+	d.hookAPI, err = storage.NewBoltWebhookAPI(d.db)
 	if err != nil {
 		return
 	}
@@ -93,9 +123,15 @@ func createDependencies(conf config) (d *dependencies, err error) {
 	h.Add("Authorization", fmt.Sprintf("Bot %s", conf.ClientToken))
 	d.httpClient.SetHeaders(h)
 
-	d.wsDialer = wsclient.WrapDialer(websocket.DefaultDialer)
+	d.health = health.NewMonitor(d, health.Options{
+		HeartbeatMaxAge: 2 * time.Minute,
+		StorageTimeout:  5 * time.Second,
+	})
+
+	d.wsDialer = d.health.WrapDialer(wsclient.WrapDialer(websocket.DefaultDialer))
 	d.wsClient = wsclient.NewWSClient(d, wsclient.Options{MaxConcurrentHandlers: conf.NumWorkers})
 
+	d.inFlight = inflight.NewTracker()
 	d.discordMsgHandler = messagehandler.NewDiscordMessageHandler(d)
 	d.botSession = etfapi.NewSession()
 
@@ -108,31 +144,121 @@ func createDependencies(conf config) (d *dependencies, err error) {
 		return
 	}
 
-	d.connectRateLimiter = rate.NewLimiter(rate.Every(5*time.Second), 1)
-	d.messageRateLimiter = rate.NewLimiter(rate.Every(60*time.Second), 120)
+	d.connectRateLimiter = rate.NewLimiter(rate.Every(conf.ConnectRateInterval), 1)
+	d.messageRateLimiter = rate.NewLimiter(rate.Every(conf.MessageRateInterval), conf.MessageRateBurst)
+
+	d.msgHandlers = msghandler.NewHandlers(d, msghandlerOptions(conf))
+	d.interactions = interactions.NewHandlers(d, interactionsOptions(conf))
 
-	d.msgHandlers = msghandler.NewHandlers(d, msghandler.Options{
-		DefaultCommandIndicator: "!",
-		ErrorColor:              0xff0000,
-		SuccessColor:            0x62aa00,
+	d.dmSender = dm.NewSender(d, dm.Options{APIURL: conf.DiscordAPI})
+	d.members = members.NewLookup(d, members.Options{APIURL: conf.DiscordAPI})
+	d.scheduler = scheduler.NewScheduler(d, schedulerOptions(conf))
+	d.notifier = notify.NewNotifier(d, notifyOptions(conf))
+	d.webhooks = webhook.NewDispatcher(d, webhook.Options{
+		PollInterval: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  10,
+		RetryBase:    30 * time.Second,
+		RetryMax:     6 * time.Hour,
 	})
 
+	if conf.WebHostPort != "" {
+		d.web, err = web.NewDashboard(d, webOptions(conf))
+		if err != nil {
+			return
+		}
+	}
+
+	// the http api and grpc service share the service and its tokens
+	if conf.APIHostPort != "" || conf.GRPCHostPort != "" {
+		d.api, err = apiservice.NewService(d, apiservice.Options{Tokens: conf.APITokens})
+		if err != nil {
+			return
+		}
+	}
+
 	return
 }
 
-func (d *dependencies) Close() {
-	if d.db != nil {
-		d.db.Close() // nolint: errcheck
+func msghandlerOptions(conf config) msghandler.Options {
+	return msghandler.Options{
+		DefaultCommandIndicator: conf.DefaultCommandIndicator,
+		ErrorColor:              conf.ErrorColor,
+		SuccessColor:            conf.SuccessColor,
 	}
+}
 
-	if d.wsClient != nil {
-		d.wsClient.Close()
+func interactionsOptions(conf config) interactions.Options {
+	return interactions.Options{
+		APIURL:        conf.DiscordAPI,
+		ApplicationID: conf.ClientID,
+		ErrorColor:    conf.ErrorColor,
+		SuccessColor:  conf.SuccessColor,
 	}
 }
 
+func schedulerOptions(conf config) scheduler.Options {
+	return scheduler.Options{
+		TickInterval: time.Minute,
+		SuccessColor: conf.SuccessColor,
+	}
+}
+
+func notifyOptions(conf config) notify.Options {
+	return notify.Options{
+		QueueSize:    100,
+		Throttle:     24 * time.Hour,
+		SuccessColor: conf.SuccessColor,
+	}
+}
+
+func webOptions(conf config) web.Options {
+	baseURL := conf.WebURL
+	if baseURL == "" {
+		baseURL = "http://" + conf.WebHostPort
+	}
+
+	return web.Options{
+		BaseURL:    baseURL,
+		LinkTTL:    15 * time.Minute,
+		SessionTTL: 12 * time.Hour,
+	}
+}
+
+// applyLive applies the settings that can change while running (see liveSettings)
+func (d *dependencies) applyLive(conf config) {
+	d.logSwap.Swap(newBaseLogger(conf))
+
+	d.connectRateLimiter.SetLimit(rate.Every(conf.ConnectRateInterval))
+	d.messageRateLimiter.SetLimit(rate.Every(conf.MessageRateInterval))
+	d.messageRateLimiter.SetBurst(conf.MessageRateBurst)
+
+	d.msgHandlers.SetOptions(msghandlerOptions(conf))
+	d.interactions.SetOptions(interactionsOptions(conf))
+	d.scheduler.SetOptions(schedulerOptions(conf))
+	d.notifier.SetOptions(notifyOptions(conf))
+}
+
+// Close closes the database and websocket client; it is safe to call more than once
+func (d *dependencies) Close() {
+	d.closeOnce.Do(func() {
+		if d.db != nil {
+			d.db.Close() // nolint: errcheck
+		}
+
+		if d.wsClient != nil {
+			d.wsClient.Close()
+		}
+	})
+}
+
 func (d *dependencies) Logger() log.Logger                         { return d.logger }
 func (d *dependencies) GuildAPI() storage.GuildAPI                 { return d.guildAPI }
 func (d *dependencies) UserAPI() storage.UserAPI                   { return d.userAPI }
+func (d *dependencies) BankAPI() storage.BankAPI                   { return d.bankAPI }
+func (d *dependencies) LootAPI() storage.LootAPI                   { return d.lootAPI }
+func (d *dependencies) ScheduleAPI() storage.ScheduleAPI           { return d.schedAPI }
+func (d *dependencies) WebhookAPI() storage.WebhookAPI             { return d.hookAPI }
 func (d *dependencies) HTTPDoer() httpclient.Doer                  { return d.httpDoer }
 func (d *dependencies) HTTPClient() httpclient.HTTPClient          { return d.httpClient }
 func (d *dependencies) WSDialer() wsclient.Dialer                  { return d.wsDialer }
@@ -143,6 +269,22 @@ func (d *dependencies) BotSession() *etfapi.Session                { return d.bo
 func (d *dependencies) CommandHandler() *cmdhandler.CommandHandler { return d.cmdHandler }
 func (d *dependencies) ConfigHandler() *cmdhandler.CommandHandler  { return d.configHandler }
 func (d *dependencies) MessageHandler() msghandler.Handlers        { return d.msgHandlers }
+func (d *dependencies) Interactions() interactions.Handlers        { return d.interactions }
+func (d *dependencies) DMSender() dm.Sender                        { return d.dmSender }
+func (d *dependencies) Members() members.Lookup                    { return d.members }
+func (d *dependencies) Scheduler() scheduler.Scheduler             { return d.scheduler }
+func (d *dependencies) Notifier() notify.Notifier                  { return d.notifier }
+func (d *dependencies) Webhooks() webhook.Dispatcher               { return d.webhooks }
+func (d *dependencies) HealthMonitor() health.Monitor              { return d.health }
+func (d *dependencies) InFlight() inflight.Tracker                 { return d.inFlight }
+func (d *dependencies) Reloader() msghandler.Reloader              { return d.reloader }
+func (d *dependencies) APIService() apiservice.Service             { return d.api }
+func (d *dependencies) Dashboard() msghandler.LoginLinker {
+	if d.web == nil {
+		return nil // keep the interface nil when the dashboard is off
+	}
+	return d.web
+}
 func (d *dependencies) DiscordMessageHandler() bot.DiscordMessageHandler {
 	return d.discordMsgHandler
 }
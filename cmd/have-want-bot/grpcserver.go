@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// serveGRPC serves the grpc service on addr until ctx is done, then lets the
+// calls in progress finish (up to a deadline) before returning, so storage is
+// not closed under them
+func serveGRPC(ctx context.Context, deps *dependencies, addr string, srv *grpc.Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "could not listen for grpc")
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_ = level.Info(deps.Logger()).Log("message", "serving the grpc service", "hostport", addr)
+		errs <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, "grpc server failed")
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		_ = level.Error(deps.Logger()).Log("message", "could not shut down the grpc service cleanly")
+		srv.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// serveHTTP serves h (the http api or the web dashboard) on addr until ctx is
+// done, then lets the requests in progress finish (they each hold a storage
+// transaction) before returning, so storage is not closed under them
+func serveHTTP(ctx context.Context, deps *dependencies, what, addr string, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_ = level.Info(deps.Logger()).Log("message", "serving the "+what, "hostport", addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, what+" server failed")
+	case <-ctx.Done():
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(sctx); err != nil {
+		_ = level.Error(deps.Logger()).Log("message", "could not shut down the "+what+" cleanly", "err", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// newBaseLogger creates the logger for the configured format and level
+func newBaseLogger(conf config) log.Logger {
+	var logger log.Logger
+	if conf.LogFormat == "json" {
+		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
+	} else {
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+	}
+
+	switch conf.LogLevel {
+	case "debug":
+		logger = level.NewFilter(logger, level.AllowDebug())
+	case "info":
+		logger = level.NewFilter(logger, level.AllowInfo())
+	case "warn":
+		logger = level.NewFilter(logger, level.AllowWarn())
+	case "error":
+		logger = level.NewFilter(logger, level.AllowError())
+	default:
+		logger = level.NewFilter(logger, level.AllowAll())
+	}
+
+	return logger
+}
+
+// swapLogger is a log.Logger that can be pointed at a new logger (say, with a
+// different level) while running
+type swapLogger struct {
+	current atomic.Value // holds a loggerBox
+}
+
+// loggerBox keeps the concrete type stored in the atomic.Value the same
+type loggerBox struct {
+	log.Logger
+}
+
+func newSwapLogger(logger log.Logger) *swapLogger {
+	l := &swapLogger{}
+	l.Swap(logger)
+	return l
+}
+
+// Swap sends everything logged from now on to logger
+func (l *swapLogger) Swap(logger log.Logger) {
+	l.current.Store(loggerBox{logger})
+}
+
+func (l *swapLogger) Log(keyvals ...interface{}) error {
+	return l.current.Load().(loggerBox).Log(keyvals...)
+}
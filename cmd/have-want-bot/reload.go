@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/pkg/errors"
+)
+
+// configLoader reads the configuration (file, environment and flags) afresh
+type configLoader func() (config, error)
+
+// liveSettings are the settings a reload applies while the bot runs; a change
+// to any other setting only takes effect after a restart
+var liveSettings = map[string]bool{
+	"log_format":                true,
+	"log_level":                 true,
+	"message_rate_interval":     true,
+	"message_rate_burst":        true,
+	"connect_rate_interval":     true,
+	"default_command_indicator": true,
+	"success_color":             true,
+	"error_color":               true,
+	"owner_ids":                 true,
+}
+
+// reloader re-reads the configuration on SIGHUP or a bot owner's command
+type reloader struct {
+	deps *dependencies
+	load configLoader
+
+	mu   sync.Mutex
+	conf config
+
+	ownersLock sync.RWMutex
+	owners     map[string]bool
+}
+
+func newReloader(deps *dependencies, conf config, load configLoader) *reloader {
+	r := &reloader{
+		deps: deps,
+		load: load,
+		conf: conf,
+	}
+	r.setOwners(conf.OwnerIDs)
+
+	return r
+}
+
+func (r *reloader) setOwners(ids []string) {
+	owners := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		owners[id] = true
+	}
+
+	r.ownersLock.Lock()
+	defer r.ownersLock.Unlock()
+
+	r.owners = owners
+}
+
+// IsOwner reports whether the user is one of the configured bot owners
+func (r *reloader) IsOwner(uid snowflake.Snowflake) bool {
+	r.ownersLock.RLock()
+	defer r.ownersLock.RUnlock()
+
+	return r.owners[uid.ToString()]
+}
+
+// Reload re-reads the configuration and applies the live settings. It returns
+// the changes it applied and the names of the changed settings that need a
+// restart (those keep their old values until then).
+func (r *reloader) Reload() (applied, restart []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conf, err := r.load()
+	if err != nil {
+		_ = level.Error(r.deps.Logger()).Log("message", "could not reload the configuration", "err", err)
+		return nil, nil, errors.Wrap(err, "could not reload the configuration")
+	}
+
+	applied, restart = configChanges(r.conf, conf)
+
+	r.deps.applyLive(conf)
+	r.setOwners(conf.OwnerIDs)
+
+	// remember what is running: the new live settings, but the old restart ones
+	r.conf = withLiveSettings(r.conf, conf)
+
+	_ = level.Info(r.deps.Logger()).Log("message", "reloaded the configuration", "applied", strings.Join(applied, "; "), "needs_restart", strings.Join(restart, ", "))
+	return applied, restart, nil
+}
+
+// configChanges lists the settings that differ between old and new: the live
+// ones with their old and new values, and the rest by name only (some of them
+// are secrets)
+func configChanges(old, new config) (applied, restart []string) {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	t := ov.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		a, b := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		if !liveSettings[name] {
+			restart = append(restart, name)
+			continue
+		}
+
+		if strings.HasSuffix(name, "_color") {
+			applied = append(applied, fmt.Sprintf("%s: %#06x -> %#06x", name, a, b))
+		} else {
+			applied = append(applied, fmt.Sprintf("%s: %v -> %v", name, a, b))
+		}
+	}
+
+	return applied, restart
+}
+
+// withLiveSettings copies the live settings from new onto running
+func withLiveSettings(running, new config) config {
+	rv, nv := reflect.ValueOf(&running).Elem(), reflect.ValueOf(new)
+	t := rv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if liveSettings[t.Field(i).Tag.Get("mapstructure")] {
+			rv.Field(i).Set(nv.Field(i))
+		}
+	}
+
+	return running
+}
+
+// reloadOnHangup reloads the configuration whenever a SIGHUP arrives, until ctx is done
+func reloadOnHangup(ctx context.Context, r *reloader, hups <-chan os.Signal) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hups:
+			_, _, _ = r.Reload() // the outcome is logged
+		}
+	}
+}
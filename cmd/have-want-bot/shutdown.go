@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/health"
+)
+
+// shutdown stops the bot gracefully on a signal: it stops taking new
+// messages, waits (up to the timeout) for the in-flight handlers, and then
+// flushes queued notifications before storage is closed
+type shutdown struct {
+	deps    *dependencies
+	timeout time.Duration
+
+	mu        sync.Mutex
+	signal    os.Signal
+	began     time.Time
+	ctx       context.Context
+	cancel    context.CancelFunc
+	waited    int
+	abandoned int
+	sent      int
+	unsent    int
+}
+
+func newShutdown(deps *dependencies, timeout time.Duration) *shutdown {
+	return &shutdown{
+		deps:    deps,
+		timeout: timeout,
+	}
+}
+
+// requested reports whether a signal started the shutdown
+func (s *shutdown) requested() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.signal != nil
+}
+
+// wait blocks until a signal arrives (or ctx is done). On a signal it drains
+// the in-flight work while the bot is still connected, so replies can go out,
+// and then calls stop to end the run.
+func (s *shutdown) wait(ctx context.Context, sigs <-chan os.Signal, stop context.CancelFunc) error {
+	var sig os.Signal
+	select {
+	case <-ctx.Done():
+		return nil
+	case sig = <-sigs:
+	}
+
+	logger := s.deps.Logger()
+	_ = level.Info(logger).Log("message", "received signal; shutting down", "signal", sig.String(), "timeout", s.timeout)
+
+	s.mu.Lock()
+	s.signal = sig
+	s.began = time.Now()
+	s.ctx, s.cancel = context.WithTimeout(context.Background(), s.timeout)
+	s.mu.Unlock()
+
+	s.deps.HealthMonitor().SetAuthenticated(false)
+	if err := health.Notify("STOPPING=1"); err != nil {
+		_ = level.Error(logger).Log("message", "could not tell systemd the bot is stopping", "err", err)
+	}
+
+	waited, abandoned := s.deps.InFlight().Drain(s.ctx)
+
+	s.mu.Lock()
+	s.waited, s.abandoned = waited, abandoned
+	s.mu.Unlock()
+
+	if abandoned > 0 {
+		_ = level.Warn(logger).Log("message", "shutdown deadline passed with handlers still running", "abandoned", abandoned)
+	}
+
+	stop()
+	return nil
+}
+
+// flush delivers the notifications still queued, within what is left of the deadline
+func (s *shutdown) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent, s.unsent = s.deps.Notifier().Flush(s.ctx)
+}
+
+// logSummary logs what the shutdown did; it should be called once storage is closed
+func (s *shutdown) logSummary() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	_ = level.Info(s.deps.Logger()).Log(
+		"message", "shutdown complete",
+		"signal", s.signal.String(),
+		"in_flight", s.waited,
+		"abandoned", s.abandoned,
+		"dropped", s.deps.InFlight().Dropped(),
+		"notifications_sent", s.sent,
+		"notifications_unsent", s.unsent,
+		"took", time.Since(s.began),
+	)
+}
@@ -7,13 +7,21 @@ import (
 	bolt "github.com/coreos/bbolt"
 	"github.com/go-kit/kit/log"
 
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/notify"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
 )
 
 type dependencies struct {
-	logger  log.Logger
-	db      *bolt.DB
-	userAPI storage.UserAPI
+	logger   log.Logger
+	db       *bolt.DB
+	userAPI  storage.UserAPI
+	guildAPI storage.GuildAPI
+	bankAPI  storage.BankAPI
+	lootAPI  storage.LootAPI
+	schedAPI storage.ScheduleAPI
+	notifier notify.Notifier
+	webhooks webhook.Dispatcher
 }
 
 func createDependencies(conf config) (d *dependencies, err error) {
@@ -32,6 +40,29 @@ func createDependencies(conf config) (d *dependencies, err error) {
 		return
 	}
 
+	d.guildAPI, err = storage.NewBoltGuildAPI(d.db)
+	if err != nil {
+		return
+	}
+
+	d.bankAPI, err = storage.NewBoltBankAPI(d.db)
+	if err != nil {
+		return
+	}
+
+	d.lootAPI, err = storage.NewBoltLootAPI(d.db)
+	if err != nil {
+		return
+	}
+
+	d.schedAPI, err = storage.NewBoltScheduleAPI(d.db)
+	if err != nil {
+		return
+	}
+
+	d.notifier = notify.NewNopNotifier()
+	d.webhooks = webhook.NewNopDispatcher()
+
 	return
 }
 
@@ -48,3 +79,27 @@ func (d *dependencies) Logger() log.Logger {
 func (d *dependencies) UserAPI() storage.UserAPI {
 	return d.userAPI
 }
+
+func (d *dependencies) GuildAPI() storage.GuildAPI {
+	return d.guildAPI
+}
+
+func (d *dependencies) BankAPI() storage.BankAPI {
+	return d.bankAPI
+}
+
+func (d *dependencies) LootAPI() storage.LootAPI {
+	return d.lootAPI
+}
+
+func (d *dependencies) ScheduleAPI() storage.ScheduleAPI {
+	return d.schedAPI
+}
+
+func (d *dependencies) Notifier() notify.Notifier {
+	return d.notifier
+}
+
+func (d *dependencies) Webhooks() webhook.Dispatcher {
+	return d.webhooks
+}
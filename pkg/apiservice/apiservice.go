@@ -0,0 +1,381 @@
+// Package apiservice is the storage operations behind the http api and the
+// grpc service, and the bearer tokens that both of them accept. The
+// transports only decode requests and encode what the service returns.
+package apiservice
+
+import (
+	"crypto/subtle"
+	"sort"
+	"strings"
+
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// ErrNoTokens is the error returned when the service is set up without any tokens to accept
+var ErrNoTokens = errors.New("the api needs at least one token")
+
+// ErrInvalidRequest is the error returned (wrapped with the reason) for missing or bad input
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrCharacterExists is the error returned when creating a character the user already has
+var ErrCharacterExists = errors.New("character already exists")
+
+// The kinds of need that SetNeed can set
+const (
+	NeedItem      = "item"
+	NeedSkill     = "skill"
+	NeedTransmute = "transmute"
+)
+
+type dependencies interface {
+	UserAPI() storage.UserAPI
+	GuildAPI() storage.GuildAPI
+	Webhooks() webhook.Dispatcher
+}
+
+// Service is the api over users, characters and guild settings. Each call runs
+// in a single storage transaction.
+type Service interface {
+	// Authorized reports whether an Authorization header value is a bearer
+	// token the service accepts
+	Authorized(authorization string) bool
+
+	// ListUsers lists the users, by id; a guild restricts it to that guild's members
+	ListUsers(guild string) ([]storage.User, error)
+	GetUser(userID string) (storage.User, error)
+	GetCharacter(userID, charName string) (storage.User, storage.Character, error)
+
+	// CreateCharacter adds a character, creating the user if need be, like `!char create`
+	CreateCharacter(userID, charName string) (storage.User, storage.Character, error)
+	DeleteCharacter(userID, charName string) error
+
+	// SetNeed sets how many of an item, skill point or transmute a character
+	// needs; a count of 0 removes the need
+	SetNeed(userID, charName, kind, name string, count uint64) (storage.User, storage.Character, error)
+
+	// GetGuild returns the guild record; a guild the bot has no record of has no settings set
+	GetGuild(guildID string) (storage.Guild, error)
+
+	// SetGuildSetting sets a guild setting, like `!config-hw set`
+	SetGuildSetting(guildID, name, value string) (storage.Guild, error)
+
+	// ResetGuildSetting returns a guild setting to its default
+	ResetGuildSetting(guildID, name string) (storage.Guild, error)
+}
+
+// Options is how to set the tokens the service accepts
+type Options struct {
+	Tokens []string
+}
+
+type service struct {
+	deps   dependencies
+	tokens [][]byte
+}
+
+// NewService creates a new Service
+func NewService(deps dependencies, opts Options) (Service, error) {
+	s := &service{deps: deps}
+	for _, t := range opts.Tokens {
+		if t != "" {
+			s.tokens = append(s.tokens, []byte(t))
+		}
+	}
+
+	if len(s.tokens) == 0 {
+		return nil, ErrNoTokens
+	}
+
+	return s, nil
+}
+
+func (s *service) Authorized(authorization string) bool {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(authorization, "Bearer "))
+
+	ok := false
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare(t, token) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func required(name, val string) error {
+	if strings.TrimSpace(val) == "" {
+		return errors.Wrap(ErrInvalidRequest, "missing "+name)
+	}
+	return nil
+}
+
+func (s *service) ListUsers(guild string) ([]storage.User, error) {
+	t, err := s.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	users := []storage.User{}
+	for _, u := range t.GetUsers() {
+		if guild != "" && !u.InGuild(guild) {
+			continue
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].GetName() < users[j].GetName() })
+
+	return users, nil
+}
+
+func (s *service) GetUser(userID string) (storage.User, error) {
+	t, err := s.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	return t.GetUser(userID)
+}
+
+func (s *service) GetCharacter(userID, charName string) (storage.User, storage.Character, error) {
+	bUser, err := s.GetUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	char, err := bUser.GetCharacter(charName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bUser, char, nil
+}
+
+func (s *service) CreateCharacter(userID, charName string) (storage.User, storage.Character, error) {
+	charName = strings.TrimSpace(charName)
+	if err := required("user", userID); err != nil {
+		return nil, nil, err
+	}
+	if err := required("character name", charName); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := s.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(userID) // add or get
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not create character")
+	}
+
+	_, err = bUser.GetCharacter(charName)
+	if err != storage.ErrCharacterNotExist {
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not verify character does not exist")
+		}
+
+		return nil, nil, ErrCharacterExists
+	}
+
+	char := bUser.AddCharacter(charName)
+	if err = t.SaveUser(bUser); err != nil {
+		return nil, nil, errors.Wrap(err, "could not save new character")
+	}
+
+	if err = t.Commit(); err != nil {
+		return nil, nil, errors.Wrap(err, "could not save new character")
+	}
+	s.publish(bUser, char, webhook.EventCharacterCreated, webhook.CharacterData{User: bUser.GetName(), Character: char.GetName()})
+
+	return bUser, char, nil
+}
+
+func (s *service) DeleteCharacter(userID, charName string) error {
+	t, err := s.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	char, err := bUser.GetCharacter(charName)
+	if err != nil {
+		return err
+	}
+
+	bUser.DeleteCharacter(char.GetName())
+	if err = t.SaveUser(bUser); err != nil {
+		return errors.Wrap(err, "could not delete character")
+	}
+
+	if err = t.Commit(); err != nil {
+		return errors.Wrap(err, "could not delete character")
+	}
+
+	return nil
+}
+
+func (s *service) SetNeed(userID, charName, kind, name string, count uint64) (storage.User, storage.Character, error) {
+	name = strings.TrimSpace(name)
+	if err := required("need name", name); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := s.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	char, err := bUser.GetCharacter(charName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := neededCount(char, kind, name)
+
+	switch kind {
+	case NeedItem:
+		char.SetNeededItem(name, count)
+	case NeedSkill:
+		char.SetNeededSkill(name, count)
+	case NeedTransmute:
+		char.SetNeededTransmute(name, count)
+	default:
+		return nil, nil, errors.Wrap(ErrInvalidRequest, "unknown kind of need")
+	}
+
+	if err = t.SaveUser(bUser); err != nil {
+		return nil, nil, errors.Wrap(err, "could not save needs")
+	}
+
+	if err = t.Commit(); err != nil {
+		return nil, nil, errors.Wrap(err, "could not save needs")
+	}
+
+	if count > before {
+		s.publish(bUser, char, webhook.EventNeedAdded, webhook.NeedData{
+			User:      bUser.GetName(),
+			Character: char.GetName(),
+			Kind:      kind,
+			Name:      name,
+			Count:     count - before,
+		})
+	}
+
+	return bUser, char, nil
+}
+
+// neededCount is how much of a need a character has now
+func neededCount(char storage.Character, kind, name string) uint64 {
+	switch kind {
+	case NeedItem:
+		if item, err := char.GetNeededItem(name); err == nil {
+			return item.Count()
+		}
+	case NeedSkill:
+		if skill, err := char.GetNeededSkill(name); err == nil {
+			return skill.Points()
+		}
+	case NeedTransmute:
+		if trans, err := char.GetNeededTransmute(name); err == nil {
+			return trans.Count()
+		}
+	}
+	return 0
+}
+
+// publish sends a webhook event about a character to each of the user's
+// guilds that can see it; the api has no guild of its own to send it to
+func (s *service) publish(bUser storage.User, char storage.Character, event string, data interface{}) {
+	for _, guild := range bUser.GetGuilds() {
+		if !storage.CanViewCharacter(bUser, char, guild) {
+			continue
+		}
+
+		gid, err := snowflake.FromString(guild)
+		if err != nil {
+			continue
+		}
+		s.deps.Webhooks().Publish(gid, event, data)
+	}
+}
+
+func (s *service) GetGuild(guildID string) (storage.Guild, error) {
+	if err := required("guild", guildID); err != nil {
+		return nil, err
+	}
+
+	t, err := s.deps.GuildAPI().NewTransaction(false)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	return t.AddGuild(guildID) // get or empty (not saved)
+}
+
+func (s *service) SetGuildSetting(guildID, name, value string) (storage.Guild, error) {
+	return s.changeSettings(guildID, func(settings *storage.GuildSettings) error {
+		return settings.Set(name, value)
+	})
+}
+
+func (s *service) ResetGuildSetting(guildID, name string) (storage.Guild, error) {
+	return s.changeSettings(guildID, func(settings *storage.GuildSettings) error {
+		return settings.Reset(name)
+	})
+}
+
+func (s *service) changeSettings(guildID string, change func(settings *storage.GuildSettings) error) (storage.Guild, error) {
+	if err := required("guild", guildID); err != nil {
+		return nil, err
+	}
+
+	t, err := s.deps.GuildAPI().NewTransaction(true)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bGuild, err := t.AddGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := bGuild.GetSettings()
+	if err = change(&settings); err != nil {
+		return nil, err
+	}
+	bGuild.SetSettings(settings)
+
+	if err = t.SaveGuild(bGuild); err != nil {
+		return nil, errors.Wrap(err, "could not save guild settings")
+	}
+
+	if err = t.Commit(); err != nil {
+		return nil, errors.Wrap(err, "could not save guild settings")
+	}
+
+	return bGuild, nil
+}
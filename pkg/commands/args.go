@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnterminatedQuote is the error returned when a quoted argument is not closed
+var ErrUnterminatedQuote = errors.New("unterminated quote in arguments")
+
+// ErrBadFlag is the error returned when a flag value cannot be understood
+var ErrBadFlag = errors.New("bad flag value")
+
+// switchFlags are the flags that take no value
+var switchFlags = map[string]bool{
+	"confirm": true,
+	"surplus": true,
+}
+
+// argToken is one word of a command's arguments
+type argToken struct {
+	text   string
+	quoted bool
+	start  int
+	end    int
+}
+
+// cmdArgs is a command's arguments, split into positional words and --name flags
+type cmdArgs struct {
+	raw   string
+	words []argToken
+	flags map[string]string
+
+	// spans of the raw text taken up by flags and their values
+	flagSpans [][2]int
+}
+
+func isQuote(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// tokenize splits s into words at whitespace. Double quotes group words
+// (including spaces) into one, and a backslash takes the next character
+// literally, so `"Lyris Titanborn"` and `Lyris\ Titanborn` are both one word.
+func tokenize(s string) ([]argToken, error) {
+	var tokens []argToken
+
+	var b strings.Builder
+	inWord, inQuote, quoted, escaped := false, false, false, false
+	start := 0
+
+	flush := func(end int) {
+		if inWord {
+			tokens = append(tokens, argToken{text: b.String(), quoted: quoted, start: start, end: end})
+		}
+		b.Reset()
+		inWord, quoted = false, false
+	}
+
+	for i, r := range s {
+		if !inWord {
+			if !escaped && !inQuote && isSpace(r) {
+				continue
+			}
+			inWord = true
+			start = i
+		}
+
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case isQuote(r):
+			inQuote = !inQuote
+			quoted = true
+		case !inQuote && isSpace(r):
+			flush(i)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	if inQuote {
+		return nil, ErrUnterminatedQuote
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	flush(len(s))
+
+	return tokens, nil
+}
+
+// QuoteArg quotes s, if it needs it, to be read back by the command parser as
+// a single argument. An empty s stays empty.
+func QuoteArg(s string) string {
+	if !strings.ContainsAny(s, " \t\r\n\"“”\\") && !strings.HasPrefix(s, "--") && !strings.HasPrefix(s, "—") {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if isQuote(r) || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// flagName returns the name of a --flag token (also accepting the long dash
+// phones substitute for two hyphens)
+func flagName(t argToken) (string, bool) {
+	if t.quoted {
+		return "", false
+	}
+
+	for _, prefix := range []string{"--", "—"} {
+		if strings.HasPrefix(t.text, prefix) && len(t.text) > len(prefix) {
+			return strings.ToLower(strings.TrimPrefix(t.text, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// parseArgs tokenizes command arguments, separating out flags. A flag is
+// written `--name value` or `--name=value`; switches (like --confirm) take
+// no value.
+func parseArgs(contents string) (cmdArgs, error) {
+	a := cmdArgs{raw: contents, flags: map[string]string{}}
+
+	tokens, err := tokenize(contents)
+	if err != nil {
+		return a, err
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		name, ok := flagName(t)
+		if !ok {
+			a.words = append(a.words, t)
+			continue
+		}
+
+		span := [2]int{t.start, t.end}
+		if eq := strings.Index(name, "="); eq >= 0 {
+			a.flags[name[:eq]] = name[eq+1:]
+		} else if switchFlags[name] || i+1 == len(tokens) {
+			a.flags[name] = ""
+		} else {
+			i++
+			a.flags[name] = tokens[i].text
+			span[1] = tokens[i].end
+		}
+		a.flagSpans = append(a.flagSpans, span)
+	}
+
+	return a, nil
+}
+
+// has reports whether the named flag was given
+func (a cmdArgs) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+// flag returns the value of the named flag
+func (a cmdArgs) flag(name string) string {
+	return a.flags[name]
+}
+
+// intFlag returns the value of the named flag as a number, or def if it was not given
+func (a cmdArgs) intFlag(name string, def int) (int, error) {
+	v, ok := a.flags[name]
+	if !ok {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def, errors.Wrapf(ErrBadFlag, "--%s must be a number", name)
+	}
+	return i, nil
+}
+
+// page returns the --page flag, defaulting to (and never less than) the first page
+func (a cmdArgs) page() int {
+	p, err := a.intFlag("page", 1)
+	if err != nil || p < 1 {
+		return 1
+	}
+	return p
+}
+
+// len returns the number of positional words
+func (a cmdArgs) len() int {
+	return len(a.words)
+}
+
+// word returns the i-th positional word, or "" if there are not that many
+func (a cmdArgs) word(i int) string {
+	if i >= len(a.words) {
+		return ""
+	}
+	return a.words[i].text
+}
+
+// text joins the positional words from i on with single spaces
+func (a cmdArgs) text(i int) string {
+	if i >= len(a.words) {
+		return ""
+	}
+
+	parts := make([]string, 0, len(a.words)-i)
+	for _, w := range a.words[i:] {
+		parts = append(parts, w.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// rawText returns the original text of the positional words from i on, with
+// any flags cut out, for arguments (like lists) that have their own syntax. A
+// single quoted word gives just the text inside the quotes.
+func (a cmdArgs) rawText(i int) string {
+	if i >= len(a.words) {
+		return ""
+	}
+	if i == len(a.words)-1 && a.words[i].quoted {
+		return a.words[i].text
+	}
+
+	var b strings.Builder
+	pos := a.words[i].start
+	for _, span := range a.flagSpans {
+		if span[1] <= pos {
+			continue
+		}
+		b.WriteString(a.raw[pos:span[0]])
+		pos = span[1]
+	}
+	if pos < len(a.raw) {
+		b.WriteString(a.raw[pos:])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// shift drops the first n positional words
+func (a cmdArgs) shift(n int) cmdArgs {
+	if n > len(a.words) {
+		n = len(a.words)
+	}
+	a.words = a.words[n:]
+	return a
+}
+
+// character picks out which character the arguments name: the --char flag, or
+// else the longest run of leading words that is one of names, so that names
+// with spaces work without quotes. Without an exact match it takes the longest
+// run that is close to a name, or the first word, for resolveName to suggest
+// corrections for. It returns the name and the arguments after it.
+func (a cmdArgs) character(names []string) (string, cmdArgs) {
+	if a.has("char") {
+		return a.flag("char"), a
+	}
+	if len(a.words) == 0 {
+		return "", a
+	}
+
+	maxWords := 1
+	for _, name := range names {
+		if n := len(strings.Fields(name)); n > maxWords {
+			maxWords = n
+		}
+	}
+
+	longest := len(a.words)
+	if longest > maxWords+1 {
+		longest = maxWords + 1
+	}
+
+	for n := longest; n > 0; n-- {
+		prefix := a.prefix(n)
+		for _, name := range names {
+			if strings.EqualFold(name, prefix) {
+				return prefix, a.shift(n)
+			}
+		}
+	}
+
+	// a misspelled name still leaves something to say what it is for
+	if longest == len(a.words) {
+		longest--
+	}
+	for n := longest; n > 1; n-- {
+		prefix := a.prefix(n)
+		if len(closeMatches(prefix, names)) > 0 {
+			return prefix, a.shift(n)
+		}
+	}
+
+	return a.word(0), a.shift(1)
+}
+
+// charArg returns the --char flag, or else all of the positional words, as a
+// character name
+func (a cmdArgs) charArg() string {
+	if a.has("char") {
+		return a.flag("char")
+	}
+	return a.text(0)
+}
+
+// head keeps only the first n positional words
+func (a cmdArgs) head(n int) cmdArgs {
+	if n > len(a.words) {
+		n = len(a.words)
+	}
+	a.words = a.words[:n]
+	return a
+}
+
+// prefix joins the first n positional words with single spaces
+func (a cmdArgs) prefix(n int) string {
+	return a.head(n).text(0)
+}
+
+// nameAndNumber reads the positional words as a name with an optional number
+// after it: the named flag, or else a last word like `5` or `x5`. A quoted
+// word is never taken as the number, so a name ending in a number can be
+// written `"Rune 7"`. The number is "" when there is none.
+func (a cmdArgs) nameAndNumber(flag string) (string, string, error) {
+	words := a.words
+
+	num := ""
+	if a.has(flag) {
+		num = a.flag(flag)
+	} else if n := len(words); n > 1 && !words[n-1].quoted {
+		last := strings.TrimPrefix(strings.ToLower(words[n-1].text), "x")
+		if _, err := strconv.ParseInt(last, 10, 64); err == nil {
+			num = last
+			words = words[:n-1]
+		}
+	}
+
+	a.words = words
+	name := a.text(0)
+	if name == "" {
+		return "", "", ErrItemNameRequired
+	}
+
+	return name, num, nil
+}
+
+// nameAndCount reads the positional words as a name with an optional
+// non-negative count (the --count flag or a trailing number, defaulting to 1)
+func (a cmdArgs) nameAndCount() (string, uint64, error) {
+	name, ctStr, err := a.nameAndNumber("count")
+	if err != nil {
+		return "", 0, err
+	}
+
+	if ctStr == "" {
+		return name, 1, nil
+	}
+
+	ct, err := strconv.Atoi(ctStr)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "could not interpret count")
+	}
+	if ct < 0 {
+		return "", 0, ErrPositiveValueRequired
+	}
+
+	return name, uint64(ct), nil
+}
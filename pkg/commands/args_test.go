@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr error
+	}{
+		{name: "words", in: "  item Bob   Iron Ore ", want: []string{"item", "Bob", "Iron", "Ore"}},
+		{name: "straight quotes", in: `"Lyris Titanborn" Rubedite`, want: []string{"Lyris Titanborn", "Rubedite"}},
+		{name: "curly quotes", in: "“Lyris Titanborn” Rubedite", want: []string{"Lyris Titanborn", "Rubedite"}},
+		{name: "quote inside a word", in: `Lyris" "Titanborn`, want: []string{"Lyris Titanborn"}},
+		{name: "empty quotes", in: `"" x`, want: []string{"", "x"}},
+		{name: "escaped space", in: `Lyris\ Titanborn x`, want: []string{"Lyris Titanborn", "x"}},
+		{name: "escaped quote", in: `"say \"hi\""`, want: []string{`say "hi"`}},
+		{name: "trailing backslash", in: `a\`, want: []string{`a\`}},
+		{name: "newlines and tabs", in: "a\tb\r\nc", want: []string{"a", "b", "c"}},
+		{name: "unterminated quote", in: `"Lyris Titanborn`, wantErr: ErrUnterminatedQuote},
+		{name: "unterminated curly quote", in: "“Lyris", wantErr: ErrUnterminatedQuote},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.in)
+			if err != tt.wantErr {
+				t.Fatalf("tokenize(%q) err = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			got := make([]string, len(tokens))
+			for i, tok := range tokens {
+				got[i] = tok.text
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		wantWords []string
+		wantFlags map[string]string
+		wantRaw   string
+	}{
+		{
+			name:      "no flags",
+			in:        "Bob Iron Ore",
+			wantWords: []string{"Bob", "Iron", "Ore"},
+			wantFlags: map[string]string{},
+			wantRaw:   "Bob Iron Ore",
+		},
+		{
+			name:      "flag with equals",
+			in:        "Bob --page=2 Ore",
+			wantWords: []string{"Bob", "Ore"},
+			wantFlags: map[string]string{"page": "2"},
+			wantRaw:   "Bob  Ore",
+		},
+		{
+			name:      "flag with separate value",
+			in:        `--char "Lyris Titanborn" Iron Ore`,
+			wantWords: []string{"Iron", "Ore"},
+			wantFlags: map[string]string{"char": "Lyris Titanborn"},
+			wantRaw:   "Iron Ore",
+		},
+		{
+			name:      "switch takes no value",
+			in:        "all --confirm Bob",
+			wantWords: []string{"all", "Bob"},
+			wantFlags: map[string]string{"confirm": ""},
+			wantRaw:   "all  Bob",
+		},
+		{
+			name:      "flag at the end",
+			in:        "Bob --page",
+			wantWords: []string{"Bob"},
+			wantFlags: map[string]string{"page": ""},
+			wantRaw:   "Bob",
+		},
+		{
+			name:      "em dash prefix",
+			in:        "Bob —Page 3",
+			wantWords: []string{"Bob"},
+			wantFlags: map[string]string{"page": "3"},
+			wantRaw:   "Bob",
+		},
+		{
+			name:      "quoted flag is a word",
+			in:        `Bob "--page" 3`,
+			wantWords: []string{"Bob", "--page", "3"},
+			wantFlags: map[string]string{},
+			wantRaw:   `Bob "--page" 3`,
+		},
+		{
+			name:      "bare dashes are a word",
+			in:        "Bob -- x",
+			wantWords: []string{"Bob", "--", "x"},
+			wantFlags: map[string]string{},
+			wantRaw:   "Bob -- x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseArgs(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var words []string
+			for i := 0; i < a.len(); i++ {
+				words = append(words, a.word(i))
+			}
+			if !reflect.DeepEqual(words, tt.wantWords) {
+				t.Errorf("words = %q, want %q", words, tt.wantWords)
+			}
+			if !reflect.DeepEqual(a.flags, tt.wantFlags) {
+				t.Errorf("flags = %q, want %q", a.flags, tt.wantFlags)
+			}
+			if got := a.rawText(0); got != tt.wantRaw {
+				t.Errorf("rawText(0) = %q, want %q", got, tt.wantRaw)
+			}
+		})
+	}
+
+	if _, err := parseArgs(`Bob "Iron Ore`); err != ErrUnterminatedQuote {
+		t.Errorf("unterminated quote err = %v, want %v", err, ErrUnterminatedQuote)
+	}
+}
+
+func TestCharacter(t *testing.T) {
+	names := []string{"Bob", "Lyris Titanborn", "Sai Sahan"}
+
+	tests := []struct {
+		name     string
+		in       string
+		wantChar string
+		wantRest string
+	}{
+		{name: "one word", in: "Bob Iron Ore", wantChar: "Bob", wantRest: "Iron Ore"},
+		{name: "multi-word", in: "Lyris Titanborn Iron Ore", wantChar: "Lyris Titanborn", wantRest: "Iron Ore"},
+		{name: "case-insensitive", in: "lyris titanborn Iron Ore", wantChar: "lyris titanborn", wantRest: "Iron Ore"},
+		{name: "quoted", in: `"Sai Sahan" Iron Ore`, wantChar: "Sai Sahan", wantRest: "Iron Ore"},
+		{name: "misspelled multi-word", in: "Lyris Titanbron Iron", wantChar: "Lyris Titanbron", wantRest: "Iron"},
+		{name: "misspelled leaves the rest", in: "Lyris Titanbron", wantChar: "Lyris", wantRest: "Titanbron"},
+		{name: "unknown", in: "Alice Iron Ore", wantChar: "Alice", wantRest: "Iron Ore"},
+		{name: "char flag", in: "Iron Ore --char Bob", wantChar: "Bob", wantRest: "Iron Ore"},
+		{name: "nothing", in: "", wantChar: "", wantRest: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseArgs(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			char, rest := a.character(names)
+			if char != tt.wantChar || rest.text(0) != tt.wantRest {
+				t.Errorf("character() = %q, %q; want %q, %q", char, rest.text(0), tt.wantChar, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestNameAndNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantName string
+		wantNum  string
+		wantErr  error
+	}{
+		{name: "no number", in: "Iron Ore", wantName: "Iron Ore", wantNum: ""},
+		{name: "trailing number", in: "Iron Ore 5", wantName: "Iron Ore", wantNum: "5"},
+		{name: "x count", in: "Iron Ore x5", wantName: "Iron Ore", wantNum: "5"},
+		{name: "upper case x count", in: "Iron Ore X12", wantName: "Iron Ore", wantNum: "12"},
+		{name: "quoted name ending in a digit", in: `"Rune 7"`, wantName: "Rune 7", wantNum: ""},
+		{name: "quoted name then a number", in: `"Rune 7" 2`, wantName: "Rune 7", wantNum: "2"},
+		{name: "number alone is the name", in: "7", wantName: "7", wantNum: ""},
+		{name: "flag", in: "Iron Ore 5 --count 3", wantName: "Iron Ore 5", wantNum: "3"},
+		{name: "not a number", in: "Iron Ore x", wantName: "Iron Ore x", wantNum: ""},
+		{name: "missing name", in: "--count 3", wantErr: ErrItemNameRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseArgs(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name, num, err := a.nameAndNumber("count")
+			if err != tt.wantErr {
+				t.Fatalf("nameAndNumber() err = %v, want %v", err, tt.wantErr)
+			}
+			if name != tt.wantName || num != tt.wantNum {
+				t.Errorf("nameAndNumber() = %q, %q; want %q, %q", name, num, tt.wantName, tt.wantNum)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/gsmcwhirter/go-util/parser"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/notify"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+const bankLogLimit = 20
+
+type bankCommands struct {
+	preCommand string
+	deps       bankDependencies
+}
+
+type bankDependencies interface {
+	BankAPI() storage.BankAPI
+	Notifier() notify.Notifier
+	Webhooks() webhook.Dispatcher
+}
+
+func (c *bankCommands) list(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.BankAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	bank, err := t.AddBank(msg.GuildID().ToString()) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find bank")
+	}
+
+	var total uint64
+	items := bank.GetItems()
+	itemStrings := make([]string, len(items))
+	for i, item := range items {
+		itemStrings[i] = fmt.Sprintf("%s x%d", item.Name(), item.Count())
+		total += item.Count()
+	}
+	sort.Strings(itemStrings)
+
+	p := &embedPager{
+		to:          r.To,
+		title:       "__Guild Bank__",
+		description: "Officers can call `bank add [item] [count?]` and `bank give [@user] [charname] [item] [count?]` to manage this list.",
+	}
+	p.addSection(fmt.Sprintf("*Bank Items (%d)*", total), itemStrings)
+
+	return listResponse(p, a.page(), ComponentState{Action: ComponentListPage, Owner: msg.UserID().ToString(), Name: "bank list"}), nil
+}
+
+func (c *bankCommands) log(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.BankAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	entries, err := t.GetLedger(msg.GuildID().ToString(), bankLogLimit)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to read bank ledger")
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		line := fmt.Sprintf("%s %s: %s x%d by %s", e.Time.UTC().Format("2006-01-02 15:04"), e.Kind, e.Item, e.Count, userMentionString(e.Actor))
+		if e.User != "" {
+			line += fmt.Sprintf(" to %s (%s)", userMentionString(e.User), e.Character)
+		}
+		lines = append(lines, line)
+	}
+
+	p := &embedPager{
+		to:    r.To,
+		title: "__Guild Bank Ledger__",
+	}
+	p.addSection(fmt.Sprintf("*Most Recent Entries (%d)*", len(lines)), lines)
+
+	return listResponse(p, a.page(), ComponentState{Action: ComponentListPage, Owner: msg.UserID().ToString(), Name: "bank log"}), nil
+}
+
+func (c *bankCommands) add(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	itemName, ct, err := a.nameAndCount()
+	if err != nil {
+		return r, err
+	}
+
+	t, err := c.deps.BankAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bank, err := t.AddBank(msg.GuildID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find bank")
+	}
+
+	bank.IncrItem(itemName, ct)
+
+	err = t.SaveBank(bank)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+
+	err = t.AddLedgerEntry(bank.GetGuild(), storage.LedgerEntry{
+		Time:  time.Now(),
+		Kind:  storage.LedgerDonation,
+		Actor: msg.UserID().ToString(),
+		Item:  itemName,
+		Count: ct,
+	})
+	if err != nil {
+		return r, errors.Wrap(err, "could not record bank donation")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+
+	c.deps.Notifier().BankItemAdded(msg.GuildID(), itemName, ct)
+
+	r.Description = fmt.Sprintf("added %d of %s to the bank", ct, itemName)
+	return r, nil
+}
+
+func (c *bankCommands) remove(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	itemName, ct, err := a.nameAndCount()
+	if err != nil {
+		return r, err
+	}
+
+	t, err := c.deps.BankAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bank, err := t.AddBank(msg.GuildID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find bank")
+	}
+
+	err = bank.DecrItem(itemName, ct)
+	if err != nil {
+		return r, errors.Wrap(err, "could not remove item from bank")
+	}
+
+	err = t.SaveBank(bank)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+
+	err = t.AddLedgerEntry(bank.GetGuild(), storage.LedgerEntry{
+		Time:  time.Now(),
+		Kind:  storage.LedgerWithdrawal,
+		Actor: msg.UserID().ToString(),
+		Item:  itemName,
+		Count: ct,
+	})
+	if err != nil {
+		return r, errors.Wrap(err, "could not record bank withdrawal")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+
+	r.Description = fmt.Sprintf("removed %d of %s from the bank", ct, itemName)
+	return r, nil
+}
+
+func (c *bankCommands) give(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	userID, ok := userIDFromMention(a.word(0))
+	if !ok {
+		return r, ErrUserMentionRequired
+	}
+	a = a.shift(1)
+
+	if a.len() == 0 && !a.has("char") {
+		return r, ErrCharacterNameRequired
+	}
+
+	t, err := c.deps.BankAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bank, err := t.AddBank(msg.GuildID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find bank")
+	}
+
+	ut := t.UserTx()
+	bUser, err := ut.GetUser(userID)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find user")
+	}
+
+	charName, rest := a.character(characterNames(bUser.GetCharacters()))
+	char, err := findCharacter(bUser, charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character")
+	}
+	charName = char.GetName()
+
+	itemName, ct, err := rest.nameAndCount()
+	if err != nil {
+		return r, err
+	}
+
+	err = bank.DecrItem(itemName, ct)
+	if err != nil {
+		return r, errors.Wrap(err, "could not give item from bank")
+	}
+
+	res := char.DecrNeededItem(itemName, ct)
+
+	// a character hidden from the guild is left out of the trade event
+	events := newEventLog(msg)
+	trade := webhook.TradeData{From: "bank", Actor: msg.UserID().ToString(), Item: itemName, Count: ct}
+	if storage.CanViewCharacter(bUser, char, bank.GetGuild()) {
+		trade.User, trade.Character = userID, charName
+	}
+	events.add(webhook.EventTradeCompleted, trade)
+	events.fulfilled(bUser, char, "item", itemName, ct, res)
+
+	err = ut.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save item need")
+	}
+
+	err = t.SaveBank(bank)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+
+	err = t.AddLedgerEntry(bank.GetGuild(), storage.LedgerEntry{
+		Time:      time.Now(),
+		Kind:      storage.LedgerWithdrawal,
+		Actor:     msg.UserID().ToString(),
+		User:      userID,
+		Character: charName,
+		Item:      itemName,
+		Count:     ct,
+	})
+	if err != nil {
+		return r, errors.Wrap(err, "could not record bank withdrawal")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save bank")
+	}
+	events.publish(c.deps.Webhooks())
+
+	r.Description = fmt.Sprintf("gave %d of %s from the bank to %s (%s)", ct, itemName, userMentionString(userID), charName)
+	return r, nil
+}
+
+// BankCommandHandler creates a command handler for the member-facing !bank commands
+func BankCommandHandler(deps bankDependencies, preCommand string) (*cmdhandler.CommandHandler, error) {
+	p := parser.NewParser(parser.Options{
+		CmdIndicator: " ",
+	})
+	bc := bankCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
+		PreCommand:          preCommand,
+		Placeholder:         "action",
+		HelpOnEmptyCommands: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch.SetHandler("list", cmdhandler.NewMessageHandler(bc.list))
+	ch.SetHandler("log", cmdhandler.NewMessageHandler(bc.log))
+
+	return ch, nil
+}
+
+// BankAdminCommandHandler creates a command handler for the officer-only !bank commands
+func BankAdminCommandHandler(deps bankDependencies, preCommand string) (*cmdhandler.CommandHandler, error) {
+	p := parser.NewParser(parser.Options{
+		CmdIndicator: " ",
+	})
+	bc := bankCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
+		PreCommand:              preCommand,
+		Placeholder:             "action",
+		NoHelpOnUnknownCommands: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch.SetHandler("add", cmdhandler.NewMessageHandler(bc.add))
+	ch.SetHandler("remove", cmdhandler.NewMessageHandler(bc.remove))
+	ch.SetHandler("give", cmdhandler.NewMessageHandler(bc.give))
+
+	return ch, nil
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrEmptyBatch is the error returned when no entry of a batch could be understood
+var ErrEmptyBatch = errors.New("no entries in the list could be understood")
+
+var (
+	batchCount    = regexp.MustCompile(`^(.*?)\s+[xX](\d+)$`)
+	batchBadCount = regexp.MustCompile(`\s[xX][-+]?\d\S*$`)
+)
+
+// batchEntry is one `Name xN` entry of a list of changes
+type batchEntry struct {
+	name  string
+	count uint64
+}
+
+// isBatch reports whether command arguments hold a list of entries rather than
+// a single one
+func isBatch(contents string) bool {
+	return strings.ContainsAny(contents, ",\n") || strings.Contains(contents, "```")
+}
+
+// parseBatch splits a comma-separated or multi-line list of `Name xN` entries
+// (the count defaulting to 1), returning the entries in order and the pieces
+// that could not be understood. Repeated names are added together.
+func parseBatch(contents string) ([]batchEntry, []string) {
+	var lines []string
+	for i, block := range strings.Split(contents, "```") {
+		// a code block may open with a language tag on the fence line
+		if i%2 == 1 {
+			if nl := strings.Index(block, "\n"); nl >= 0 && !strings.ContainsAny(block[:nl], ", ") {
+				block = block[nl+1:]
+			}
+		}
+		lines = append(lines, strings.Split(block, "\n")...)
+	}
+
+	var entries []batchEntry
+	var unparsed []string
+	seen := map[string]int{}
+
+	for _, line := range lines {
+		for _, piece := range strings.Split(line, ",") {
+			piece = strings.TrimSpace(piece)
+			if piece == "" {
+				continue
+			}
+
+			name, ct := piece, uint64(1)
+			if m := batchCount.FindStringSubmatch(piece); m != nil {
+				n, err := strconv.ParseUint(m[2], 10, 64)
+				if err != nil {
+					unparsed = append(unparsed, piece)
+					continue
+				}
+				name, ct = strings.TrimSpace(m[1]), n
+			} else if batchBadCount.MatchString(piece) {
+				unparsed = append(unparsed, piece)
+				continue
+			}
+
+			if i, ok := seen[strings.ToLower(name)]; ok {
+				entries[i].count += ct
+				continue
+			}
+			seen[strings.ToLower(name)] = len(entries)
+			entries = append(entries, batchEntry{name: name, count: ct})
+		}
+	}
+
+	return entries, unparsed
+}
+
+// batchAdjust applies every entry of a list of changes (contents) to a
+// character. apply makes one change and describes it; an error from apply
+// skips that entry without stopping the rest. The caller commits all of the
+// changes at once.
+func batchAdjust(msg cmdhandler.Message, contents string, user storage.User, charName, what string, apply func(char storage.Character, name string, ct uint64) (string, error)) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	char, err := user.GetCharacter(charName)
+	if err != nil {
+		return r, errors.Wrapf(err, "could not find character to adjust %s", what)
+	}
+
+	entries, unparsed := parseBatch(contents)
+
+	var changes, skipped []string
+	for _, e := range entries {
+		line, err := apply(char, e.name, e.count)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		changes = append(changes, line)
+	}
+
+	p := &embedPager{
+		to:          r.To,
+		description: fmt.Sprintf("updated the %s of %s", what, charName),
+	}
+	p.addSection(fmt.Sprintf("*Changes (%d)*", len(changes)), changes)
+	if len(unparsed) > 0 {
+		p.addSection(fmt.Sprintf("*Not Understood (%d)*", len(unparsed)), unparsed)
+	}
+	if len(skipped) > 0 {
+		p.addSection(fmt.Sprintf("*Skipped (%d)*", len(skipped)), skipped)
+	}
+	r = p.summary()
+
+	if len(changes) == 0 {
+		return r, ErrEmptyBatch
+	}
+	return r, nil
+}
@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"sort"
-	"strings"
 
 	"github.com/pkg/errors"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/gsmcwhirter/go-util/parser"
 
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
 )
 
 type charCommands struct {
@@ -24,7 +24,11 @@ func (c *charCommands) show(msg cmdhandler.Message) (cmdhandler.Response, error)
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+	charName, page := a.charArg(), a.page()
 
 	if len(charName) == 0 {
 		return r, ErrCharacterNameRequired
@@ -36,38 +40,38 @@ func (c *charCommands) show(msg cmdhandler.Message) (cmdhandler.Response, error)
 	}
 	defer deferutil.CheckDefer(t.Rollback)
 
-	bUser, err := t.AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
 	if err != nil {
 		return r, errors.Wrap(err, "unable to find user")
 	}
 
-	char, err := bUser.GetCharacter(charName)
+	char, err := visibleCharacter(bUser, charName, msg, other)
 	if err != nil {
 		return r, err
 	}
 
-	itemDescrip, itemCt := itemsDescription(char, "")
-	skillDescrip, skillCt := skillsDescription(char, "")
-	transDescrip, transCt := transDescription(char, "")
+	items, itemCt := itemsLines(char)
+	skills, skillCt := skillsLines(char)
+	trans, transCt := transLines(char)
 
-	r.Title = fmt.Sprintf("__%s__", char.GetName())
-	r.Description = "Remember, you can call `need item [charname] [item]` and `need pts [charname] [item]` to add items to these lists. You can also call `got item [charname] [item]` and `got pts [charname] [item]` to remove items from this list."
-	r.Fields = []cmdhandler.EmbedField{
-		{
-			Name: fmt.Sprintf("*Needed Items (%d)*", itemCt),
-			Val:  fmt.Sprintf("```\n%s\n```\n", itemDescrip),
-		},
-		{
-			Name: fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", transCt, transCt*50),
-			Val:  fmt.Sprintf("```\n%s\n```\n", transDescrip),
-		},
-		{
-			Name: fmt.Sprintf("*Needed Skills (%d)*", skillCt),
-			Val:  fmt.Sprintf("```\n%s\n```\n", skillDescrip),
-		},
+	p := &embedPager{
+		to:          r.To,
+		title:       fmt.Sprintf("__%s__", char.GetName()),
+		description: "Remember, you can call `need item [charname] [item]` and `need pts [charname] [item]` to add items to these lists. You can also call `got item [charname] [item]` and `got pts [charname] [item]` to remove items from this list.",
 	}
+	p.addSection(fmt.Sprintf("*Needed Items (%d)*", itemCt), items)
+	p.addSection(fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", transCt, transCt*50), trans)
+	p.addSection(fmt.Sprintf("*Needed Skills (%d)*", skillCt), skills)
 
-	return r, nil
+	r, page, pages := p.page(page)
+
+	chars := visibleCharacters(bUser, msg, other)
+	sort.Slice(chars, func(i, j int) bool { return chars[i].GetName() < chars[j].GetName() })
+
+	return &componentResponse{
+		EmbedResponse: r,
+		components:    showComponents(userID, char, chars, page, pages, !other),
+	}, nil
 }
 
 func (c *charCommands) create(msg cmdhandler.Message) (cmdhandler.Response, error) {
@@ -75,7 +79,11 @@ func (c *charCommands) create(msg cmdhandler.Message) (cmdhandler.Response, erro
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	charName := a.charArg()
 
 	if len(charName) == 0 {
 		return r, ErrCharacterNameRequired
@@ -104,7 +112,14 @@ func (c *charCommands) create(msg cmdhandler.Message) (cmdhandler.Response, erro
 		return r, ErrCharacterExists
 	}
 
-	_ = bUser.AddCharacter(charName)
+	char := bUser.AddCharacter(charName)
+	recordGuild(bUser, msg)
+
+	events := newEventLog(msg)
+	if storage.CanViewCharacter(bUser, char, msg.GuildID().ToString()) {
+		events.add(webhook.EventCharacterCreated, webhook.CharacterData{User: bUser.GetName(), Character: charName})
+	}
+
 	err = t.SaveUser(bUser)
 	if err != nil {
 		return r, errors.Wrap(err, "could not save new character")
@@ -114,6 +129,7 @@ func (c *charCommands) create(msg cmdhandler.Message) (cmdhandler.Response, erro
 	if err != nil {
 		return r, errors.Wrap(err, "could not save new character")
 	}
+	events.publish(c.deps.Webhooks())
 
 	r.Description = "character created"
 	return r, nil
@@ -124,7 +140,11 @@ func (c *charCommands) delete(msg cmdhandler.Message) (cmdhandler.Response, erro
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	charName := a.charArg()
 
 	if len(charName) == 0 {
 		return r, ErrCharacterNameRequired
@@ -144,12 +164,12 @@ func (c *charCommands) delete(msg cmdhandler.Message) (cmdhandler.Response, erro
 		}
 	}
 
-	_, err = bUser.GetCharacter(charName)
+	char, err := findCharacter(bUser, charName)
 	if err != nil {
 		return r, errors.Wrap(err, "could not find character")
 	}
 
-	bUser.DeleteCharacter(charName)
+	bUser.DeleteCharacter(char.GetName())
 	err = t.SaveUser(bUser)
 	if err != nil {
 		return r, errors.Wrap(err, "could not delete character")
@@ -175,28 +195,38 @@ func (c *charCommands) list(msg cmdhandler.Message) (cmdhandler.Response, error)
 	}
 	defer deferutil.CheckDefer(t.Rollback)
 
-	bUser, err := t.AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
 	if err != nil {
 		return r, errors.Wrap(err, "unable to find user")
 	}
 
-	chars := bUser.GetCharacters()
+	chars := visibleCharacters(bUser, msg, other)
 	charNames := make([]string, 0, len(chars))
 	for _, char := range chars {
 		charNames = append(charNames, char.GetName())
 	}
 	sort.Strings(charNames)
 
-	r.Title = "__Character List__"
-	r.Description = "Remember, you can call `char create [charname]` and `char delete [charname]` to edit this list."
-	r.Fields = []cmdhandler.EmbedField{
-		{
-			Name: "*Your Characters*",
-			Val:  fmt.Sprintf("```\n%s\n```\n", strings.Join(charNames, "\n")),
-		},
+	p := &embedPager{
+		to:          r.To,
+		title:       "__Character List__",
+		description: "Remember, you can call `char create [charname]` and `char delete [charname]` to edit this list.",
 	}
+	p.addSection(charListTitle(userID, other), charNames)
 
-	return r, nil
+	return listResponse(p, a.page(), ComponentState{Action: ComponentListPage, Owner: userID, Name: "char list"}), nil
+}
+
+func charListTitle(userID string, other bool) string {
+	if other {
+		return fmt.Sprintf("*Characters of %s*", userMentionString(userID))
+	}
+	return "*Your Characters*"
 }
 
 func (c *charCommands) help(msg cmdhandler.Message) (cmdhandler.Response, error) {
@@ -208,7 +238,7 @@ func (c *charCommands) help(msg cmdhandler.Message) (cmdhandler.Response, error)
 	r.Fields = []cmdhandler.EmbedField{
 		{
 			Name: "*Available Actions*",
-			Val:  "- help\n- list\n- show [charname]\n- create [charname]\n- delete [charname]\n",
+			Val:  "- help\n- list [@user?]\n- show [@user?] [charname] [--page N?]\n- create [charname]\n- delete [charname]\n",
 		},
 	}
 
@@ -4,12 +4,21 @@ import (
 	"fmt"
 
 	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/dm"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/notify"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
 	"github.com/gsmcwhirter/go-util/parser"
 )
 
 type dependencies interface {
 	UserAPI() storage.UserAPI
+	GuildAPI() storage.GuildAPI
+	BankAPI() storage.BankAPI
+	LootAPI() storage.LootAPI
+	ScheduleAPI() storage.ScheduleAPI
+	Notifier() notify.Notifier
+	Webhooks() webhook.Dispatcher
 }
 
 // Options enables setting the command indicator string for a CommandHandler
@@ -32,35 +41,67 @@ func CommandHandler(deps dependencies, versionStr string, opts Options) (*cmdhan
 	if err != nil {
 		return nil, err
 	}
-	ch.SetHandler("char", cch)
+	ch.SetHandler("char", suggestSubcommands(deps, cch, "list", "show", "create", "delete"))
 
 	nch, err := NeedCommandHandler(deps, fmt.Sprintf("%sneed", opts.CmdIndicator))
 	if err != nil {
 		return nil, err
 	}
-	ch.SetHandler("need", nch)
+	ch.SetHandler("need", suggestSubcommands(deps, nch, "item", "pts", "trans", "set", "clear"))
 
 	gch, err := GotCommandHandler(deps, fmt.Sprintf("%sgot", opts.CmdIndicator))
 	if err != nil {
 		return nil, err
 	}
-	ch.SetHandler("got", gch)
+	ch.SetHandler("got", suggestSubcommands(deps, gch, "item", "pts", "trans"))
 
 	lch, err := ListCommandHandler(deps, fmt.Sprintf("%slist", opts.CmdIndicator))
 	if err != nil {
 		return nil, err
 	}
-	ch.SetHandler("list", lch)
+	ch.SetHandler("list", suggestSubcommands(deps, lch, "items", "pts", "trans"))
+
+	bch, err := BankCommandHandler(deps, fmt.Sprintf("%sbank", opts.CmdIndicator))
+	if err != nil {
+		return nil, err
+	}
+	ch.SetHandler("bank", suggestSubcommands(deps, bch, "list", "log"))
+
+	loch, err := LootCommandHandler(deps, fmt.Sprintf("%sloot", opts.CmdIndicator))
+	if err != nil {
+		return nil, err
+	}
+	ch.SetHandler("loot", suggestSubcommands(deps, loch, "points", "history"))
+
+	ch.SetHandler("remind", RemindHandler(deps, fmt.Sprintf("%sremind", opts.CmdIndicator)))
+	ch.SetHandler("digest", DigestHandler(deps, fmt.Sprintf("%sdigest", opts.CmdIndicator)))
+
+	hch, err := HaveCommandHandler(deps, fmt.Sprintf("%shave", opts.CmdIndicator))
+	if err != nil {
+		return nil, err
+	}
+	ch.SetHandler("have", suggestSubcommands(deps, hch, "add", "remove", "list"))
+	ch.SetHandler("notify", NotifyHandler(deps, fmt.Sprintf("%snotify", opts.CmdIndicator)))
+	ch.SetHandler("privacy", PrivacyHandler(deps, fmt.Sprintf("%sprivacy", opts.CmdIndicator)))
+	ch.SetHandler("replies", RepliesHandler(deps, fmt.Sprintf("%sreplies", opts.CmdIndicator)))
 
 	return ch, nil
 }
 
 type configDependencies interface {
 	GuildAPI() storage.GuildAPI
+	WebhookAPI() storage.WebhookAPI
+	DMSender() dm.Sender
+}
+
+type adminDependencies interface {
+	configDependencies
+	bankDependencies
+	lootDependencies
 }
 
-// ConfigHandler creates a new command handler for !config-hw
-func ConfigHandler(deps configDependencies, versionStr string, opts Options) (*cmdhandler.CommandHandler, error) {
+// ConfigHandler creates a new command handler for !config-hw and the officer-only !bank and !loot commands
+func ConfigHandler(deps adminDependencies, versionStr string, opts Options) (*cmdhandler.CommandHandler, error) {
 	p := parser.NewParser(parser.Options{
 		CmdIndicator: opts.CmdIndicator,
 	})
@@ -77,6 +118,13 @@ func ConfigHandler(deps configDependencies, versionStr string, opts Options) (*c
 		return nil, err
 	}
 	ch.SetHandler("config-hw", cch)
+
+	bch, err := BankAdminCommandHandler(deps, fmt.Sprintf("%sbank", opts.CmdIndicator))
+	if err != nil {
+		return nil, err
+	}
+	ch.SetHandler("bank", bch)
+	ch.SetHandler("loot", LootAdminHandler(deps, fmt.Sprintf("%sloot", opts.CmdIndicator)))
 	// disable help for config
 	ch.SetHandler("help", cmdhandler.NewMessageHandler(func(msg cmdhandler.Message) (cmdhandler.Response, error) {
 		r := &cmdhandler.SimpleEmbedResponse{}
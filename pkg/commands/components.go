@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrBadComponentState is the error returned when a component custom id cannot be decoded
+var ErrBadComponentState = errors.New("bad component state")
+
+// Component actions carried in custom ids
+const (
+	ComponentGotOne     = "g1"
+	ComponentGotAll     = "ga"
+	ComponentSelectChar = "sc"
+	ComponentPage       = "pg"
+	ComponentListPage   = "lp"
+)
+
+const (
+	componentPrefix   = "hw1"
+	maxCustomIDLength = 100
+	maxLabelLength    = 80
+	maxSelectOptions  = 25
+	showPageSize      = 5
+)
+
+const (
+	componentActionRow = 1
+	componentButton    = 2
+	componentSelect    = 3
+
+	buttonPrimary   = 1
+	buttonSecondary = 2
+)
+
+// ComponentState is the state a message component carries in its custom id
+type ComponentState struct {
+	Action string
+	Owner  string
+	Char   string
+	Kind   string
+	Name   string
+	Page   int
+}
+
+// Encode packs the state into a custom id. Each field is escaped so that names
+// cannot break the format; states too long for discord are rejected.
+func (s ComponentState) Encode() (string, error) {
+	fields := []string{componentPrefix, s.Action, s.Owner, s.Char, s.Kind, s.Name, strconv.Itoa(s.Page)}
+	for i, f := range fields {
+		fields[i] = url.QueryEscape(f)
+	}
+
+	id := strings.Join(fields, ":")
+	if len(id) > maxCustomIDLength {
+		return "", ErrBadComponentState
+	}
+	return id, nil
+}
+
+// DecodeComponentState unpacks a custom id created by ComponentState.Encode
+func DecodeComponentState(id string) (s ComponentState, err error) {
+	fields := strings.Split(id, ":")
+	if len(fields) != 7 || fields[0] != componentPrefix {
+		return s, ErrBadComponentState
+	}
+
+	for i, f := range fields {
+		fields[i], err = url.QueryUnescape(f)
+		if err != nil {
+			return s, ErrBadComponentState
+		}
+	}
+
+	page, err := strconv.Atoi(fields[6])
+	if err != nil || page < 1 {
+		return s, ErrBadComponentState
+	}
+
+	return ComponentState{
+		Action: fields[1],
+		Owner:  fields[2],
+		Char:   fields[3],
+		Kind:   fields[4],
+		Name:   fields[5],
+		Page:   page,
+	}, nil
+}
+
+type selectOption struct {
+	Label   string `json:"label"`
+	Value   string `json:"value"`
+	Default bool   `json:"default,omitempty"`
+}
+
+type component struct {
+	Type        int            `json:"type"`
+	Components  []component    `json:"components,omitempty"`
+	Style       int            `json:"style,omitempty"`
+	Label       string         `json:"label,omitempty"`
+	CustomID    string         `json:"custom_id,omitempty"`
+	Placeholder string         `json:"placeholder,omitempty"`
+	Options     []selectOption `json:"options,omitempty"`
+	Disabled    bool           `json:"disabled,omitempty"`
+}
+
+// componentResponse is an embed response with message components attached
+type componentResponse struct {
+	*cmdhandler.EmbedResponse
+	components []component
+}
+
+type componentMessage struct {
+	message    json.Marshaler
+	components []component
+}
+
+func (m componentMessage) MarshalJSON() ([]byte, error) {
+	raw, err := m.message.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.components) == 0 {
+		return raw, nil
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	data["components"] = m.components
+
+	return json.Marshal(data)
+}
+
+func (r *componentResponse) ToMessage() json.Marshaler {
+	return componentMessage{
+		message:    r.EmbedResponse.ToMessage(),
+		components: r.components,
+	}
+}
+
+func truncateLabel(label string) string {
+	if len(label) <= maxLabelLength {
+		return label
+	}
+	return label[:maxLabelLength-3] + "..."
+}
+
+// needEntry is one line of a character's needs that can be marked as got
+type needEntry struct {
+	kind  string
+	name  string
+	count uint64
+}
+
+func needEntries(char storage.Character) []needEntry {
+	var items, trans, skills []needEntry
+	for _, it := range char.GetNeededItems() {
+		items = append(items, needEntry{"item", it.Name(), it.Count()})
+	}
+	for _, t := range char.GetNeededTransmutes() {
+		trans = append(trans, needEntry{"trans", t.Name(), t.Count()})
+	}
+	for _, s := range char.GetNeededSkills() {
+		skills = append(skills, needEntry{"pts", s.Name(), s.Points()})
+	}
+
+	var entries []needEntry
+	for _, group := range [][]needEntry{items, trans, skills} {
+		sort.Slice(group, func(i, j int) bool { return group[i].name < group[j].name })
+		entries = append(entries, group...)
+	}
+	return entries
+}
+
+// pageButtons builds a prev / page count / next row for responses with more
+// than one page. Custom ids must be unique within a message, so the direction
+// rides in Kind.
+func pageButtons(state ComponentState, page, pages int) []component {
+	if pages < 2 {
+		return nil
+	}
+
+	var nav []component
+	for _, b := range []struct {
+		kind     string
+		label    string
+		page     int
+		disabled bool
+	}{
+		{"prev", "Prev", page - 1, page == 1},
+		{"info", fmt.Sprintf("Page %d/%d", page, pages), page, true},
+		{"next", "Next", page + 1, page == pages},
+	} {
+		if b.page < 1 || b.page > pages {
+			b.page = page
+		}
+
+		s := state
+		s.Kind = b.kind
+		s.Page = b.page
+		id, err := s.Encode()
+		if err != nil {
+			return nil
+		}
+		nav = append(nav, component{Type: componentButton, Style: buttonSecondary, Label: b.label, CustomID: id, Disabled: b.disabled})
+	}
+
+	return []component{{Type: componentActionRow, Components: nav}}
+}
+
+// showComponents builds the got buttons, character select menu and paging
+// buttons for a char show response. Only the owner gets buttons that change
+// the list. The got buttons page along with the embed, showing showPageSize
+// entries per page, so there are as many pages as the longer of the two needs.
+func showComponents(owner string, char storage.Character, chars []storage.Character, page, embedPages int, isOwner bool) []component {
+	var rows []component
+
+	entries := needEntries(char)
+	pages := (len(entries) + showPageSize - 1) / showPageSize
+	if embedPages > pages {
+		pages = embedPages
+	}
+	if page > pages {
+		page = pages
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	if isOwner {
+		var gotOne, gotAll []component
+		start := (page - 1) * showPageSize
+		for i := start; i < len(entries) && i < start+showPageSize; i++ {
+			e := entries[i]
+			for _, b := range []struct {
+				action string
+				label  string
+				into   *[]component
+			}{
+				{ComponentGotOne, fmt.Sprintf("Got 1 %s", e.name), &gotOne},
+				{ComponentGotAll, fmt.Sprintf("Got all %s (%d)", e.name, e.count), &gotAll},
+			} {
+				id, err := ComponentState{Action: b.action, Owner: owner, Char: char.GetName(), Kind: e.kind, Name: e.name, Page: page}.Encode()
+				if err != nil {
+					continue
+				}
+				*b.into = append(*b.into, component{Type: componentButton, Style: buttonPrimary, Label: truncateLabel(b.label), CustomID: id})
+			}
+		}
+
+		if len(gotOne) > 0 {
+			rows = append(rows, component{Type: componentActionRow, Components: gotOne})
+		}
+		if len(gotAll) > 0 {
+			rows = append(rows, component{Type: componentActionRow, Components: gotAll})
+		}
+	}
+
+	navState := ComponentState{Action: ComponentPage, Owner: owner, Char: char.GetName()}
+	rows = append(rows, pageButtons(navState, page, pages)...)
+
+	if len(chars) > 1 {
+		selectID, err := ComponentState{Action: ComponentSelectChar, Owner: owner, Page: 1}.Encode()
+		if err == nil {
+			var opts []selectOption
+			for _, c := range chars {
+				if len(opts) == maxSelectOptions {
+					break
+				}
+				if len(c.GetName()) > maxCustomIDLength {
+					continue
+				}
+				opts = append(opts, selectOption{Label: truncateLabel(c.GetName()), Value: c.GetName(), Default: c.GetName() == char.GetName()})
+			}
+			rows = append(rows, component{Type: componentActionRow, Components: []component{
+				{Type: componentSelect, CustomID: selectID, Placeholder: "Switch character", Options: opts},
+			}})
+		}
+	}
+
+	return rows
+}
@@ -53,7 +53,11 @@ func (c *configCommands) get(msg cmdhandler.Message) (cmdhandler.Response, error
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	settingName := strings.TrimSpace(msg.Contents())
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	settingName := a.text(0)
 
 	t, err := c.deps.GuildAPI().NewTransaction(false)
 	if err != nil {
@@ -67,15 +71,50 @@ func (c *configCommands) get(msg cmdhandler.Message) (cmdhandler.Response, error
 	}
 
 	s := bGuild.GetSettings()
-	sVal, err := s.GetSettingString(settingName)
+	sVal, err := s.Get(settingName)
 	if err != nil {
-		return r, fmt.Errorf("'%s' is not the name of a setting", settingName)
+		return r, unknownSetting(settingName)
 	}
 
 	r.Description = fmt.Sprintf("```\n%s: '%s'\n```", settingName, sVal)
 	return r, nil
 }
 
+func (c *configCommands) describe(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	settingName := a.text(0)
+
+	defs := storage.SettingDefs()
+	if settingName != "" {
+		d, ok := storage.LookupSetting(settingName)
+		if !ok {
+			return r, unknownSetting(settingName)
+		}
+		defs = []storage.SettingDef{d}
+	}
+
+	r.Title = "__Guild Settings__"
+	for _, d := range defs {
+		def := d.Display(d.Default)
+		if def == "" {
+			def = "(empty)"
+		}
+		r.Fields = append(r.Fields, cmdhandler.EmbedField{
+			Name: fmt.Sprintf("*%s* (%s)", d.Name, d.Type),
+			Val:  fmt.Sprintf("%s\nDefault: %s", d.Description, def),
+		})
+	}
+
+	return r, nil
+}
+
 type argPair struct {
 	key, val string
 }
@@ -85,16 +124,15 @@ func (c *configCommands) set(msg cmdhandler.Message) (cmdhandler.Response, error
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args := strings.TrimSpace(msg.Contents())
-
-	argList := strings.Split(args, " ")
-	argPairs := make([]argPair, 0, len(argList))
-
-	for _, arg := range argList {
-		if arg == "" {
-			continue
-		}
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
 
+	// each setting is one word, so values with spaces are quoted: key="a b"
+	argPairs := make([]argPair, 0, a.len())
+	for i := 0; i < a.len(); i++ {
+		arg := a.word(i)
 		argPairList := strings.SplitN(arg, "=", 2)
 		if len(argPairList) != 2 {
 			return r, fmt.Errorf("could not parse setting '%s'", arg)
@@ -122,9 +160,12 @@ func (c *configCommands) set(msg cmdhandler.Message) (cmdhandler.Response, error
 
 	s := bGuild.GetSettings()
 	for _, ap := range argPairs {
-		err = s.SetSettingString(ap.key, ap.val)
+		err = s.Set(ap.key, ap.val)
+		if err == storage.ErrBadSetting {
+			return r, unknownSetting(ap.key)
+		}
 		if err != nil {
-			return r, err
+			return r, errors.Wrapf(err, "could not set '%s'", ap.key)
 		}
 	}
 	bGuild.SetSettings(s)
@@ -158,7 +199,20 @@ func (c *configCommands) reset(msg cmdhandler.Message) (cmdhandler.Response, err
 		return r, errors.Wrap(err, "unable to find or add guild")
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	settingName := a.text(0)
+
 	s := storage.GuildSettings{}
+	if settingName != "" {
+		s = bGuild.GetSettings()
+		err = s.Reset(settingName)
+		if err != nil {
+			return r, unknownSetting(settingName)
+		}
+	}
 	bGuild.SetSettings(s)
 
 	err = t.SaveGuild(bGuild)
@@ -199,6 +253,24 @@ func ConfigCommandHandler(deps configDependencies, versionStr, preCommand string
 	ch.SetHandler("get", cmdhandler.NewMessageHandler(cc.get))
 	ch.SetHandler("set", cmdhandler.NewMessageHandler(cc.set))
 	ch.SetHandler("reset", cmdhandler.NewMessageHandler(cc.reset))
+	ch.SetHandler("describe", cmdhandler.NewMessageHandler(cc.describe))
+
+	wh, err := WebhookCommandHandler(deps, fmt.Sprintf("%s webhook", preCommand))
+	if err != nil {
+		return nil, err
+	}
+	ch.SetHandler("webhook", wh)
 
 	return ch, nil
 }
+
+// unknownSetting is the error for a setting name that does not exist, with
+// suggestions of the names that were probably meant
+func unknownSetting(name string) error {
+	defs := storage.SettingDefs()
+	names := make([]string, len(defs))
+	for i, d := range defs {
+		names[i] = d.Name
+	}
+	return suggestionError{what: "setting", name: name, matches: closeMatches(name, names)}
+}
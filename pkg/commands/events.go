@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// eventLog collects the webhook events of a command while its transaction is
+// open, so they are only published once it commits. A nil eventLog records
+// nothing.
+type eventLog struct {
+	gid    snowflake.Snowflake
+	events []loggedEvent
+}
+
+type loggedEvent struct {
+	name string
+	data interface{}
+}
+
+func newEventLog(msg cmdhandler.Message) *eventLog {
+	return &eventLog{gid: msg.GuildID()}
+}
+
+func (l *eventLog) add(name string, data interface{}) {
+	if l == nil || l.gid == 0 {
+		return
+	}
+	l.events = append(l.events, loggedEvent{name: name, data: data})
+}
+
+// need records a change to a character's needs, unless its privacy settings
+// hide the character from the guild
+func (l *eventLog) need(event string, user storage.User, char storage.Character, kind, name string, count uint64) {
+	if l == nil || l.gid == 0 || !storage.CanViewCharacter(user, char, l.gid.ToString()) {
+		return
+	}
+
+	l.add(event, webhook.NeedData{
+		User:      user.GetName(),
+		Character: char.GetName(),
+		Kind:      kind,
+		Name:      name,
+		Count:     count,
+	})
+}
+
+// fulfilled records a need.fulfilled event if res shows the need was met
+func (l *eventLog) fulfilled(user storage.User, char storage.Character, kind, name string, ct uint64, res storage.DecrResult) {
+	if res.Removed {
+		l.need(webhook.EventNeedFulfilled, user, char, kind, name, ct)
+	}
+}
+
+func (l *eventLog) publish(d webhook.Dispatcher) {
+	for _, e := range l.events {
+		d.Publish(l.gid, e.name, e.data)
+	}
+}
@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -16,35 +15,35 @@ import (
 )
 
 type gotItemHandler struct {
-	user     storage.User
-	charName string
+	user        storage.User
+	charName    string
+	autoCorrect bool
+	events      *eventLog
 }
 
-func (h *gotItemHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *gotItemHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	itemName := strings.TrimSpace(args)
-
-	if len(itemName) == 0 {
-		return r, ErrItemNameRequired
-	}
-
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed items", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, note, err := resolveName("needed item", name, neededNames(char, "item"), h.autoCorrect)
+			if err != nil {
+				return "", err
+			}
+			res := char.DecrNeededItem(name, ct)
+			if !res.Found {
+				return "", notNeeded(char, name)
+			}
+			h.events.fulfilled(h.user, char, "item", name, ct, res)
+			return strings.TrimSpace(fmt.Sprintf("-%d %s (%s) %s", ct, name, gotOutcome(char, name, res, "", a.has("surplus")), note)), nil
+		})
 	}
 
-	ct, err := strconv.Atoi(ctStr)
+	itemName, ct, err := a.nameAndCount()
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust item needs")
-	}
-
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+		return r, err
 	}
 
 	char, err := h.user.GetCharacter(h.charName)
@@ -52,42 +51,55 @@ func (h *gotItemHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Respo
 		return r, errors.Wrap(err, "could not find character to adjust item needs")
 	}
 
-	char.DecrNeededItem(itemName, uint64(ct))
+	itemName, note, err := resolveName("needed item", itemName, neededNames(char, "item"), h.autoCorrect)
+	if err != nil {
+		return r, err
+	}
+
+	res := char.DecrNeededItem(itemName, ct)
+	if !res.Found {
+		return r, notNeeded(char, itemName)
+	}
+	h.events.fulfilled(h.user, char, "item", itemName, ct, res)
 
-	r.Description = fmt.Sprintf("marked %s as needing -%d of %s", h.charName, ct, itemName)
+	r.Description = fmt.Sprintf("marked %s as needing -%d of %s (%s)", h.charName, ct, itemName, gotOutcome(char, itemName, res, "", a.has("surplus")))
+	addNote(r, note)
 	return r, nil
 }
 
 type gotPointHandler struct {
-	user     storage.User
-	charName string
+	user        storage.User
+	charName    string
+	autoCorrect bool
+	events      *eventLog
 }
 
-func (h *gotPointHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *gotPointHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	skillName := strings.TrimSpace(args)
-
-	if len(skillName) == 0 {
-		return r, ErrSkillNameRequired
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed skill points", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, note, err := resolveName("needed skill", name, neededNames(char, "pts"), h.autoCorrect)
+			if err != nil {
+				return "", err
+			}
+			res := char.DecrNeededSkill(name, ct)
+			if !res.Found {
+				return "", notNeeded(char, name)
+			}
+			h.events.fulfilled(h.user, char, "skill", name, ct, res)
+			return strings.TrimSpace(fmt.Sprintf("-%d %s (%s) %s", ct, name, gotOutcome(char, name, res, " points", false), note)), nil
+		})
 	}
 
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
+	skillName, ct, err := a.nameAndCount()
+	if err == ErrItemNameRequired {
+		return r, ErrSkillNameRequired
 	}
-
-	ct, err := strconv.Atoi(ctStr)
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust skill needs")
-	}
-
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+		return r, err
 	}
 
 	char, err := h.user.GetCharacter(h.charName)
@@ -95,53 +107,99 @@ func (h *gotPointHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Resp
 		return r, errors.Wrap(err, "could not find character to adjust skill needs")
 	}
 
-	char.DecrNeededSkill(skillName, uint64(ct))
+	skillName, note, err := resolveName("needed skill", skillName, neededNames(char, "pts"), h.autoCorrect)
+	if err != nil {
+		return r, err
+	}
+
+	res := char.DecrNeededSkill(skillName, ct)
+	if !res.Found {
+		return r, notNeeded(char, skillName)
+	}
+	h.events.fulfilled(h.user, char, "skill", skillName, ct, res)
 
-	r.Description = fmt.Sprintf("marked %s as needing -%d points in %s", h.charName, ct, skillName)
+	r.Description = fmt.Sprintf("marked %s as needing -%d points in %s (%s)", h.charName, ct, skillName, gotOutcome(char, skillName, res, " points", false))
+	addNote(r, note)
 	return r, nil
 }
 
 type gotTransmuteHandler struct {
-	user     storage.User
-	charName string
+	user        storage.User
+	charName    string
+	autoCorrect bool
+	events      *eventLog
 }
 
-func (h *gotTransmuteHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *gotTransmuteHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	itemName := strings.TrimSpace(args)
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed transmutes", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, note, err := resolveName("needed transmute", name, neededNames(char, "trans"), h.autoCorrect)
+			if err != nil {
+				return "", err
+			}
+			res := char.DecrNeededTransmute(name, ct)
+			if !res.Found {
+				return "", notNeeded(char, name)
+			}
+			h.events.fulfilled(h.user, char, "transmute", name, ct, res)
+			return strings.TrimSpace(fmt.Sprintf("-%d %s (%s) %s", ct, name, gotOutcome(char, name, res, "", false), note)), nil
+		})
+	}
 
-	if len(itemName) == 0 {
-		return r, ErrItemNameRequired
+	itemName, ct, err := a.nameAndCount()
+	if err != nil {
+		return r, err
 	}
 
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
+	char, err := h.user.GetCharacter(h.charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character to adjust transmute needs")
 	}
 
-	ct, err := strconv.Atoi(ctStr)
+	itemName, note, err := resolveName("needed transmute", itemName, neededNames(char, "trans"), h.autoCorrect)
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust transmute needs")
+		return r, err
 	}
 
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+	res := char.DecrNeededTransmute(itemName, ct)
+	if !res.Found {
+		return r, notNeeded(char, itemName)
 	}
+	h.events.fulfilled(h.user, char, "transmute", itemName, ct, res)
 
-	char, err := h.user.GetCharacter(h.charName)
-	if err != nil {
-		return r, errors.Wrap(err, "could not find character to adjust transmute needs")
+	r.Description = fmt.Sprintf("marked %s as needing -%d transmutes for %s (%s)", h.charName, ct, itemName, gotOutcome(char, itemName, res, "", false))
+	addNote(r, note)
+	return r, nil
+}
+
+// gotOutcome describes how getting some of a need left it, recording anything
+// beyond what was needed as surplus items when surplus is set
+func gotOutcome(char storage.Character, name string, res storage.DecrResult, unit string, surplus bool) string {
+	var desc string
+	switch {
+	case res.Removed && res.Overflow > 0:
+		desc = fmt.Sprintf("need met, with %d%s more than needed", res.Overflow, unit)
+	case res.Removed:
+		desc = "need met"
+	default:
+		desc = fmt.Sprintf("%d%s still needed", res.Remaining, unit)
 	}
 
-	char.DecrNeededTransmute(itemName, uint64(ct))
+	if surplus && res.Overflow > 0 {
+		char.IncrSurplusItem(name, res.Overflow)
+		desc += fmt.Sprintf("; %d extra added to surplus", res.Overflow)
+	}
 
-	r.Description = fmt.Sprintf("marked %s as needing -%d transmutes for %s", h.charName, ct, itemName)
-	return r, nil
+	return desc
+}
+
+// notNeeded is the error for getting something a character does not need
+func notNeeded(char storage.Character, name string) error {
+	return fmt.Errorf("%s does not need %s", char.GetName(), name)
 }
 
 type gotCommands struct {
@@ -155,7 +213,11 @@ func (c *gotCommands) helpChars(use, cmd string) func(msg cmdhandler.Message) (c
 			To: cmdhandler.UserMentionString(msg.UserID()),
 		}
 
-		r.Description = fmt.Sprintf("Usage: %s %s [%s] [%s] [count?]\n\n", c.preCommand, cmd, "charname", use)
+		r.Description = fmt.Sprintf("Usage: %s %s [%s] [%s] [count?]\nor, for several at once: %s %s [%s] Name A x2, Name B, Name C x5\n\nQuote a name that ends in a number (\"Rune 7\") or give the count as --count N. The character can also be given as --char \"Name\".\n\n", c.preCommand, cmd, "charname", use, c.preCommand, cmd, "charname")
+
+		if cmd == "item" {
+			r.Description += "Add --surplus to keep anything beyond what was needed as spare (`have`) items.\n\n"
+		}
 
 		t, err := c.deps.UserAPI().NewTransaction(false)
 		if err != nil {
@@ -176,13 +238,20 @@ func (c *gotCommands) helpChars(use, cmd string) func(msg cmdhandler.Message) (c
 		}
 
 		sort.Strings(charNames)
-		r.Fields = []cmdhandler.EmbedField{
-			{
-				Name: "*Available Character Names*",
-				Val:  fmt.Sprintf("```\n%s\n```\n", strings.Join(charNames, "\n")),
-			},
+
+		a, err := parseArgs(msg.Contents())
+		if err != nil {
+			return r, nil
 		}
 
+		p := &embedPager{
+			to:          r.To,
+			title:       r.Title,
+			description: r.Description,
+		}
+		p.addSection("*Available Character Names*", charNames)
+
+		r, _, _ = p.page(a.page())
 		return r, nil
 	}
 }
@@ -192,6 +261,17 @@ func (c *gotCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error)
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("skill name", "pts")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -206,29 +286,16 @@ func (c *gotCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error)
 		}
 	}
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " pts",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("skill name", "pts")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &gotPointHandler{charName: char.GetName(), user: bUser})
-	}
-
-	r2, err := ch.HandleMessage(msg)
+	h := &gotPointHandler{charName: charName, user: bUser, autoCorrect: autoCorrect, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -243,6 +310,7 @@ func (c *gotCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error)
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save points gotten")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -252,6 +320,17 @@ func (c *gotCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("item name", "item")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -266,28 +345,16 @@ func (c *gotCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 		}
 	}
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " item",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("item name", "item")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &gotItemHandler{charName: char.GetName(), user: bUser})
-	}
-	r2, err := ch.HandleMessage(msg)
+	h := &gotItemHandler{charName: charName, user: bUser, autoCorrect: autoCorrect, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -302,6 +369,7 @@ func (c *gotCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save item gotten")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -311,6 +379,17 @@ func (c *gotCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, er
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("item name", "trans")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -325,28 +404,16 @@ func (c *gotCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, er
 		}
 	}
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " trans",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("item name", "trans")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &gotTransmuteHandler{charName: char.GetName(), user: bUser})
-	}
-	r2, err := ch.HandleMessage(msg)
+	h := &gotTransmuteHandler{charName: charName, user: bUser, autoCorrect: autoCorrect, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -361,6 +428,7 @@ func (c *gotCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, er
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save item transmuted")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/gsmcwhirter/go-util/parser"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/notify"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type haveDependencies interface {
+	UserAPI() storage.UserAPI
+	Notifier() notify.Notifier
+}
+
+type haveCommands struct {
+	preCommand string
+	deps       haveDependencies
+}
+
+func surplusLines(char storage.Character) ([]string, uint64) {
+	var total uint64
+	items := char.GetSurplusItems()
+	itemStrings := make([]string, len(items))
+	for i, item := range items {
+		itemStrings[i] = fmt.Sprintf("%s x%d", item.Name(), item.Count())
+		total += item.Count()
+	}
+
+	sort.Strings(itemStrings)
+
+	return itemStrings, total
+}
+
+// charItemAndCount reads `[charname] [item] [count?]` command arguments for
+// one of user's characters
+func charItemAndCount(user storage.User, a cmdArgs) (storage.Character, string, uint64, error) {
+	charName, rest := a.character(characterNames(user.GetCharacters()))
+	if charName == "" {
+		return nil, "", 0, ErrCharacterNameRequired
+	}
+
+	char, err := findCharacter(user, charName)
+	if err != nil {
+		return nil, "", 0, errors.Wrap(err, "could not find character to adjust surplus")
+	}
+
+	itemName, ct, err := rest.nameAndCount()
+	return char, itemName, ct, err
+}
+
+func (c *haveCommands) add(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	t, err := c.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not create user")
+	}
+	recordGuild(bUser, msg)
+
+	char, itemName, ct, err := charItemAndCount(bUser, a)
+	if err != nil {
+		return r, err
+	}
+
+	char.IncrSurplusItem(itemName, ct)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save surplus item")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save surplus item")
+	}
+
+	c.deps.Notifier().HaveAdded(msg.GuildID(), msg.UserID().ToString(), itemName, ct)
+
+	r.Description = fmt.Sprintf("marked %s as having +%d spare %s", char.GetName(), ct, itemName)
+	return r, nil
+}
+
+func (c *haveCommands) remove(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	t, err := c.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not find user")
+	}
+
+	char, itemName, ct, err := charItemAndCount(bUser, a)
+	if err != nil {
+		return r, err
+	}
+
+	char.DecrSurplusItem(itemName, ct)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save surplus item")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save surplus item")
+	}
+
+	r.Description = fmt.Sprintf("marked %s as having -%d spare %s", char.GetName(), ct, itemName)
+	return r, nil
+}
+
+func (c *haveCommands) list(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+	page := a.page()
+
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find user")
+	}
+
+	chars := visibleCharacters(bUser, msg, other)
+	sort.Slice(chars, func(i, j int) bool { return chars[i].GetName() < chars[j].GetName() })
+
+	p := &embedPager{
+		to:          r.To,
+		title:       "__Surplus Items__",
+		description: "Remember, you can call `have add [charname] [item] [count?]` and `have remove [charname] [item] [count?]` to edit this list.",
+	}
+	for _, char := range chars {
+		lines, ct := surplusLines(char)
+		if ct == 0 {
+			continue
+		}
+
+		p.addSection(fmt.Sprintf("*%s (%d)*", char.GetName(), ct), lines)
+	}
+
+	return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Name: "have list"}), nil
+}
+
+func (c *haveCommands) notify(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	mode := strings.ToLower(a.text(0))
+
+	t, err := c.deps.UserAPI().NewTransaction(mode != "")
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not create user")
+	}
+
+	switch mode {
+	case "":
+		r.Description = fmt.Sprintf("match notifications are `%s`; call `notify [off|dm|channel]` to change this", bUser.GetNotifyMode())
+		return r, nil
+	case storage.NotifyOff, storage.NotifyDM, storage.NotifyChannel:
+	default:
+		return r, errors.New("notification mode must be one of off, dm or channel")
+	}
+
+	bUser.SetNotifyMode(mode)
+	recordGuild(bUser, msg)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save notification setting")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save notification setting")
+	}
+
+	r.Description = fmt.Sprintf("match notifications set to `%s`", mode)
+	return r, nil
+}
+
+// HaveCommandHandler creates a command handler for !have commands
+func HaveCommandHandler(deps haveDependencies, preCommand string) (*cmdhandler.CommandHandler, error) {
+	p := parser.NewParser(parser.Options{
+		CmdIndicator: " ",
+	})
+	hc := haveCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
+		PreCommand:          preCommand,
+		Placeholder:         "action",
+		HelpOnEmptyCommands: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch.SetHandler("add", cmdhandler.NewMessageHandler(hc.add))
+	ch.SetHandler("remove", cmdhandler.NewMessageHandler(hc.remove))
+	ch.SetHandler("list", cmdhandler.NewMessageHandler(hc.list))
+
+	return ch, nil
+}
+
+// NotifyHandler creates a handler for !notify commands
+func NotifyHandler(deps haveDependencies, preCommand string) cmdhandler.MessageHandler {
+	hc := haveCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	return cmdhandler.NewMessageHandler(hc.notify)
+}
@@ -5,10 +5,12 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
 )
 
-func skillsDescription(char storage.Character, indent string) (string, uint64) {
+func skillsLines(char storage.Character) ([]string, uint64) {
 	var total uint64
 	skills := char.GetNeededSkills()
 	skillStrings := make([]string, len(skills))
@@ -19,10 +21,10 @@ func skillsDescription(char storage.Character, indent string) (string, uint64) {
 
 	sort.Strings(skillStrings)
 
-	return strings.Join(skillStrings, fmt.Sprintf("\n%s", indent)), total
+	return skillStrings, total
 }
 
-func itemsDescription(char storage.Character, indent string) (string, uint64) {
+func itemsLines(char storage.Character) ([]string, uint64) {
 	var total uint64
 	items := char.GetNeededItems()
 	itemStrings := make([]string, len(items))
@@ -33,10 +35,10 @@ func itemsDescription(char storage.Character, indent string) (string, uint64) {
 
 	sort.Strings(itemStrings)
 
-	return strings.Join(itemStrings, fmt.Sprintf("\n%s", indent)), total
+	return itemStrings, total
 }
 
-func transDescription(char storage.Character, indent string) (string, uint64) {
+func transLines(char storage.Character) ([]string, uint64) {
 	var total uint64
 	items := char.GetNeededTransmutes()
 	itemStrings := make([]string, len(items))
@@ -47,5 +49,45 @@ func transDescription(char storage.Character, indent string) (string, uint64) {
 
 	sort.Strings(itemStrings)
 
-	return strings.Join(itemStrings, fmt.Sprintf("\n%s", indent)), total
+	return itemStrings, total
+}
+
+// countLines lists summed counts as sorted `name xN` lines
+func countLines(counts map[string]uint64) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s x%d", name, counts[name])
+	}
+	return lines
+}
+
+// userIDFromMention extracts the user id from a discord user mention string
+func userIDFromMention(mention string) (string, bool) {
+	if !cmdhandler.IsUserMention(mention) {
+		return "", false
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(mention, "<@"), ">")
+	return strings.TrimPrefix(id, "!"), true
+}
+
+// userMentionString formats a stored user id as a discord user mention
+func userMentionString(id string) string {
+	return fmt.Sprintf("<@%s>", id)
+}
+
+// recordGuild notes that the user has used the bot in the message's guild, so
+// that guild-wide features (like loot) can find them
+func recordGuild(user storage.User, msg cmdhandler.Message) {
+	if msg.GuildID() == 0 {
+		return
+	}
+
+	user.AddGuild(msg.GuildID().ToString())
 }
@@ -2,8 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"sort"
-	"strings"
 
 	"github.com/pkg/errors"
 
@@ -22,7 +20,11 @@ func (c *listCommands) items(msg cmdhandler.Message) (cmdhandler.Response, error
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+	charName, page := a.charArg(), a.page()
 
 	t, err := c.deps.UserAPI().NewTransaction(false)
 	if err != nil {
@@ -30,62 +32,46 @@ func (c *listCommands) items(msg cmdhandler.Message) (cmdhandler.Response, error
 	}
 	defer deferutil.CheckDefer(t.Rollback)
 
-	bUser, err := t.AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
 	if err != nil {
 		return r, errors.Wrap(err, "unable to find user")
 	}
 
 	if charName != "" {
-		char, err := bUser.GetCharacter(charName)
+		char, err := visibleCharacter(bUser, charName, msg, other)
 		if err != nil {
 			return r, err
 		}
 
-		itemDescrip, itemCt := itemsDescription(char, "")
+		items, itemCt := itemsLines(char)
 
-		r.Title = fmt.Sprintf("__%s__", char.GetName())
-		r.Description = "Remember, you can call `need item [charname] [item]` and `got item [charname] [item]` to add and remove items to this list."
-		r.Fields = []cmdhandler.EmbedField{
-			{
-				Name: fmt.Sprintf("*Needed Items (%d)*", itemCt),
-				Val:  fmt.Sprintf("```\n%s\n```\n", itemDescrip),
-			},
+		p := &embedPager{
+			to:          r.To,
+			title:       fmt.Sprintf("__%s__", char.GetName()),
+			description: "Remember, you can call `need item [charname] [item]` and `got item [charname] [item]` to add and remove items to this list.",
 		}
+		p.addSection(fmt.Sprintf("*Needed Items (%d)*", itemCt), items)
 
-		return r, nil
+		return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Char: charName, Name: "list items"}), nil
 	}
 
 	var total uint64
 	itemCounts := map[string]uint64{}
-	for _, char := range bUser.GetCharacters() {
+	for _, char := range visibleCharacters(bUser, msg, other) {
 		for _, item := range char.GetNeededItems() {
 			itemCounts[item.Name()] += item.Count()
 			total += item.Count()
 		}
 	}
 
-	itemNames := make([]string, 0, len(itemCounts))
-	for itemName := range itemCounts {
-		itemNames = append(itemNames, itemName)
-	}
-	sort.Strings(itemNames)
-
-	itemDescrip := ""
-	for _, itemName := range itemNames {
-		ct := itemCounts[itemName]
-		itemDescrip += fmt.Sprintf("%s x%d\n", itemName, ct)
+	p := &embedPager{
+		to:          r.To,
+		title:       "__All Characters__",
+		description: "Remember, you can call `need item [charname] [item]` and `got item [charname] [item]` to add and remove items to this list.",
 	}
+	p.addSection(fmt.Sprintf("*Needed Items (%d)*", total), countLines(itemCounts))
 
-	r.Title = "__All Characters__"
-	r.Description = "Remember, you can call `need item [charname] [item]` and `got item [charname] [item]` to add and remove items to this list."
-	r.Fields = []cmdhandler.EmbedField{
-		{
-			Name: fmt.Sprintf("*Needed Items (%d)*", total),
-			Val:  fmt.Sprintf("```\n%s\n```\n", itemDescrip),
-		},
-	}
-
-	return r, nil
+	return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Name: "list items"}), nil
 }
 
 func (c *listCommands) points(msg cmdhandler.Message) (cmdhandler.Response, error) {
@@ -93,7 +79,11 @@ func (c *listCommands) points(msg cmdhandler.Message) (cmdhandler.Response, erro
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+	charName, page := a.charArg(), a.page()
 
 	t, err := c.deps.UserAPI().NewTransaction(false)
 	if err != nil {
@@ -101,63 +91,46 @@ func (c *listCommands) points(msg cmdhandler.Message) (cmdhandler.Response, erro
 	}
 	defer deferutil.CheckDefer(t.Rollback)
 
-	bUser, err := t.AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
 	if err != nil {
 		return r, errors.Wrap(err, "unable to find user")
 	}
 
 	if charName != "" {
-		char, err := bUser.GetCharacter(charName)
+		char, err := visibleCharacter(bUser, charName, msg, other)
 		if err != nil {
 			return r, err
 		}
 
-		skillsDescrip, skillsCt := skillsDescription(char, "  ")
+		skills, skillsCt := skillsLines(char)
 
-		r.Title = fmt.Sprintf("__%s__", char.GetName())
-		r.Description = "Remember, you can call `need pts [charname] [item]` and `got pts [charname] [item]` to add and remove items to this list."
-		r.Fields = []cmdhandler.EmbedField{
-			{
-				Name: fmt.Sprintf("*Needed Points (%d)*", skillsCt),
-				Val:  fmt.Sprintf("```\n%s\n```\n", skillsDescrip),
-			},
+		p := &embedPager{
+			to:          r.To,
+			title:       fmt.Sprintf("__%s__", char.GetName()),
+			description: "Remember, you can call `need pts [charname] [item]` and `got pts [charname] [item]` to add and remove items to this list.",
 		}
+		p.addSection(fmt.Sprintf("*Needed Points (%d)*", skillsCt), skills)
 
-		return r, nil
+		return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Char: charName, Name: "list pts"}), nil
 	}
 
 	var total uint64
 	skillCounts := map[string]uint64{}
-	for _, char := range bUser.GetCharacters() {
+	for _, char := range visibleCharacters(bUser, msg, other) {
 		for _, skill := range char.GetNeededSkills() {
 			skillCounts[skill.Name()] += skill.Points()
 			total += skill.Points()
 		}
 	}
 
-	skillNames := make([]string, 0, len(skillCounts))
-	for k := range skillCounts {
-		skillNames = append(skillNames, k)
+	p := &embedPager{
+		to:          r.To,
+		title:       "__All Characters__",
+		description: "Remember, you can call `need pts [charname] [item]` and `got pts [charname] [item]` to add and remove items to this list.",
 	}
+	p.addSection(fmt.Sprintf("*Needed Points (%d)*", total), countLines(skillCounts))
 
-	sort.Strings(skillNames)
-
-	skillDescrip := ""
-	for _, skillName := range skillNames {
-		ct := skillCounts[skillName]
-		skillDescrip += fmt.Sprintf("%s x%d\n", skillName, ct)
-	}
-
-	r.Title = "__All Characters__"
-	r.Description = "Remember, you can call `need pts [charname] [item]` and `got pts [charname] [item]` to add and remove items to this list."
-	r.Fields = []cmdhandler.EmbedField{
-		{
-			Name: fmt.Sprintf("*Needed Points (%d)*", total),
-			Val:  fmt.Sprintf("```\n%s\n```\n", skillDescrip),
-		},
-	}
-
-	return r, nil
+	return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Name: "list pts"}), nil
 }
 
 func (c *listCommands) transmutes(msg cmdhandler.Message) (cmdhandler.Response, error) {
@@ -165,7 +138,11 @@ func (c *listCommands) transmutes(msg cmdhandler.Message) (cmdhandler.Response,
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	charName := strings.TrimSpace(msg.Contents())
+	userID, a, other, err := targetUser(msg)
+	if err != nil {
+		return r, err
+	}
+	charName, page := a.charArg(), a.page()
 
 	t, err := c.deps.UserAPI().NewTransaction(false)
 	if err != nil {
@@ -173,63 +150,59 @@ func (c *listCommands) transmutes(msg cmdhandler.Message) (cmdhandler.Response,
 	}
 	defer deferutil.CheckDefer(t.Rollback)
 
-	bUser, err := t.AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	bUser, err := t.AddUser(userID) // add or get empty (don't save)
 	if err != nil {
 		return r, errors.Wrap(err, "unable to find user")
 	}
 
 	if charName != "" {
-		char, err := bUser.GetCharacter(charName)
+		char, err := visibleCharacter(bUser, charName, msg, other)
 		if err != nil {
 			return r, err
 		}
 
-		transDescrip, transCt := transDescription(char, "  ")
+		trans, transCt := transLines(char)
 
-		r.Title = fmt.Sprintf("__%s__", char.GetName())
-		r.Description = "Remember, you can call `need trans [charname] [item]` and `got trans [charname] [item]` to add and remove items to this list."
-		r.Fields = []cmdhandler.EmbedField{
-			{
-				Name: fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", transCt, transCt*50),
-				Val:  fmt.Sprintf("```\n%s\n```\n", transDescrip),
-			},
+		p := &embedPager{
+			to:          r.To,
+			title:       fmt.Sprintf("__%s__", char.GetName()),
+			description: "Remember, you can call `need trans [charname] [item]` and `got trans [charname] [item]` to add and remove items to this list.",
 		}
+		p.addSection(fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", transCt, transCt*50), trans)
 
-		return r, nil
+		return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Char: charName, Name: "list trans"}), nil
 	}
 
 	var total uint64
 	itemCounts := map[string]uint64{}
-	for _, char := range bUser.GetCharacters() {
+	for _, char := range visibleCharacters(bUser, msg, other) {
 		for _, trans := range char.GetNeededTransmutes() {
 			itemCounts[trans.Name()] += trans.Count()
 			total += trans.Count()
 		}
 	}
 
-	itemNames := make([]string, 0, len(itemCounts))
-	for k := range itemCounts {
-		itemNames = append(itemNames, k)
+	p := &embedPager{
+		to:          r.To,
+		title:       "__All Characters__",
+		description: "Remember, you can call `need trans [charname] [item]` and `got trans [charname] [item]` to add and remove items to this list.",
 	}
+	p.addSection(fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", total, total*50), countLines(itemCounts))
 
-	sort.Strings(itemNames)
+	return listResponse(p, page, ComponentState{Action: ComponentListPage, Owner: userID, Name: "list trans"}), nil
+}
 
-	itemDescrip := ""
-	for _, itemName := range itemNames {
-		ct := itemCounts[itemName]
-		itemDescrip += fmt.Sprintf("%s x%d\n", itemName, ct)
-	}
+// listResponse picks a page of a list, adding buttons to move between pages when
+// there is more than one
+func listResponse(p *embedPager, page int, state ComponentState) cmdhandler.Response {
+	r, page, pages := p.page(page)
 
-	r.Title = "__All Characters__"
-	r.Description = "Remember, you can call `need trans [charname] [item]` and `got trans [charname] [item]` to add and remove items to this list."
-	r.Fields = []cmdhandler.EmbedField{
-		{
-			Name: fmt.Sprintf("*Needed Transmutes (%d; %d stones)*", total, total*50),
-			Val:  fmt.Sprintf("```\n%s\n```\n", itemDescrip),
-		},
+	rows := pageButtons(state, page, pages)
+	if len(rows) == 0 {
+		return r
 	}
 
-	return r, nil
+	return &componentResponse{EmbedResponse: r, components: rows}
 }
 
 // ListCommandHandler creates a command handler for !list commands
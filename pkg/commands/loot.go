@@ -0,0 +1,455 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/gsmcwhirter/go-util/parser"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+const lootHistoryLimit = 20
+
+type lootDependencies interface {
+	LootAPI() storage.LootAPI
+}
+
+type lootAdminDependencies interface {
+	lootDependencies
+	configDependencies
+	Webhooks() webhook.Dispatcher
+}
+
+type lootCandidate struct {
+	user      string
+	charName  string
+	itemName  string
+	count     uint64
+	points    int64
+	lastAward time.Time
+}
+
+// rankLootCandidates orders candidates according to the guild's loot system
+func rankLootCandidates(system string, candidates []lootCandidate) {
+	switch system {
+	case storage.LootSystemRotation:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if !candidates[i].lastAward.Equal(candidates[j].lastAward) {
+				return candidates[i].lastAward.Before(candidates[j].lastAward)
+			}
+			return candidates[i].points > candidates[j].points
+		})
+	default:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].points != candidates[j].points {
+				return candidates[i].points > candidates[j].points
+			}
+			return candidates[i].lastAward.Before(candidates[j].lastAward)
+		})
+	}
+}
+
+func findNeededItem(char storage.Character, itemName string) (storage.Item, bool) {
+	for _, item := range char.GetNeededItems() {
+		if strings.EqualFold(item.Name(), itemName) {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+type lootCommands struct {
+	preCommand string
+	deps       lootDependencies
+}
+
+func (c *lootCommands) points(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.LootAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	table, err := t.AddLootTable(msg.GuildID().ToString()) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find loot table")
+	}
+
+	members := table.GetMembers()
+	sort.SliceStable(members, func(i, j int) bool {
+		return table.GetPoints(members[i]) > table.GetPoints(members[j])
+	})
+
+	lines := make([]string, 0, len(members))
+	for _, user := range members {
+		lines = append(lines, fmt.Sprintf("%s: %d pts", userMentionString(user), table.GetPoints(user)))
+	}
+
+	p := &embedPager{
+		to:    r.To,
+		title: "__Loot Points__",
+	}
+	p.addSection(fmt.Sprintf("*Members (%d)*", len(lines)), lines)
+
+	return listResponse(p, a.page(), ComponentState{Action: ComponentListPage, Owner: msg.UserID().ToString(), Name: "loot points"}), nil
+}
+
+func (c *lootCommands) history(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.LootAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	awards, err := t.GetAwards(msg.GuildID().ToString(), lootHistoryLimit)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to read loot history")
+	}
+
+	lines := make([]string, 0, len(awards))
+	for _, award := range awards {
+		lines = append(lines, fmt.Sprintf("%s %s to %s (%s) for %d pts by %s", award.Time.UTC().Format("2006-01-02 15:04"), award.Item, userMentionString(award.User), award.Character, award.Cost, userMentionString(award.Awarder)))
+	}
+
+	p := &embedPager{
+		to:    r.To,
+		title: "__Loot History__",
+	}
+	p.addSection(fmt.Sprintf("*Most Recent Awards (%d)*", len(lines)), lines)
+
+	return listResponse(p, a.page(), ComponentState{Action: ComponentListPage, Owner: msg.UserID().ToString(), Name: "loot history"}), nil
+}
+
+type lootAdminCommands struct {
+	preCommand string
+	deps       lootAdminDependencies
+}
+
+func (c *lootAdminCommands) handle(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	contents := strings.TrimSpace(msg.Contents())
+	action := strings.SplitN(contents, " ", 2)[0]
+	rest := strings.TrimSpace(strings.TrimPrefix(contents, action))
+
+	switch action {
+	case "award":
+		return c.award(cmdhandler.NewWithContents(msg, rest))
+	case "grant":
+		return c.grant(cmdhandler.NewWithContents(msg, rest))
+	case "", "points", "history", "help":
+		// fall through to the member-facing handler
+		return &cmdhandler.SimpleEmbedResponse{}, parser.ErrUnknownCommand
+	default:
+		return c.drop(msg)
+	}
+}
+
+func (c *lootAdminCommands) drop(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	itemName := a.text(0)
+	if len(itemName) == 0 {
+		return r, ErrItemNameRequired
+	}
+
+	s, err := storage.GetSettings(c.deps.GuildAPI(), msg.GuildID())
+	if err != nil {
+		return r, err
+	}
+
+	system := s.LootSystem()
+
+	t, err := c.deps.LootAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	gid := msg.GuildID().ToString()
+	table, err := t.AddLootTable(gid) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find loot table")
+	}
+
+	var candidates []lootCandidate
+	for _, bUser := range t.UserTx().GetUsers() {
+		if !bUser.InGuild(gid) {
+			continue
+		}
+
+		for _, char := range bUser.GetCharacters() {
+			if !storage.CanViewCharacter(bUser, char, gid) {
+				continue
+			}
+
+			item, ok := findNeededItem(char, itemName)
+			if !ok {
+				continue
+			}
+
+			candidates = append(candidates, lootCandidate{
+				user:      bUser.GetName(),
+				charName:  char.GetName(),
+				itemName:  item.Name(),
+				count:     item.Count(),
+				points:    table.GetPoints(bUser.GetName()),
+				lastAward: table.GetLastAward(bUser.GetName()),
+			})
+		}
+	}
+
+	rankLootCandidates(system, candidates)
+
+	lines := make([]string, 0, len(candidates))
+	for i, cand := range candidates {
+		lastAward := "never"
+		if !cand.lastAward.IsZero() {
+			lastAward = cand.lastAward.UTC().Format("2006-01-02")
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s (%s, needs x%d): %d pts, last award %s", i+1, userMentionString(cand.user), cand.charName, cand.count, cand.points, lastAward))
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, "nobody needs this item")
+	}
+
+	p := &embedPager{
+		to:          r.To,
+		title:       fmt.Sprintf("__Loot: %s__", itemName),
+		description: fmt.Sprintf("Ranked by `%s`. Call `loot award [@user] [charname] [item] [cost?]` to hand it out.", system),
+	}
+	p.addSection(fmt.Sprintf("*Eligible Members (%d)*", len(candidates)), lines)
+
+	// the item name is free text, so this pages with --page rather than buttons
+	r, _, _ = p.page(a.page())
+	return r, nil
+}
+
+func (c *lootAdminCommands) award(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	userID, ok := userIDFromMention(a.word(0))
+	if !ok {
+		return r, ErrUserMentionRequired
+	}
+	a = a.shift(1)
+
+	if a.len() == 0 && !a.has("char") {
+		return r, ErrCharacterNameRequired
+	}
+
+	t, err := c.deps.LootAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	gid := msg.GuildID().ToString()
+	table, err := t.AddLootTable(gid)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find loot table")
+	}
+
+	ut := t.UserTx()
+	bUser, err := ut.GetUser(userID)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find user")
+	}
+
+	charName, rest := a.character(characterNames(bUser.GetCharacters()))
+	char, err := findCharacter(bUser, charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character")
+	}
+	charName = char.GetName()
+
+	itemName, costStr, err := rest.nameAndNumber("cost")
+	if err != nil {
+		return r, err
+	}
+
+	var cost int64
+	if costStr != "" {
+		cost, err = strconv.ParseInt(costStr, 10, 64)
+		if err != nil {
+			return r, errors.Wrap(err, "could not interpret loot cost")
+		}
+	}
+
+	item, needed := findNeededItem(char, itemName)
+	if needed {
+		itemName = item.Name()
+	}
+
+	// a character hidden from the guild is left out of the trade event
+	events := newEventLog(msg)
+	trade := webhook.TradeData{From: "loot", Actor: msg.UserID().ToString(), Item: itemName, Count: 1}
+	if storage.CanViewCharacter(bUser, char, gid) {
+		trade.User, trade.Character = userID, charName
+	}
+	events.add(webhook.EventTradeCompleted, trade)
+
+	if needed {
+		res := char.DecrNeededItem(itemName, 1)
+		events.fulfilled(bUser, char, "item", itemName, 1, res)
+	}
+
+	err = ut.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save item need")
+	}
+
+	now := time.Now()
+	table.RecordAward(userID, now, cost)
+
+	err = t.SaveLootTable(table)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save loot table")
+	}
+
+	err = t.AddAward(gid, storage.LootAward{
+		Time:      now,
+		Awarder:   msg.UserID().ToString(),
+		User:      userID,
+		Character: charName,
+		Item:      itemName,
+		Cost:      cost,
+	})
+	if err != nil {
+		return r, errors.Wrap(err, "could not record loot award")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save loot award")
+	}
+	events.publish(c.deps.Webhooks())
+
+	r.Description = fmt.Sprintf("awarded %s to %s (%s) for %d pts; %d pts remaining", itemName, userMentionString(userID), charName, cost, table.GetPoints(userID))
+	return r, nil
+}
+
+func (c *lootAdminCommands) grant(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	if a.len() != 2 {
+		return r, errors.New("usage: loot grant [@user] [points]")
+	}
+
+	userID, ok := userIDFromMention(a.word(0))
+	if !ok {
+		return r, ErrUserMentionRequired
+	}
+
+	amt, err := strconv.ParseInt(a.word(1), 10, 64)
+	if err != nil {
+		return r, errors.Wrap(err, "could not interpret points")
+	}
+
+	t, err := c.deps.LootAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	table, err := t.AddLootTable(msg.GuildID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find loot table")
+	}
+
+	table.AddPoints(userID, amt)
+
+	err = t.SaveLootTable(table)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save loot table")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save loot table")
+	}
+
+	r.Description = fmt.Sprintf("%s now has %d pts", userMentionString(userID), table.GetPoints(userID))
+	return r, nil
+}
+
+// LootCommandHandler creates a command handler for the member-facing !loot commands
+func LootCommandHandler(deps lootDependencies, preCommand string) (*cmdhandler.CommandHandler, error) {
+	p := parser.NewParser(parser.Options{
+		CmdIndicator: " ",
+	})
+	lc := lootCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
+		PreCommand:          preCommand,
+		Placeholder:         "action",
+		HelpOnEmptyCommands: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch.SetHandler("points", cmdhandler.NewMessageHandler(lc.points))
+	ch.SetHandler("history", cmdhandler.NewMessageHandler(lc.history))
+
+	return ch, nil
+}
+
+// LootAdminHandler creates a handler for the officer-only !loot commands: reporting
+// a drop (`loot [item]`), awarding it, and granting points
+func LootAdminHandler(deps lootAdminDependencies, preCommand string) cmdhandler.MessageHandler {
+	lc := lootAdminCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+
+	return cmdhandler.NewMessageHandler(lc.handle)
+}
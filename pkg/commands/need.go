@@ -3,8 +3,6 @@ package commands
 import (
 	"fmt"
 	"sort"
-	"strconv"
-	"strings"
 
 	"github.com/pkg/errors"
 
@@ -13,38 +11,32 @@ import (
 	"github.com/gsmcwhirter/go-util/parser"
 
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
 )
 
 type needItemHandler struct {
 	user     storage.User
 	charName string
+	events   *eventLog
 }
 
-func (h *needItemHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *needItemHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	itemName := strings.TrimSpace(args)
-
-	if len(itemName) == 0 {
-		return r, ErrItemNameRequired
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed items", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, _ = nearName("needed item", name, neededNames(char, "item"))
+			char.IncrNeededItem(name, ct)
+			h.events.need(webhook.EventNeedAdded, h.user, char, "item", name, ct)
+			return fmt.Sprintf("+%d %s", ct, name), nil
+		})
 	}
 
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
-	}
-
-	ct, err := strconv.Atoi(ctStr)
+	itemName, ct, err := a.nameAndCount()
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust item needs")
-	}
-
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+		return r, err
 	}
 
 	char, err := h.user.GetCharacter(h.charName)
@@ -52,42 +44,41 @@ func (h *needItemHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Resp
 		return r, errors.Wrap(err, "could not find character to adjust item needs")
 	}
 
-	char.IncrNeededItem(itemName, uint64(ct))
+	itemName, note := nearName("needed item", itemName, neededNames(char, "item"))
+	char.IncrNeededItem(itemName, ct)
+	h.events.need(webhook.EventNeedAdded, h.user, char, "item", itemName, ct)
 
 	r.Description = fmt.Sprintf("marked %s as needing +%d of %s", h.charName, ct, itemName)
+	addNote(r, note)
 	return r, nil
 }
 
 type needPointHandler struct {
 	user     storage.User
 	charName string
+	events   *eventLog
 }
 
-func (h *needPointHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *needPointHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	skillName := strings.TrimSpace(args)
-
-	if len(skillName) == 0 {
-		return r, ErrSkillNameRequired
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed skill points", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, _ = nearName("needed skill", name, neededNames(char, "pts"))
+			char.IncrNeededSkill(name, ct)
+			h.events.need(webhook.EventNeedAdded, h.user, char, "skill", name, ct)
+			return fmt.Sprintf("+%d %s", ct, name), nil
+		})
 	}
 
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
+	skillName, ct, err := a.nameAndCount()
+	if err == ErrItemNameRequired {
+		return r, ErrSkillNameRequired
 	}
-
-	ct, err := strconv.Atoi(ctStr)
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust skill needs")
-	}
-
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+		return r, err
 	}
 
 	char, err := h.user.GetCharacter(h.charName)
@@ -95,42 +86,38 @@ func (h *needPointHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Res
 		return r, errors.Wrap(err, "could not find character to adjust skill needs")
 	}
 
-	char.IncrNeededSkill(skillName, uint64(ct))
+	skillName, note := nearName("needed skill", skillName, neededNames(char, "pts"))
+	char.IncrNeededSkill(skillName, ct)
+	h.events.need(webhook.EventNeedAdded, h.user, char, "skill", skillName, ct)
 
 	r.Description = fmt.Sprintf("marked %s as needing +%d points in %s", h.charName, ct, skillName)
+	addNote(r, note)
 	return r, nil
 }
 
 type needTransmuteHandler struct {
 	user     storage.User
 	charName string
+	events   *eventLog
 }
 
-func (h *needTransmuteHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+func (h *needTransmuteHandler) HandleArgs(msg cmdhandler.Message, a cmdArgs) (cmdhandler.Response, error) {
 	r := &cmdhandler.SimpleEmbedResponse{
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
-	args, ctStr := parser.MaybeCount(msg.Contents())
-
-	itemName := strings.TrimSpace(args)
-
-	if len(itemName) == 0 {
-		return r, ErrItemNameRequired
-	}
-
-	ctStr = strings.TrimSpace(ctStr)
-	if ctStr == "" {
-		ctStr = "1"
+	if contents := a.rawText(0); isBatch(contents) {
+		return batchAdjust(msg, contents, h.user, h.charName, "needed transmutes", func(char storage.Character, name string, ct uint64) (string, error) {
+			name, _ = nearName("needed transmute", name, neededNames(char, "trans"))
+			char.IncrNeededTransmute(name, ct)
+			h.events.need(webhook.EventNeedAdded, h.user, char, "transmute", name, ct)
+			return fmt.Sprintf("+%d %s", ct, name), nil
+		})
 	}
 
-	ct, err := strconv.Atoi(ctStr)
+	itemName, ct, err := a.nameAndCount()
 	if err != nil {
-		return r, errors.Wrap(err, "could not interpret count to adjust transmute needs")
-	}
-
-	if ct < 0 {
-		return r, ErrPositiveValueRequired
+		return r, err
 	}
 
 	char, err := h.user.GetCharacter(h.charName)
@@ -138,9 +125,12 @@ func (h *needTransmuteHandler) HandleMessage(msg cmdhandler.Message) (cmdhandler
 		return r, errors.Wrap(err, "could not find character to adjust transmute needs")
 	}
 
-	char.IncrNeededTransmute(itemName, uint64(ct))
+	itemName, note := nearName("needed transmute", itemName, neededNames(char, "trans"))
+	char.IncrNeededTransmute(itemName, ct)
+	h.events.need(webhook.EventNeedAdded, h.user, char, "transmute", itemName, ct)
 
 	r.Description = fmt.Sprintf("marked %s as needing +%d transmutes for %s", h.charName, ct, itemName)
+	addNote(r, note)
 	return r, nil
 }
 
@@ -155,7 +145,7 @@ func (c *needCommands) helpChars(use, cmd string) func(msg cmdhandler.Message) (
 			To: cmdhandler.UserMentionString(msg.UserID()),
 		}
 
-		r.Description = fmt.Sprintf("Usage: %s %s [%s] [%s] [count?]\n\n", c.preCommand, cmd, "charname", use)
+		r.Description = fmt.Sprintf("Usage: %s %s [%s] [%s] [count?]\nor, for several at once: %s %s [%s] Name A x2, Name B, Name C x5\n\nQuote a name that ends in a number (\"Rune 7\") or give the count as --count N. The character can also be given as --char \"Name\".\n\n", c.preCommand, cmd, "charname", use, c.preCommand, cmd, "charname")
 
 		t, err := c.deps.UserAPI().NewTransaction(false)
 		if err != nil {
@@ -176,13 +166,20 @@ func (c *needCommands) helpChars(use, cmd string) func(msg cmdhandler.Message) (
 		}
 
 		sort.Strings(charNames)
-		r.Fields = []cmdhandler.EmbedField{
-			{
-				Name: "*Available Character Names*",
-				Val:  fmt.Sprintf("```\n%s\n```\n", strings.Join(charNames, "\n")),
-			},
+
+		a, err := parseArgs(msg.Contents())
+		if err != nil {
+			return r, nil
+		}
+
+		p := &embedPager{
+			to:          r.To,
+			title:       r.Title,
+			description: r.Description,
 		}
+		p.addSection("*Available Character Names*", charNames)
 
+		r, _, _ = p.page(a.page())
 		return r, nil
 	}
 }
@@ -192,6 +189,17 @@ func (c *needCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("skill name", "pts")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -205,30 +213,18 @@ func (c *needCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error
 			return r, errors.Wrap(err, "could not create user")
 		}
 	}
+	recordGuild(bUser, msg)
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " pts",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("skill name", "pts")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &needPointHandler{charName: char.GetName(), user: bUser})
-	}
-
-	r2, err := ch.HandleMessage(msg)
+	h := &needPointHandler{charName: charName, user: bUser, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -243,6 +239,7 @@ func (c *needCommands) point(msg cmdhandler.Message) (cmdhandler.Response, error
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save points need")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -252,6 +249,17 @@ func (c *needCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("item name", "item")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -265,29 +273,18 @@ func (c *needCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 			return r, errors.Wrap(err, "could not create user")
 		}
 	}
+	recordGuild(bUser, msg)
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " item",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("item name", "item")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &needItemHandler{charName: char.GetName(), user: bUser})
-	}
-	r2, err := ch.HandleMessage(msg)
+	h := &needItemHandler{charName: charName, user: bUser, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -302,6 +299,7 @@ func (c *needCommands) item(msg cmdhandler.Message) (cmdhandler.Response, error)
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save item need")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -311,6 +309,17 @@ func (c *needCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, e
 		To: cmdhandler.UserMentionString(msg.UserID()),
 	}
 
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if (a.len() == 0 && !a.has("char")) || a.word(0) == "help" {
+		return c.helpChars("item name", "trans")(msg)
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
 	t, err := c.deps.UserAPI().NewTransaction(true)
 	if err != nil {
 		return r, err
@@ -324,29 +333,18 @@ func (c *needCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, e
 			return r, errors.Wrap(err, "could not create user")
 		}
 	}
+	recordGuild(bUser, msg)
 
-	characters := bUser.GetCharacters()
-	charNames := make([]string, len(characters))
-	for i, char := range characters {
-		charNames[i] = char.GetName()
-	}
-
-	p := parser.NewParser(parser.Options{
-		CmdIndicator: " ",
-	})
-	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
-		PreCommand:  c.preCommand + " trans",
-		Placeholder: "charname",
-	})
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
 	if err != nil {
 		return r, err
 	}
 
-	ch.SetHandler("", cmdhandler.NewMessageHandler(c.helpChars("item name", "trans")))
-	for _, char := range characters {
-		ch.SetHandler(char.GetName(), &needTransmuteHandler{charName: char.GetName(), user: bUser})
-	}
-	r2, err := ch.HandleMessage(msg)
+	h := &needTransmuteHandler{charName: charName, user: bUser, events: newEventLog(msg)}
+	r2, err := h.HandleArgs(msg, rest)
+	addNote(r2, note)
 
 	if err != nil {
 		return r2, err
@@ -361,6 +359,7 @@ func (c *needCommands) transmute(msg cmdhandler.Message) (cmdhandler.Response, e
 	if err != nil {
 		return r2, errors.Wrap(err, "could not save transmute need")
 	}
+	h.events.publish(c.deps.Webhooks())
 
 	return r2, nil
 }
@@ -386,6 +385,8 @@ func NeedCommandHandler(deps dependencies, preCommand string) (*cmdhandler.Comma
 	ch.SetHandler("pts", cmdhandler.NewMessageHandler(nc.point))
 	ch.SetHandler("item", cmdhandler.NewMessageHandler(nc.item))
 	ch.SetHandler("trans", cmdhandler.NewMessageHandler(nc.transmute))
+	ch.SetHandler("set", cmdhandler.NewMessageHandler(nc.set))
+	ch.SetHandler("clear", cmdhandler.NewMessageHandler(nc.clear))
 
 	return ch, nil
 }
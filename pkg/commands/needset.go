@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// ErrValueRequired is the error returned when a set command has no =N value
+var ErrValueRequired = errors.New("value required (like =5)")
+
+// needKindNames are how each kind of need (from needEntries) is shown in replies
+var needKindNames = map[string]string{
+	"item":  "item",
+	"trans": "transmute",
+	"pts":   "skill",
+}
+
+// nameAndValue reads `[name] =N` arguments (also accepting `[name] = N` and
+// `[name] --count N`) for setting an exact count
+func nameAndValue(a cmdArgs) (string, uint64, error) {
+	valStr := ""
+	n := a.len()
+	switch {
+	case a.has("count"):
+		valStr = a.flag("count")
+	case n > 1 && !a.words[n-1].quoted && strings.HasPrefix(a.word(n-1), "=") && a.word(n-1) != "=":
+		valStr = strings.TrimPrefix(a.word(n-1), "=")
+		a = a.head(n - 1)
+	case n > 2 && a.word(n-2) == "=":
+		valStr = a.word(n - 1)
+		a = a.head(n - 2)
+	}
+
+	name := a.text(0)
+	if name == "" {
+		return "", 0, ErrItemNameRequired
+	}
+	if valStr == "" {
+		return "", 0, ErrValueRequired
+	}
+
+	val, err := strconv.Atoi(valStr)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "could not interpret value")
+	}
+	if val < 0 {
+		return "", 0, ErrPositiveValueRequired
+	}
+
+	return name, uint64(val), nil
+}
+
+// needDiff lists how a character's needs changed between two snapshots
+// taken with needEntries
+func needDiff(before, after []needEntry) []string {
+	key := func(e needEntry) string { return e.kind + "\x00" + e.name }
+
+	afterCounts := make(map[string]uint64, len(after))
+	for _, e := range after {
+		afterCounts[key(e)] = e.count
+	}
+
+	var lines []string
+	seen := make(map[string]bool, len(before))
+	for _, e := range before {
+		seen[key(e)] = true
+		ct := afterCounts[key(e)]
+		switch {
+		case ct == e.count:
+		case ct == 0:
+			lines = append(lines, fmt.Sprintf("%s (%s): %d -> 0, removed", e.name, needKindNames[e.kind], e.count))
+		default:
+			lines = append(lines, fmt.Sprintf("%s (%s): %d -> %d", e.name, needKindNames[e.kind], e.count, ct))
+		}
+	}
+
+	for _, e := range after {
+		if !seen[key(e)] {
+			lines = append(lines, fmt.Sprintf("%s (%s): 0 -> %d, added", e.name, needKindNames[e.kind], e.count))
+		}
+	}
+
+	return lines
+}
+
+// needDiffResponse describes the changes to a character's needs since before
+func needDiffResponse(msg cmdhandler.Message, char storage.Character, description string, before []needEntry) *cmdhandler.EmbedResponse {
+	changes := needDiff(before, needEntries(char))
+
+	p := &embedPager{
+		to:          cmdhandler.UserMentionString(msg.UserID()),
+		description: description,
+	}
+	if len(changes) == 0 {
+		p.description += "; nothing changed"
+	} else {
+		p.addSection(fmt.Sprintf("*Changes (%d)*", len(changes)), changes)
+	}
+
+	return p.summary()
+}
+
+func (c *needCommands) set(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	kind := strings.ToLower(a.word(0))
+	if _, ok := needKindNames[kind]; !ok {
+		return r, fmt.Errorf("usage: %s set [item|pts|trans] [charname] [name] =N", c.preCommand)
+	}
+	a = a.shift(1)
+
+	if a.len() == 0 && !a.has("char") {
+		return r, ErrCharacterNameRequired
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
+	t, err := c.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not find user")
+	}
+
+	charNames := characterNames(bUser.GetCharacters())
+	charName, rest := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
+	if err != nil {
+		return r, err
+	}
+
+	char, err := bUser.GetCharacter(charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character to set needs")
+	}
+
+	name, ct, err := nameAndValue(rest)
+	if err == ErrItemNameRequired && kind == "pts" {
+		err = ErrSkillNameRequired
+	}
+	if err != nil {
+		return r, err
+	}
+
+	name, nameNote := nearName("needed "+needKindNames[kind], name, neededNames(char, kind))
+
+	before := needEntries(char)
+	switch kind {
+	case "item":
+		char.SetNeededItem(name, ct)
+	case "trans":
+		char.SetNeededTransmute(name, ct)
+	case "pts":
+		char.SetNeededSkill(name, ct)
+	}
+
+	// only a raised count is a need added; lowering it is not fulfilling it
+	events := newEventLog(msg)
+	var prev uint64
+	for _, e := range before {
+		if e.kind == kind && e.name == name {
+			prev = e.count
+		}
+	}
+	if ct > prev {
+		events.need(webhook.EventNeedAdded, bUser, char, needKindNames[kind], name, ct-prev)
+	}
+
+	r2 := needDiffResponse(msg, char, fmt.Sprintf("set %s as needing %d of %s", charName, ct, name), before)
+	addNote(r2, note)
+	addNote(r2, nameNote)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r2, errors.Wrap(err, "could not save need")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r2, errors.Wrap(err, "could not save need")
+	}
+	events.publish(c.deps.Webhooks())
+
+	return r2, nil
+}
+
+func (c *needCommands) clear(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	what := strings.ToLower(a.word(0))
+	cleared, ok := map[string]string{
+		"items": "needed items",
+		"pts":   "needed skill points",
+		"trans": "needed transmutes",
+		"all":   "needs",
+	}[what]
+	if !ok {
+		return r, fmt.Errorf("usage: %s clear [items|pts|trans|all] [charname]", c.preCommand)
+	}
+	a = a.shift(1)
+
+	if a.len() == 0 && !a.has("char") {
+		return r, ErrCharacterNameRequired
+	}
+
+	autoCorrect := autoCorrectEnabled(c.deps, msg)
+
+	t, err := c.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not find user")
+	}
+
+	charNames := characterNames(bUser.GetCharacters())
+	charName, _ := a.character(charNames)
+	charName, note, err := resolveName("character", charName, charNames, autoCorrect)
+	if err != nil {
+		return r, err
+	}
+
+	char, err := bUser.GetCharacter(charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character to clear needs")
+	}
+
+	before := needEntries(char)
+
+	if what == "all" && !a.has("confirm") {
+		r.Description = fmt.Sprintf("this would clear all of the %d needs of %s; call `%s clear all %s --confirm` to go ahead", len(before), charName, c.preCommand, QuoteArg(charName))
+		addNote(r, note)
+		return r, nil
+	}
+
+	switch what {
+	case "items":
+		char.ClearNeededItems()
+	case "pts":
+		char.ClearNeededSkills()
+	case "trans":
+		char.ClearNeededTransmutes()
+	case "all":
+		char.ClearNeededItems()
+		char.ClearNeededSkills()
+		char.ClearNeededTransmutes()
+	}
+
+	r2 := needDiffResponse(msg, char, fmt.Sprintf("cleared the %s of %s", cleared, charName), before)
+	addNote(r2, note)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r2, errors.Wrap(err, "could not save cleared needs")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r2, errors.Wrap(err, "could not save cleared needs")
+	}
+
+	return r2, nil
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+)
+
+// discord's limits on embed sizes
+const (
+	maxFieldValueLength = 1024
+	maxEmbedLength      = 6000
+	maxEmbedFields      = 25
+)
+
+// room held back in each embed for the page footer
+const pageFooterReserve = 100
+
+var codeBlockOverhead = len(codeBlock(nil))
+
+func codeBlock(lines []string) string {
+	return fmt.Sprintf("```\n%s\n```\n", strings.Join(lines, "\n"))
+}
+
+// listSection is a named list of lines shown as one or more code block fields
+type listSection struct {
+	name  string
+	lines []string
+}
+
+// embedPager builds list embeds that fit within discord's limits. Sections too
+// long for one field are continued in further fields, and fields that do not
+// fit in one embed are moved on to further pages.
+type embedPager struct {
+	to          string
+	title       string
+	description string
+	sections    []listSection
+}
+
+func (p *embedPager) addSection(name string, lines []string) {
+	p.sections = append(p.sections, listSection{name: name, lines: lines})
+}
+
+func (p *embedPager) fields() []cmdhandler.EmbedField {
+	var fields []cmdhandler.EmbedField
+
+	for _, s := range p.sections {
+		name := s.name
+		var chunk []string
+		size := codeBlockOverhead
+
+		flush := func() {
+			fields = append(fields, cmdhandler.EmbedField{Name: name, Val: codeBlock(chunk)})
+			name = fmt.Sprintf("%s (cont.)", s.name)
+			chunk = nil
+			size = codeBlockOverhead
+		}
+
+		for _, line := range s.lines {
+			if len(line)+codeBlockOverhead > maxFieldValueLength {
+				line = line[:maxFieldValueLength-codeBlockOverhead-3] + "..."
+			}
+			if len(chunk) > 0 && size+len(line)+1 > maxFieldValueLength {
+				flush()
+			}
+			chunk = append(chunk, line)
+			size += len(line) + 1
+		}
+
+		// an empty section still shows, as an empty code block
+		if len(chunk) > 0 || len(s.lines) == 0 {
+			flush()
+		}
+	}
+
+	return fields
+}
+
+func (p *embedPager) pages() [][]cmdhandler.EmbedField {
+	budget := maxEmbedLength - len(p.title) - len(p.description) - pageFooterReserve
+
+	var pages [][]cmdhandler.EmbedField
+	var current []cmdhandler.EmbedField
+	size := 0
+	for _, f := range p.fields() {
+		fieldSize := len(f.Name) + len(f.Val)
+		if len(current) > 0 && (size+fieldSize > budget || len(current) == maxEmbedFields) {
+			pages = append(pages, current)
+			current = nil
+			size = 0
+		}
+		current = append(current, f)
+		size += fieldSize
+	}
+
+	if len(current) > 0 || len(pages) == 0 {
+		pages = append(pages, current)
+	}
+	return pages
+}
+
+func (p *embedPager) response(fields []cmdhandler.EmbedField, page, pages int) *cmdhandler.EmbedResponse {
+	r := &cmdhandler.EmbedResponse{
+		To:          p.to,
+		Title:       p.title,
+		Description: p.description,
+		Fields:      fields,
+	}
+	if pages > 1 {
+		r.FooterText = fmt.Sprintf("Page %d of %d. Add --page N to the command to see another page.", page, pages)
+	}
+	return r
+}
+
+// page builds the embed for one page, counting from 1 and clamped to the pages
+// there are, and reports which page it was and how many pages there are
+func (p *embedPager) page(page int) (*cmdhandler.EmbedResponse, int, int) {
+	pages := p.pages()
+	if page > len(pages) {
+		page = len(pages)
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	return p.response(pages[page-1], page, len(pages)), page, len(pages)
+}
+
+// responses builds every page as its own message, for places like direct
+// messages where there is no command to page with
+func (p *embedPager) responses() []cmdhandler.Response {
+	pages := p.pages()
+	resps := make([]cmdhandler.Response, len(pages))
+	for i, fields := range pages {
+		r := p.response(fields, i+1, len(pages))
+		if len(pages) > 1 {
+			r.FooterText = fmt.Sprintf("Part %d of %d", i+1, len(pages))
+		}
+		resps[i] = r
+	}
+	return resps
+}
+
+// summary builds a single embed for commands that change data, which cannot
+// be re-run to see another page; lines that do not fit are left out and
+// counted in the footer instead
+func (p *embedPager) summary() *cmdhandler.EmbedResponse {
+	pages := p.pages()
+	r := p.response(pages[0], 1, 1)
+
+	omitted := 0
+	for _, fields := range pages[1:] {
+		for _, f := range fields {
+			if f.Val != codeBlock(nil) {
+				omitted += strings.Count(f.Val, "\n") - 2
+			}
+		}
+	}
+	if omitted > 0 {
+		r.FooterText = fmt.Sprintf("...and %d more not shown", omitted)
+	}
+
+	return r
+}
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrListPrivate is the error returned when viewing a list its owner has not shared
+var ErrListPrivate = errors.New("that list is private")
+
+// targetUser parses the command arguments, splitting off a leading user
+// mention. With no mention, the target is the message author and other is false
+func targetUser(msg cmdhandler.Message) (userID string, a cmdArgs, other bool, err error) {
+	a, err = parseArgs(msg.Contents())
+	if err != nil {
+		return msg.UserID().ToString(), a, false, err
+	}
+
+	id, ok := userIDFromMention(a.word(0))
+	if !ok {
+		return msg.UserID().ToString(), a, false, nil
+	}
+
+	return id, a.shift(1), id != msg.UserID().ToString(), nil
+}
+
+// visibleCharacters returns the characters of user that can be shown to the
+// message author
+func visibleCharacters(user storage.User, msg cmdhandler.Message, other bool) []storage.Character {
+	chars := user.GetCharacters()
+	if !other {
+		return chars
+	}
+
+	visible := make([]storage.Character, 0, len(chars))
+	for _, char := range chars {
+		if storage.CanViewCharacter(user, char, msg.GuildID().ToString()) {
+			visible = append(visible, char)
+		}
+	}
+	return visible
+}
+
+// visibleCharacter returns the named character of user if it can be shown to
+// the message author
+func visibleCharacter(user storage.User, charName string, msg cmdhandler.Message, other bool) (storage.Character, error) {
+	char, err := findCharacter(user, charName)
+	if err != nil {
+		if other {
+			return nil, ErrListPrivate
+		}
+		return nil, err
+	}
+
+	if other && !storage.CanViewCharacter(user, char, msg.GuildID().ToString()) {
+		return nil, ErrListPrivate
+	}
+
+	return char, nil
+}
+
+type privacyCommands struct {
+	preCommand string
+	deps       dependencies
+}
+
+func (c *privacyCommands) privacy(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	// the setting is the last word, after the (possibly several word) name
+	var mode, charName string
+	if a.len() > 0 {
+		mode = strings.ToLower(a.word(a.len() - 1))
+		charName = a.prefix(a.len() - 1)
+	}
+	if a.has("char") {
+		charName = a.flag("char")
+	}
+
+	t, err := c.deps.UserAPI().NewTransaction(mode != "")
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not create user")
+	}
+
+	switch {
+	case mode == "":
+		r.Description = fmt.Sprintf("your lists are `%s`", bUser.GetPrivacy())
+		return r, nil
+	case charName == "":
+		switch mode {
+		case storage.PrivacyPublic, storage.PrivacyGuild, storage.PrivacyPrivate, storage.PrivacyCharacter:
+		default:
+			return r, errors.New("privacy must be one of public, guild, private or character")
+		}
+		bUser.SetPrivacy(mode)
+		r.Description = fmt.Sprintf("your lists are now `%s`", mode)
+	default:
+		switch mode {
+		case storage.PrivacyPublic, storage.PrivacyGuild, storage.PrivacyPrivate:
+		default:
+			return r, errors.New("character privacy must be one of public, guild or private")
+		}
+
+		var char storage.Character
+		for _, ch := range bUser.GetCharacters() {
+			if strings.EqualFold(ch.GetName(), charName) {
+				char = ch
+			}
+		}
+		if char == nil {
+			return r, storage.ErrCharacterNotExist
+		}
+
+		char.SetPrivacy(mode)
+		r.Description = fmt.Sprintf("%s is now `%s`", char.GetName(), mode)
+		if bUser.GetPrivacy() != storage.PrivacyCharacter {
+			r.Description += fmt.Sprintf("; call `%s character` for per-character settings to apply", c.preCommand)
+		}
+	}
+
+	recordGuild(bUser, msg)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save privacy setting")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save privacy setting")
+	}
+
+	return r, nil
+}
+
+// PrivacyHandler creates a handler for !privacy commands
+func PrivacyHandler(deps dependencies, preCommand string) cmdhandler.MessageHandler {
+	pc := privacyCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	return cmdhandler.NewMessageHandler(pc.privacy)
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type repliesDependencies interface {
+	UserAPI() storage.UserAPI
+}
+
+type repliesCommands struct {
+	preCommand string
+	deps       repliesDependencies
+}
+
+func (c *repliesCommands) replies(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	mode := strings.ToLower(a.text(0))
+
+	t, err := c.deps.UserAPI().NewTransaction(mode != "")
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "could not create user")
+	}
+
+	switch mode {
+	case "":
+		current := "channel"
+		if bUser.GetDMReplies() {
+			current = "dm"
+		}
+		r.Description = fmt.Sprintf("command replies go to `%s`; call `replies [dm|channel]` to change this", current)
+		return r, nil
+	case "dm", "channel":
+	default:
+		return r, errors.New("reply mode must be one of dm or channel")
+	}
+
+	bUser.SetDMReplies(mode == "dm")
+	recordGuild(bUser, msg)
+
+	err = t.SaveUser(bUser)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save reply setting")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save reply setting")
+	}
+
+	r.Description = fmt.Sprintf("command replies set to `%s`", mode)
+	return r, nil
+}
+
+// RepliesHandler creates a handler for !replies commands
+func RepliesHandler(deps repliesDependencies, preCommand string) cmdhandler.MessageHandler {
+	rc := repliesCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	return cmdhandler.NewMessageHandler(rc.replies)
+}
@@ -0,0 +1,316 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrBadInterval is the error returned when a schedule interval cannot be parsed
+var ErrBadInterval = errors.New("could not understand interval; try something like 30m, 12h, 3d or 2w")
+
+const minDigestInterval = time.Hour
+
+type scheduleDependencies interface {
+	ScheduleAPI() storage.ScheduleAPI
+}
+
+// parseInterval understands a count followed by one of m, h, d or w, and the
+// words daily and weekly
+func parseInterval(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	if len(s) < 2 {
+		return 0, ErrBadInterval
+	}
+
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, ErrBadInterval
+	}
+
+	ct, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || ct <= 0 {
+		return 0, ErrBadInterval
+	}
+
+	return time.Duration(ct) * unit, nil
+}
+
+type scheduleCommands struct {
+	preCommand string
+	deps       scheduleDependencies
+}
+
+func (c *scheduleCommands) remind(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	if a.len() == 1 && a.word(0) == "list" {
+		return c.listReminders(msg)
+	}
+
+	// the interval follows the last (unquoted) "in"
+	idx := -1
+	for i := a.len() - 2; i >= 0; i-- {
+		if !a.words[i].quoted && strings.EqualFold(a.word(i), "in") {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return r, fmt.Errorf("usage: %s [charname] [item] in [interval]", c.preCommand)
+	}
+
+	after, err := parseInterval(a.text(idx + 1))
+	if err != nil {
+		return r, err
+	}
+
+	if idx == 0 && !a.has("char") {
+		return r, ErrCharacterNameRequired
+	}
+	a = a.head(idx)
+
+	t, err := c.deps.ScheduleAPI().NewTransaction(true)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.UserTx().AddUser(msg.UserID().ToString()) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find user")
+	}
+
+	charName, rest := a.character(characterNames(bUser.GetCharacters()))
+	char, err := findCharacter(bUser, charName)
+	if err != nil {
+		return r, errors.Wrap(err, "could not find character")
+	}
+
+	itemName := rest.text(0)
+	if itemName == "" {
+		return r, ErrItemNameRequired
+	}
+
+	schedule, err := t.AddSchedule(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find schedule")
+	}
+
+	reminder := schedule.AddReminder(char.GetName(), itemName, time.Now().Add(after))
+
+	err = t.SaveSchedule(schedule)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save reminder")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save reminder")
+	}
+
+	r.Description = fmt.Sprintf("will remind you about %s for %s at %s", itemName, char.GetName(), reminder.Due.UTC().Format("2006-01-02 15:04 MST"))
+	return r, nil
+}
+
+func (c *scheduleCommands) listReminders(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.ScheduleAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	schedule, err := t.AddSchedule(msg.UserID().ToString()) // add or get empty (don't save)
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find schedule")
+	}
+
+	reminders := schedule.GetReminders()
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i].Due.Before(reminders[j].Due) })
+
+	lines := make([]string, 0, len(reminders))
+	for _, rem := range reminders {
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", rem.Due.UTC().Format("2006-01-02 15:04"), rem.Item, rem.Character))
+	}
+
+	p := &embedPager{
+		to:    r.To,
+		title: "__Reminders__",
+	}
+	p.addSection(fmt.Sprintf("*Pending Reminders (%d)*", len(lines)), lines)
+
+	r, _, _ = p.page(a.page())
+	return r, nil
+}
+
+func (c *scheduleCommands) digest(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+	arg := strings.TrimPrefix(a.text(0), "every ")
+
+	t, err := c.deps.ScheduleAPI().NewTransaction(arg != "")
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	schedule, err := t.AddSchedule(msg.UserID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find schedule")
+	}
+
+	if arg == "" {
+		if schedule.GetDigestInterval() == 0 {
+			r.Description = fmt.Sprintf("digests are off; call `%s weekly` (or `%s every 3d`) to turn them on", c.preCommand, c.preCommand)
+		} else {
+			r.Description = fmt.Sprintf("digests every %s; the next one is due at %s", schedule.GetDigestInterval(), schedule.GetNextDigest().UTC().Format("2006-01-02 15:04 MST"))
+		}
+		return r, nil
+	}
+
+	var interval time.Duration
+	if arg != "off" {
+		interval, err = parseInterval(arg)
+		if err != nil {
+			return r, err
+		}
+
+		if interval < minDigestInterval {
+			return r, fmt.Errorf("digests can be sent at most every %s", minDigestInterval)
+		}
+	}
+
+	schedule.SetDigest(interval, time.Now().Add(interval))
+
+	err = t.SaveSchedule(schedule)
+	if err != nil {
+		return r, errors.Wrap(err, "could not save digest schedule")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return r, errors.Wrap(err, "could not save digest schedule")
+	}
+
+	if interval == 0 {
+		r.Description = "digests turned off"
+	} else {
+		r.Description = fmt.Sprintf("will send you a digest of your needs every %s", interval)
+	}
+	return r, nil
+}
+
+// RemindHandler creates a handler for !remind commands
+func RemindHandler(deps scheduleDependencies, preCommand string) cmdhandler.MessageHandler {
+	sc := scheduleCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	return cmdhandler.NewMessageHandler(sc.remind)
+}
+
+// DigestHandler creates a handler for !digest commands
+func DigestHandler(deps scheduleDependencies, preCommand string) cmdhandler.MessageHandler {
+	sc := scheduleCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+	return cmdhandler.NewMessageHandler(sc.digest)
+}
+
+// DigestResponses builds the direct messages listing every outstanding need of
+// a user, split over as many messages as it takes to fit
+func DigestResponses(user storage.User) []cmdhandler.Response {
+	p := &embedPager{
+		title:       "__Outstanding Needs__",
+		description: "This is your scheduled digest. Call `digest off` to stop receiving these.",
+	}
+
+	chars := user.GetCharacters()
+	sort.Slice(chars, func(i, j int) bool { return chars[i].GetName() < chars[j].GetName() })
+
+	for _, char := range chars {
+		items, itemCt := itemsLines(char)
+		skills, skillCt := skillsLines(char)
+		trans, transCt := transLines(char)
+
+		if itemCt+skillCt+transCt == 0 {
+			continue
+		}
+
+		lines := append(append(items, trans...), skills...)
+		p.addSection(fmt.Sprintf("*%s (%d items, %d transmutes, %d points)*", char.GetName(), itemCt, transCt, skillCt), lines)
+	}
+
+	if len(p.sections) == 0 {
+		p.description = "You have no outstanding needs. Call `digest off` to stop receiving these."
+		return []cmdhandler.Response{p.response(nil, 1, 1)}
+	}
+
+	return p.responses()
+}
+
+// ReminderResponse builds the direct message for a due reminder
+func ReminderResponse(user storage.User, reminder storage.Reminder) cmdhandler.Response {
+	r := &cmdhandler.SimpleEmbedResponse{
+		Description: fmt.Sprintf("Reminder: %s wanted %s.", reminder.Character, reminder.Item),
+	}
+
+	char, err := user.GetCharacter(reminder.Character)
+	if err != nil {
+		return r
+	}
+
+	if item, ok := findNeededItem(char, reminder.Item); ok {
+		r.Description = fmt.Sprintf("Reminder: %s still needs %s x%d.", char.GetName(), item.Name(), item.Count())
+	}
+
+	return r
+}
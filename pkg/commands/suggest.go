@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+const maxSuggestions = 3
+
+// suggestionError is the error returned when a name matches nothing, listing
+// the close matches there are
+type suggestionError struct {
+	what    string
+	name    string
+	matches []string
+}
+
+func (e suggestionError) Error() string {
+	if len(e.matches) == 0 {
+		return fmt.Sprintf("there is no %s named '%s'", e.what, e.name)
+	}
+	return fmt.Sprintf("there is no %s named '%s'; did you mean %s?", e.what, e.name, orList(e.matches))
+}
+
+func orList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = fmt.Sprintf("'%s'", n)
+	}
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return fmt.Sprintf("%s or %s", strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+}
+
+// editDistance is the case-insensitive edit distance between a and b, counting
+// insertions, deletions, substitutions and swaps of adjacent letters
+func editDistance(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, minInt(d[i][j-1]+1, d[i-1][j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// closeMatches returns the candidates within a few edits of name, closest
+// first. Longer names tolerate more edits.
+func closeMatches(name string, candidates []string) []string {
+	limit := len([]rune(name)) / 4
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > 3 {
+		limit = 3
+	}
+
+	type match struct {
+		name string
+		dist int
+	}
+
+	seen := map[string]bool{}
+	var matches []match
+	for _, c := range candidates {
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		if d := editDistance(name, c); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if len(names) == maxSuggestions {
+			break
+		}
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// resolveName finds name among candidates. An exact match, ignoring case, is
+// returned as-is; with autocorrect on, a single close match is returned along
+// with a note saying so. Otherwise the error suggests what was probably meant.
+func resolveName(what, name string, candidates []string, autoCorrect bool) (string, string, error) {
+	for _, c := range candidates {
+		if strings.EqualFold(c, name) {
+			return c, "", nil
+		}
+	}
+
+	matches := closeMatches(name, candidates)
+	if autoCorrect && len(matches) == 1 {
+		return matches[0], fmt.Sprintf("(assumed '%s' meant the %s '%s')", name, what, matches[0]), nil
+	}
+
+	return name, "", suggestionError{what: what, name: name, matches: matches}
+}
+
+// autoCorrectEnabled reports whether the message's guild has turned on
+// autocorrect. Call it before opening a write transaction, since it reads the
+// guild settings in a transaction of its own.
+func autoCorrectEnabled(deps dependencies, msg cmdhandler.Message) bool {
+	if msg.GuildID() == 0 {
+		return false
+	}
+
+	s, err := storage.GetSettings(deps.GuildAPI(), msg.GuildID())
+	if err != nil {
+		return false
+	}
+	return s.AutoCorrect()
+}
+
+// correctLeadingName resolves the first argument of msg against names,
+// returning the message with the argument swapped for its match
+func correctLeadingName(msg cmdhandler.Message, what string, names []string, autoCorrect bool) (cmdhandler.Message, string, error) {
+	contents := msg.Contents()
+	args := strings.Fields(contents)
+	if len(args) == 0 {
+		return msg, "", nil
+	}
+
+	first := args[0]
+	resolved, note := first, ""
+	if !containsString(names, first) {
+		var err error
+		resolved, note, err = resolveName(what, first, names, autoCorrect)
+		if err != nil {
+			return msg, "", err
+		}
+	}
+
+	// handlers split the first argument off at a space, so one must follow it
+	// even when the rest starts on a new line (like a code block)
+	idx := strings.Index(contents, first)
+	rest := strings.TrimLeft(contents[idx+len(first):], " \t\r\n")
+	return cmdhandler.NewWithContents(msg, contents[:idx]+resolved+" "+rest), note, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// addNote appends an explanatory line, like an autocorrection, to a response
+func addNote(resp cmdhandler.Response, note string) {
+	if note == "" {
+		return
+	}
+
+	switch r := resp.(type) {
+	case *cmdhandler.SimpleEmbedResponse:
+		r.Description = strings.TrimSpace(r.Description + "\n" + note)
+	case *cmdhandler.EmbedResponse:
+		r.Description = strings.TrimSpace(r.Description + "\n" + note)
+	case *componentResponse:
+		r.Description = strings.TrimSpace(r.Description + "\n" + note)
+	}
+}
+
+// findCharacter gets the named character of user, suggesting close names when
+// there is no such character
+func findCharacter(user storage.User, charName string) (storage.Character, error) {
+	char, err := user.GetCharacter(charName)
+	if err != storage.ErrCharacterNotExist {
+		return char, err
+	}
+
+	resolved, _, err := resolveName("character", charName, characterNames(user.GetCharacters()), false)
+	if err != nil {
+		return nil, err
+	}
+	return user.GetCharacter(resolved)
+}
+
+// nearName checks a possibly new name against the existing ones, returning
+// the existing spelling for a match that differs only in case, or else a note
+// mentioning a single close match. New names are allowed, so it never
+// corrects to a different name.
+func nearName(what, name string, existing []string) (string, string) {
+	for _, e := range existing {
+		if strings.EqualFold(e, name) {
+			return e, ""
+		}
+	}
+
+	if matches := closeMatches(name, existing); len(matches) == 1 {
+		return name, fmt.Sprintf("(did you mean the %s '%s'?)", what, matches[0])
+	}
+	return name, ""
+}
+
+// neededNames lists the names of one kind (item, trans or pts) of a character's needs
+func neededNames(char storage.Character, kind string) []string {
+	var names []string
+	for _, e := range needEntries(char) {
+		if e.kind == kind {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+func characterNames(chars []storage.Character) []string {
+	names := make([]string, len(chars))
+	for i, c := range chars {
+		names[i] = c.GetName()
+	}
+	return names
+}
+
+// subcommandSuggester wraps a subcommand handler so that an unknown subcommand
+// gets a "did you mean" reply (or, with autocorrect on, runs the close match)
+// instead of the generic help text
+type subcommandSuggester struct {
+	deps    dependencies
+	handler cmdhandler.MessageHandler
+	names   []string
+}
+
+func suggestSubcommands(deps dependencies, handler cmdhandler.MessageHandler, names ...string) cmdhandler.MessageHandler {
+	return &subcommandSuggester{
+		deps:    deps,
+		handler: handler,
+		names:   append(names, "help"),
+	}
+}
+
+func (s *subcommandSuggester) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	args := strings.Fields(msg.Contents())
+	if len(args) == 0 {
+		return s.handler.HandleMessage(msg)
+	}
+
+	if containsString(s.names, args[0]) || len(closeMatches(args[0], s.names)) == 0 {
+		// leave exact and hopeless cases to the handler's own help
+		return s.handler.HandleMessage(msg)
+	}
+
+	msg, note, err := correctLeadingName(msg, "subcommand", s.names, autoCorrectEnabled(s.deps, msg))
+	if err != nil {
+		return &cmdhandler.SimpleEmbedResponse{To: cmdhandler.UserMentionString(msg.UserID())}, err
+	}
+
+	resp, err := s.handler.HandleMessage(msg)
+	addNote(resp, note)
+	return resp, err
+}
@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/gsmcwhirter/go-util/parser"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// maxWebhooks is how many webhooks a guild can have
+const maxWebhooks = 10
+
+// ErrTooManyWebhooks is the error returned when a guild already has maxWebhooks webhooks
+var ErrTooManyWebhooks = fmt.Errorf("a guild can have at most %d webhooks", maxWebhooks)
+
+type webhookCommands struct {
+	preCommand string
+	deps       configDependencies
+}
+
+// webhookEvents reads the events to subscribe to; none (or "all") is every event
+func webhookEvents(a cmdArgs) ([]string, error) {
+	var events []string
+	for i := 0; i < a.len(); i++ {
+		e := strings.ToLower(a.word(i))
+		if e == "all" {
+			return webhook.Events(), nil
+		}
+		if !webhook.IsEvent(e) {
+			return nil, fmt.Errorf("unknown event '%s'; the events are %s", e, strings.Join(webhook.Events(), ", "))
+		}
+		events = append(events, e)
+	}
+
+	if len(events) == 0 {
+		return webhook.Events(), nil
+	}
+	return events, nil
+}
+
+func (c *webhookCommands) add(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	u, err := webhook.CheckURL(a.word(0))
+	if err != nil {
+		return r, err
+	}
+
+	events, err := webhookEvents(a.shift(1))
+	if err != nil {
+		return r, err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return r, err
+	}
+
+	hook, err := c.save(msg, func(hooks storage.Webhooks) (storage.Webhook, error) {
+		if len(hooks.GetWebhooks()) >= maxWebhooks {
+			return storage.Webhook{}, ErrTooManyWebhooks
+		}
+		return hooks.AddWebhook(u.String(), secret, events, msg.UserID().ToString(), time.Now()), nil
+	})
+	if err != nil {
+		return r, err
+	}
+
+	// the secret goes by DM so it is not posted in the channel; a webhook
+	// whose secret could not be sent is no use, so it is removed again
+	err = c.deps.DMSender().SendDM(msg.Context(), msg.UserID(), (&cmdhandler.SimpleEmbedResponse{
+		Description: fmt.Sprintf("Webhook #%d for %s\nSigning secret: `%s`\n\nEach request has an X-HaveWant-Signature header of `sha256=` and the hex HMAC-SHA256, keyed with this secret, of the X-HaveWant-Timestamp header, a `.`, and the request body. Keep the secret private.", hook.ID, hook.URL, secret),
+	}).ToMessage())
+	if err != nil {
+		_, _ = c.save(msg, func(hooks storage.Webhooks) (storage.Webhook, error) {
+			return hook, hooks.RemoveWebhook(hook.ID)
+		})
+		return r, errors.New("could not send you the webhook secret; check that you allow direct messages from server members")
+	}
+
+	r.Description = fmt.Sprintf("added webhook #%d for %s (%s); sent you its signing secret by direct message", hook.ID, webhook.RedactURL(hook.URL), strings.Join(hook.Events, ", "))
+	return r, nil
+}
+
+// save changes the guild's webhooks with change in a transaction of its own
+func (c *webhookCommands) save(msg cmdhandler.Message, change func(hooks storage.Webhooks) (storage.Webhook, error)) (storage.Webhook, error) {
+	t, err := c.deps.WebhookAPI().NewTransaction(true)
+	if err != nil {
+		return storage.Webhook{}, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	hooks, err := t.AddWebhooks(msg.GuildID().ToString())
+	if err != nil {
+		return storage.Webhook{}, errors.Wrap(err, "unable to find webhooks")
+	}
+
+	hook, err := change(hooks)
+	if err != nil {
+		return hook, err
+	}
+
+	err = t.SaveWebhooks(hooks)
+	if err != nil {
+		return hook, errors.Wrap(err, "could not save webhooks")
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return hook, errors.Wrap(err, "could not save webhooks")
+	}
+
+	return hook, nil
+}
+
+func (c *webhookCommands) list(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	t, err := c.deps.WebhookAPI().NewTransaction(false)
+	if err != nil {
+		return r, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	hooks, err := t.AddWebhooks(msg.GuildID().ToString())
+	if err != nil {
+		return r, errors.Wrap(err, "unable to find webhooks")
+	}
+
+	lines := []string{}
+	for _, h := range hooks.GetWebhooks() {
+		lines = append(lines, fmt.Sprintf("#%d %s\n    %s (added by %s)", h.ID, webhook.RedactURL(h.URL), strings.Join(h.Events, ", "), userMentionString(h.Creator)))
+	}
+
+	if len(lines) == 0 {
+		r.Description = fmt.Sprintf("no webhooks; add one with `%s add [url] [events?]`", c.preCommand)
+		return r, nil
+	}
+
+	r.Description = strings.Join(lines, "\n")
+	return r, nil
+}
+
+func (c *webhookCommands) remove(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	a, err := parseArgs(msg.Contents())
+	if err != nil {
+		return r, err
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(a.word(0), "#"), 10, 64)
+	if err != nil {
+		return r, fmt.Errorf("usage: %s remove [webhook number]", c.preCommand)
+	}
+
+	hook, err := c.save(msg, func(hooks storage.Webhooks) (storage.Webhook, error) {
+		hook, err := hooks.GetWebhook(id)
+		if err != nil {
+			return hook, err
+		}
+		return hook, hooks.RemoveWebhook(id)
+	})
+	if err != nil {
+		return r, err
+	}
+
+	r.Description = fmt.Sprintf("removed webhook #%d for %s", hook.ID, webhook.RedactURL(hook.URL))
+	return r, nil
+}
+
+// WebhookCommandHandler creates a handler for the !config-hw webhook commands
+func WebhookCommandHandler(deps configDependencies, preCommand string) (*cmdhandler.CommandHandler, error) {
+	p := parser.NewParser(parser.Options{
+		CmdIndicator: " ",
+	})
+	wc := webhookCommands{
+		preCommand: preCommand,
+		deps:       deps,
+	}
+
+	ch, err := cmdhandler.NewCommandHandler(p, cmdhandler.Options{
+		PreCommand:          preCommand,
+		Placeholder:         "action",
+		HelpOnEmptyCommands: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch.SetHandler("add", cmdhandler.NewMessageHandler(wc.add))
+	ch.SetHandler("list", cmdhandler.NewMessageHandler(wc.list))
+	ch.SetHandler("remove", cmdhandler.NewMessageHandler(wc.remove))
+
+	return ch, nil
+}
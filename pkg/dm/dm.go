@@ -0,0 +1,131 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gsmcwhirter/discord-bot-lib/httpclient"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/metrics"
+)
+
+// ErrSendFailed is the error returned when discord rejects a message
+var ErrSendFailed = errors.New("discord rejected the message")
+
+type dependencies interface {
+	HTTPClient() httpclient.HTTPClient
+	MessageRateLimiter() *rate.Limiter
+}
+
+// Sender sends messages to discord users and channels outside of a command response
+type Sender interface {
+	SendDM(ctx context.Context, uid snowflake.Snowflake, m json.Marshaler) error
+	SendMessage(ctx context.Context, cid snowflake.Snowflake, m json.Marshaler) error
+}
+
+// Options is how to point a Sender at the discord api
+type Options struct {
+	APIURL string
+}
+
+type sender struct {
+	deps     dependencies
+	apiURL   string
+	channels sync.Map
+}
+
+// NewSender creates a new Sender
+func NewSender(deps dependencies, opts Options) Sender {
+	return &sender{
+		deps:   deps,
+		apiURL: opts.APIURL,
+	}
+}
+
+func (s *sender) dmChannel(ctx context.Context, uid snowflake.Snowflake) (snowflake.Snowflake, error) {
+	if cid, ok := s.channels.Load(uid); ok {
+		return cid.(snowflake.Snowflake), nil
+	}
+
+	body, err := json.Marshal(map[string]string{"recipient_id": uid.ToString()})
+	if err != nil {
+		return 0, err
+	}
+
+	header := http.Header{}
+	header.Add("Content-Type", "application/json")
+
+	resp, respBody, err := s.deps.HTTPClient().PostBody(ctx, fmt.Sprintf("%s/users/@me/channels", s.apiURL), &header, bytes.NewReader(body))
+	if err != nil {
+		metrics.SendFailed(0)
+		return 0, errors.Wrap(err, "could not create dm channel")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		metrics.SendFailed(resp.StatusCode)
+		return 0, errors.Wrap(ErrSendFailed, fmt.Sprintf("dm channel status %d", resp.StatusCode))
+	}
+
+	channel := struct {
+		ID string `json:"id"`
+	}{}
+	err = json.Unmarshal(respBody, &channel)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not parse dm channel")
+	}
+
+	cid, err := snowflake.FromString(channel.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not parse dm channel id")
+	}
+
+	s.channels.Store(uid, cid)
+	return cid, nil
+}
+
+func (s *sender) SendDM(ctx context.Context, uid snowflake.Snowflake, m json.Marshaler) error {
+	cid, err := s.dmChannel(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	return s.SendMessage(ctx, cid, m)
+}
+
+func (s *sender) SendMessage(ctx context.Context, cid snowflake.Snowflake, m json.Marshaler) error {
+	body, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	waitStart := time.Now()
+	err = s.deps.MessageRateLimiter().Wait(ctx)
+	metrics.RateLimitWait("sender", time.Since(waitStart))
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Add("Content-Type", "application/json")
+
+	resp, _, err := s.deps.HTTPClient().PostBody(ctx, fmt.Sprintf("%s/channels/%s/messages", s.apiURL, cid.ToString()), &header, bytes.NewReader(body))
+	if err != nil {
+		metrics.SendFailed(0)
+		return errors.Wrap(err, "could not send message")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		metrics.SendFailed(resp.StatusCode)
+		return errors.Wrap(ErrSendFailed, fmt.Sprintf("status %d", resp.StatusCode))
+	}
+
+	return nil
+}
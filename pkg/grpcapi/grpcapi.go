@@ -0,0 +1,90 @@
+package grpcapi
+
+//go:generate protoc --go_out=plugins=grpc,Muserapi.proto=github.com/gsmcwhirter/discord-have-want-bot/pkg/storage,Mguildapi.proto=github.com/gsmcwhirter/discord-have-want-bot/pkg/storage:. --proto_path=. --proto_path=../storage ./havewant.proto
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/apiservice"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type dependencies interface {
+	Logger() log.Logger
+	APIService() apiservice.Service
+}
+
+type service struct {
+	deps dependencies
+	api  apiservice.Service
+}
+
+// NewServer creates a grpc server with the HaveWant service registered on it;
+// calls need an `authorization: Bearer <token>` metadata entry with one of the
+// api service's tokens
+func NewServer(deps dependencies) *grpc.Server {
+	s := &service{
+		deps: deps,
+		api:  deps.APIService(),
+	}
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.intercept))
+	RegisterHaveWantServer(srv, s)
+	return srv
+}
+
+// intercept checks the token a call was made with, and turns the errors the
+// calls return into grpc statuses
+func (s *service) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !s.authorized(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "missing or unknown token")
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, s.toStatus(info.FullMethod, err)
+	}
+	return resp, nil
+}
+
+func (s *service) authorized(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	for _, auth := range md.Get("authorization") {
+		if s.api.Authorized(auth) {
+			return true
+		}
+	}
+	return false
+}
+
+// toStatus picks the grpc code for err: missing records are NotFound, bad
+// input is InvalidArgument, and anything else is logged as Internal
+func (s *service) toStatus(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch errors.Cause(err) {
+	case storage.ErrUserNotExist, storage.ErrCharacterNotExist:
+		return status.Error(codes.NotFound, err.Error())
+	case apiservice.ErrInvalidRequest, storage.ErrBadSetting, storage.ErrBadSettingValue:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case apiservice.ErrCharacterExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		_ = level.Error(s.deps.Logger()).Log("message", "error handling grpc call", "method", method, "err", err)
+		return status.Error(codes.Internal, err.Error())
+	}
+}
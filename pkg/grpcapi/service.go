@@ -0,0 +1,143 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/apiservice"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// protoUser copies the stored record of a user
+func protoUser(u storage.User) (*storage.ProtoUser, error) {
+	b, err := u.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	pu := &storage.ProtoUser{}
+	err = proto.Unmarshal(b, pu)
+	return pu, errors.Wrap(err, "could not copy user record")
+}
+
+// protoCharacter copies the stored record of one of a user's characters
+func protoCharacter(u storage.User, charName string) (*storage.ProtoCharacter, error) {
+	pu, err := protoUser(u)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, ok := pu.Characters[charName]
+	if !ok {
+		return nil, storage.ErrCharacterNotExist
+	}
+	return pc, nil
+}
+
+// protoGuild copies the stored record of a guild
+func protoGuild(g storage.Guild) (*storage.ProtoGuild, error) {
+	b, err := g.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	pg := &storage.ProtoGuild{}
+	err = proto.Unmarshal(b, pg)
+	return pg, errors.Wrap(err, "could not copy guild record")
+}
+
+// needKinds maps the kinds of need in the requests to the api service's
+var needKinds = map[NeedKind]string{
+	NeedKind_ITEM:      apiservice.NeedItem,
+	NeedKind_SKILL:     apiservice.NeedSkill,
+	NeedKind_TRANSMUTE: apiservice.NeedTransmute,
+}
+
+func (s *service) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	users, err := s.api.ListUsers(req.Guild)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListUsersResponse{}
+	for _, u := range users {
+		pu, err := protoUser(u)
+		if err != nil {
+			return nil, err
+		}
+		resp.Users = append(resp.Users, pu)
+	}
+
+	return resp, nil
+}
+
+func (s *service) GetUser(ctx context.Context, req *UserRequest) (*storage.ProtoUser, error) {
+	bUser, err := s.api.GetUser(req.User)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoUser(bUser)
+}
+
+func (s *service) GetCharacter(ctx context.Context, req *CharacterRequest) (*storage.ProtoCharacter, error) {
+	bUser, char, err := s.api.GetCharacter(req.User, req.Character)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoCharacter(bUser, char.GetName())
+}
+
+func (s *service) CreateCharacter(ctx context.Context, req *CharacterRequest) (*storage.ProtoCharacter, error) {
+	bUser, char, err := s.api.CreateCharacter(req.User, req.Character)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoCharacter(bUser, char.GetName())
+}
+
+func (s *service) DeleteCharacter(ctx context.Context, req *CharacterRequest) (*DeleteCharacterResponse, error) {
+	if err := s.api.DeleteCharacter(req.User, req.Character); err != nil {
+		return nil, err
+	}
+
+	return &DeleteCharacterResponse{}, nil
+}
+
+func (s *service) SetNeed(ctx context.Context, req *SetNeedRequest) (*storage.ProtoCharacter, error) {
+	kind, ok := needKinds[req.Kind]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown kind of need %v", req.Kind)
+	}
+
+	bUser, char, err := s.api.SetNeed(req.User, req.Character, kind, req.Name, req.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoCharacter(bUser, char.GetName())
+}
+
+func (s *service) GetGuild(ctx context.Context, req *GuildRequest) (*storage.ProtoGuild, error) {
+	bGuild, err := s.api.GetGuild(req.Guild)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoGuild(bGuild)
+}
+
+func (s *service) SetGuildSetting(ctx context.Context, req *SetGuildSettingRequest) (*storage.ProtoGuild, error) {
+	bGuild, err := s.api.SetGuildSetting(req.Guild, req.Name, req.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoGuild(bGuild)
+}
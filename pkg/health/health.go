@@ -0,0 +1,274 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gsmcwhirter/discord-bot-lib/bot"
+	"github.com/gsmcwhirter/discord-bot-lib/etfapi"
+	"github.com/gsmcwhirter/discord-bot-lib/wsclient"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrNotReady is the error returned when one of the readiness checks fails
+var ErrNotReady = errors.New("not ready")
+
+// opInvalidSession is the gateway opcode telling the bot its session is gone
+// and it must identify again
+const opInvalidSession = 9
+
+// maxInvalidSessionSize bounds the frames checked for an invalid session;
+// those frames are tiny, so bigger ones are not decoded a second time
+const maxInvalidSessionSize = 64
+
+type dependencies interface {
+	UserAPI() storage.UserAPI
+}
+
+// Monitor tracks the state of the gateway connection and storage, to tell a
+// hung bot from a healthy one
+type Monitor interface {
+	// SetAuthenticated records whether the bot has authenticated with discord
+	SetAuthenticated(bool)
+
+	// ConnectToBot follows the gateway session: the bot is authenticated from
+	// READY or RESUMED until the session is invalidated or the connection drops
+	ConnectToBot(bot.DiscordBot)
+
+	// WrapDialer watches the gateway connections made with d
+	WrapDialer(d wsclient.Dialer) wsclient.Dialer
+
+	// Checks runs the readiness checks, in a fixed order
+	Checks() []Check
+
+	// Ready is nil when all of the readiness checks pass
+	Ready() error
+
+	// HealthzHandler answers whether the process is up
+	HealthzHandler() http.Handler
+
+	// ReadyzHandler answers whether the bot is ready, listing the checks
+	ReadyzHandler() http.Handler
+}
+
+// Check is the result of one readiness check
+type Check struct {
+	Name string
+	Err  error
+}
+
+// Options is how to set the thresholds for a Monitor
+type Options struct {
+	// HeartbeatMaxAge is how long the gateway can be silent before the bot is
+	// not ready; discord acknowledges every heartbeat, so a live connection
+	// hears something at least once per heartbeat interval
+	HeartbeatMaxAge time.Duration
+
+	// StorageTimeout is how long to wait for a read transaction
+	StorageTimeout time.Duration
+}
+
+type monitor struct {
+	deps            dependencies
+	heartbeatMaxAge time.Duration
+	storageTimeout  time.Duration
+
+	authenticated int32
+	openConns     int32
+	lastHeard     int64 // unix nanoseconds
+}
+
+// NewMonitor creates a new Monitor
+func NewMonitor(deps dependencies, opts Options) Monitor {
+	return &monitor{
+		deps:            deps,
+		heartbeatMaxAge: opts.HeartbeatMaxAge,
+		storageTimeout:  opts.StorageTimeout,
+	}
+}
+
+func (m *monitor) SetAuthenticated(auth bool) {
+	var v int32
+	if auth {
+		v = 1
+	}
+	atomic.StoreInt32(&m.authenticated, v)
+}
+
+func (m *monitor) ConnectToBot(b bot.DiscordBot) {
+	b.AddMessageHandler("READY", m.handleSessionStart)
+	b.AddMessageHandler("RESUMED", m.handleSessionStart)
+}
+
+func (m *monitor) handleSessionStart(p *etfapi.Payload, req wsclient.WSMessage, respChan chan<- wsclient.WSMessage) {
+	m.SetAuthenticated(true)
+}
+
+// watchFrame notices invalid session frames, which are not dispatched to
+// message handlers
+func (m *monitor) watchFrame(data []byte) {
+	if len(data) > maxInvalidSessionSize {
+		return
+	}
+
+	p, err := etfapi.Unmarshal(data)
+	if err == nil && p != nil && int(p.OpCode) == opInvalidSession {
+		m.SetAuthenticated(false)
+	}
+}
+
+func (m *monitor) heard() {
+	atomic.StoreInt64(&m.lastHeard, time.Now().UnixNano())
+}
+
+func (m *monitor) checkAuthenticated() error {
+	if atomic.LoadInt32(&m.authenticated) == 0 {
+		return errors.New("not authenticated")
+	}
+	return nil
+}
+
+func (m *monitor) checkConnected() error {
+	if atomic.LoadInt32(&m.openConns) <= 0 {
+		return errors.New("no gateway connection")
+	}
+	return nil
+}
+
+func (m *monitor) checkHeartbeat() error {
+	last := atomic.LoadInt64(&m.lastHeard)
+	if last == 0 {
+		return errors.New("nothing heard from the gateway yet")
+	}
+
+	age := time.Since(time.Unix(0, last))
+	if age > m.heartbeatMaxAge {
+		return fmt.Errorf("last heard from the gateway %s ago", age.Truncate(time.Second))
+	}
+	return nil
+}
+
+// checkStorage opens (and rolls back) a read transaction; a stuck database
+// lock fails the check after the timeout rather than hanging the probe
+func (m *monitor) checkStorage() error {
+	done := make(chan error, 1)
+	go func() {
+		t, err := m.deps.UserAPI().NewTransaction(false)
+		if err != nil {
+			done <- errors.Wrap(err, "could not open a read transaction")
+			return
+		}
+		done <- t.Rollback()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(m.storageTimeout):
+		return fmt.Errorf("no read transaction after %s", m.storageTimeout)
+	}
+}
+
+func (m *monitor) Checks() []Check {
+	return []Check{
+		{Name: "authenticated", Err: m.checkAuthenticated()},
+		{Name: "gateway", Err: m.checkConnected()},
+		{Name: "heartbeat", Err: m.checkHeartbeat()},
+		{Name: "storage", Err: m.checkStorage()},
+	}
+}
+
+func (m *monitor) Ready() error {
+	for _, c := range m.Checks() {
+		if c.Err != nil {
+			return errors.Wrap(ErrNotReady, fmt.Sprintf("%s: %v", c.Name, c.Err))
+		}
+	}
+	return nil
+}
+
+func (m *monitor) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+func (m *monitor) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks := m.Checks()
+
+		status := http.StatusOK
+		lines := make([]string, 0, len(checks)+1)
+		for _, c := range checks {
+			if c.Err != nil {
+				status = http.StatusServiceUnavailable
+				lines = append(lines, fmt.Sprintf("[-] %s: %v", c.Name, c.Err))
+			} else {
+				lines = append(lines, fmt.Sprintf("[+] %s ok", c.Name))
+			}
+		}
+
+		if status == http.StatusOK {
+			lines = append(lines, "ready")
+		} else {
+			lines = append(lines, "not ready")
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	})
+}
+
+func (m *monitor) WrapDialer(d wsclient.Dialer) wsclient.Dialer {
+	return &watchedDialer{Dialer: d, m: m}
+}
+
+// watchedDialer counts open gateway connections and notes whenever anything
+// (including heartbeat acks) is read from them; a connection that closes takes
+// its session with it until the next READY or RESUMED
+type watchedDialer struct {
+	wsclient.Dialer
+	m *monitor
+}
+
+func (d *watchedDialer) Dial(addr string, header http.Header) (wsclient.Conn, *http.Response, error) {
+	conn, resp, err := d.Dialer.Dial(addr, header)
+	if err != nil {
+		return conn, resp, err
+	}
+
+	atomic.AddInt32(&d.m.openConns, 1)
+	d.m.heard()
+	return &watchedConn{Conn: conn, m: d.m}, resp, nil
+}
+
+type watchedConn struct {
+	wsclient.Conn
+	m      *monitor
+	closed sync.Once
+}
+
+func (c *watchedConn) ReadMessage() (int, []byte, error) {
+	mt, data, err := c.Conn.ReadMessage()
+	if err == nil {
+		c.m.heard()
+		c.m.watchFrame(data)
+	}
+	return mt, data, err
+}
+
+func (c *watchedConn) Close() error {
+	c.closed.Do(func() {
+		atomic.AddInt32(&c.m.openConns, -1)
+		c.m.SetAuthenticated(false)
+	})
+	return c.Conn.Close()
+}
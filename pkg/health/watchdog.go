@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// Notify sends a state line (like READY=1) to systemd; it does nothing when
+// the process was not started by systemd with a notify socket
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "could not connect to the notify socket")
+	}
+	defer conn.Close() // nolint: errcheck
+
+	_, err = conn.Write([]byte(state))
+	return errors.Wrap(err, "could not notify systemd")
+}
+
+// watchdogInterval is how often systemd wants to hear from the watchdog (half
+// of WatchdogSec), or 0 when the watchdog is not enabled for this process
+func watchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// RunWatchdog pets the systemd watchdog for as long as the Monitor says the
+// bot is ready, so that systemd restarts a bot whose gateway connection has
+// hung. It returns straight away when the watchdog is not enabled.
+func RunWatchdog(ctx context.Context, m Monitor, logger log.Logger) error {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := m.Ready(); err != nil {
+			_ = level.Warn(logger).Log("message", "not petting the watchdog", "err", err)
+			continue
+		}
+
+		if err := Notify("WATCHDOG=1"); err != nil {
+			_ = level.Error(logger).Log("message", "could not pet the watchdog", "err", err)
+		}
+	}
+}
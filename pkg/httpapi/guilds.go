@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type settingJSON struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Default     string `json:"default"`
+	IsSet       bool   `json:"is_set"`
+	Description string `json:"description"`
+}
+
+type guildSettingsJSON struct {
+	Guild    string        `json:"guild"`
+	Settings []settingJSON `json:"settings"`
+}
+
+type setValueJSON struct {
+	Value string `json:"value"`
+}
+
+func newGuildSettingsJSON(guildID string, s storage.GuildSettings) guildSettingsJSON {
+	gs := guildSettingsJSON{
+		Guild:    guildID,
+		Settings: []settingJSON{},
+	}
+
+	for _, d := range storage.SettingDefs() {
+		val, _ := s.Get(d.Name)
+		gs.Settings = append(gs.Settings, settingJSON{
+			Name:        d.Name,
+			Type:        string(d.Type),
+			Value:       val,
+			Default:     d.Default,
+			IsSet:       s.IsSet(d.Name),
+			Description: d.Description,
+		})
+	}
+
+	return gs
+}
+
+// serveGuilds routes the requests under /api/v1/guilds
+func (a *api) serveGuilds(w http.ResponseWriter, r *http.Request, parts []string) {
+	var err error
+
+	switch {
+	case len(parts) == 2 && parts[1] == "settings" && r.Method == http.MethodGet:
+		err = a.getSettings(w, parts[0])
+	case len(parts) == 3 && parts[1] == "settings" && r.Method == http.MethodPut:
+		err = a.setSetting(w, r, parts[0], parts[2])
+	case len(parts) == 3 && parts[1] == "settings" && r.Method == http.MethodDelete:
+		err = a.setSetting(w, nil, parts[0], parts[2])
+	case (len(parts) == 2 || len(parts) == 3) && parts[1] == "settings":
+		err = errMethodNotAllowed
+	default:
+		err = errNotFound
+	}
+
+	if err != nil {
+		a.writeError(w, r, err)
+	}
+}
+
+// getSettings lists every guild setting with its current value; a guild the
+// bot has no record of has all the defaults
+func (a *api) getSettings(w http.ResponseWriter, guildID string) error {
+	bGuild, err := a.service.GetGuild(guildID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, newGuildSettingsJSON(guildID, bGuild.GetSettings()))
+	return nil
+}
+
+// setSetting reads the value for a guild setting from the body; a DELETE
+// (when r is nil) resets it to the default
+func (a *api) setSetting(w http.ResponseWriter, r *http.Request, guildID, name string) error {
+	var bGuild storage.Guild
+	var err error
+
+	if r != nil {
+		var body setValueJSON
+		if err = readJSON(r, &body); err != nil {
+			return err
+		}
+		bGuild, err = a.service.SetGuildSetting(guildID, name, body.Value)
+	} else {
+		bGuild, err = a.service.ResetGuildSetting(guildID, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, newGuildSettingsJSON(guildID, bGuild.GetSettings()))
+	return nil
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/apiservice"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+var errNotFound = errors.New("not found")
+var errMethodNotAllowed = errors.New("method not allowed")
+var errBadRequest = errors.New("bad request")
+
+// prefix is where the api is served
+const prefix = "/api/v1/"
+
+type dependencies interface {
+	Logger() log.Logger
+	APIService() apiservice.Service
+}
+
+type api struct {
+	deps    dependencies
+	service apiservice.Service
+}
+
+// NewHandler creates the http handler for the api. Every request (other than
+// for the OpenAPI description) needs an `Authorization: Bearer <token>` header
+// with one of the service's tokens.
+func NewHandler(deps dependencies) http.Handler {
+	return &api{
+		deps:    deps,
+		service: deps.APIService(),
+	}
+}
+
+// pathParts splits the request path after the api prefix into its unescaped
+// segments, so names with spaces or slashes can be given percent-encoded
+func pathParts(r *http.Request) ([]string, error) {
+	p := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		var err error
+		parts[i], err = url.PathUnescape(part)
+		if err != nil {
+			return nil, errors.Wrap(errBadRequest, "bad path")
+		}
+	}
+	return parts, nil
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts, err := pathParts(r)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	if len(parts) == 1 && parts[0] == "openapi.yaml" && r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte(openAPI))
+		return
+	}
+
+	if !a.service.Authorized(r.Header.Get("Authorization")) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="have-want-bot"`)
+		a.writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "missing or unknown token"})
+		return
+	}
+
+	switch {
+	case len(parts) >= 1 && parts[0] == "users":
+		a.serveUsers(w, r, parts[1:])
+	case len(parts) >= 1 && parts[0] == "guilds":
+		a.serveGuilds(w, r, parts[1:])
+	default:
+		a.writeError(w, r, errNotFound)
+	}
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+func (a *api) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		_ = level.Error(a.deps.Logger()).Log("message", "could not write api response", "err", err)
+	}
+}
+
+// writeError answers with the status for err: missing records are 404s, bad
+// input is a 400, and anything else is logged as a 500
+func (a *api) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch errors.Cause(err) {
+	case errNotFound, storage.ErrUserNotExist, storage.ErrCharacterNotExist, storage.ErrGuildNotExist:
+		status = http.StatusNotFound
+	case errBadRequest, apiservice.ErrInvalidRequest, storage.ErrBadSetting, storage.ErrBadSettingValue:
+		status = http.StatusBadRequest
+	case apiservice.ErrCharacterExists:
+		status = http.StatusConflict
+	case errMethodNotAllowed:
+		status = http.StatusMethodNotAllowed
+	default:
+		_ = level.Error(a.deps.Logger()).Log("message", "error handling api request", "method", r.Method, "path", r.URL.Path, "err", err)
+	}
+
+	a.writeJSON(w, status, errorJSON{Error: err.Error()})
+}
+
+// readJSON decodes the request body into v, rejecting unknown fields
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return errors.Wrap(errBadRequest, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package httpapi
+
+// openAPI describes the api; it is served (without a token) at /api/v1/openapi.yaml
+const openAPI = `openapi: 3.0.3
+info:
+  title: have-want-bot api
+  version: "1"
+  description: |
+    Reads and changes the bot's users, characters, needs and guild settings.
+    Each request runs in a single storage transaction, the same as a bot
+    command. Tokens have full access: user and character privacy settings
+    do not apply.
+servers:
+  - url: /api/v1
+security:
+  - bearer: []
+paths:
+  /users:
+    get:
+      summary: List users
+      parameters:
+        - name: guild
+          in: query
+          description: Only list the users seen in this guild
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The users
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/UserSummary"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+  /users/{user}:
+    parameters:
+      - $ref: "#/components/parameters/User"
+    get:
+      summary: Get a user with all their characters
+      responses:
+        "200":
+          description: The user
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "404":
+          $ref: "#/components/responses/NotFound"
+  /users/{user}/characters:
+    parameters:
+      - $ref: "#/components/parameters/User"
+    post:
+      summary: Create a character (and the user, if need be)
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: The new character
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Character"
+        "400":
+          $ref: "#/components/responses/BadRequest"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "409":
+          description: The user already has a character with that name
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Error"
+  /users/{user}/characters/{character}:
+    parameters:
+      - $ref: "#/components/parameters/User"
+      - $ref: "#/components/parameters/Character"
+    get:
+      summary: Get a character
+      responses:
+        "200":
+          description: The character
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Character"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "404":
+          $ref: "#/components/responses/NotFound"
+    delete:
+      summary: Delete a character
+      responses:
+        "204":
+          description: The character was deleted
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "404":
+          $ref: "#/components/responses/NotFound"
+  /users/{user}/characters/{character}/needs/{kind}/{name}:
+    parameters:
+      - $ref: "#/components/parameters/User"
+      - $ref: "#/components/parameters/Character"
+      - name: kind
+        in: path
+        required: true
+        schema:
+          type: string
+          enum: [items, skills, transmutes]
+      - name: name
+        in: path
+        required: true
+        description: The item, skill or transmute name
+        schema:
+          type: string
+    put:
+      summary: Set how many the character needs (0 removes the need)
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [count]
+              properties:
+                count:
+                  type: integer
+                  minimum: 0
+      responses:
+        "200":
+          description: The updated character
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Character"
+        "400":
+          $ref: "#/components/responses/BadRequest"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "404":
+          $ref: "#/components/responses/NotFound"
+    delete:
+      summary: Remove the need
+      responses:
+        "200":
+          description: The updated character
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Character"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+        "404":
+          $ref: "#/components/responses/NotFound"
+  /guilds/{guild}/settings:
+    parameters:
+      - $ref: "#/components/parameters/Guild"
+    get:
+      summary: Get all the guild settings
+      responses:
+        "200":
+          description: The settings
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/GuildSettings"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+  /guilds/{guild}/settings/{setting}:
+    parameters:
+      - $ref: "#/components/parameters/Guild"
+      - name: setting
+        in: path
+        required: true
+        schema:
+          type: string
+    put:
+      summary: Set a guild setting (an empty value resets it)
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [value]
+              properties:
+                value:
+                  type: string
+      responses:
+        "200":
+          description: The updated settings
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/GuildSettings"
+        "400":
+          $ref: "#/components/responses/BadRequest"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+    delete:
+      summary: Reset a guild setting to its default
+      responses:
+        "200":
+          description: The updated settings
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/GuildSettings"
+        "400":
+          $ref: "#/components/responses/BadRequest"
+        "401":
+          $ref: "#/components/responses/Unauthorized"
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+  parameters:
+    User:
+      name: user
+      in: path
+      required: true
+      description: The discord user id
+      schema:
+        type: string
+    Character:
+      name: character
+      in: path
+      required: true
+      schema:
+        type: string
+    Guild:
+      name: guild
+      in: path
+      required: true
+      description: The discord guild id
+      schema:
+        type: string
+  responses:
+    BadRequest:
+      description: The request was invalid
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Error"
+    Unauthorized:
+      description: The token was missing or unknown
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Error"
+    NotFound:
+      description: The user, character or path does not exist
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Error"
+  schemas:
+    Error:
+      type: object
+      properties:
+        error:
+          type: string
+    Count:
+      type: object
+      properties:
+        name:
+          type: string
+        count:
+          type: integer
+    UserSummary:
+      type: object
+      properties:
+        id:
+          type: string
+        guilds:
+          type: array
+          items:
+            type: string
+        characters:
+          type: array
+          items:
+            type: string
+    User:
+      type: object
+      properties:
+        id:
+          type: string
+        guilds:
+          type: array
+          items:
+            type: string
+        privacy:
+          type: string
+        notify_mode:
+          type: string
+        dm_replies:
+          type: boolean
+        characters:
+          type: array
+          items:
+            $ref: "#/components/schemas/Character"
+    Character:
+      type: object
+      properties:
+        name:
+          type: string
+        privacy:
+          type: string
+        needed_items:
+          type: array
+          items:
+            $ref: "#/components/schemas/Count"
+        needed_skills:
+          type: array
+          items:
+            $ref: "#/components/schemas/Count"
+        needed_transmutes:
+          type: array
+          items:
+            $ref: "#/components/schemas/Count"
+        surplus_items:
+          type: array
+          items:
+            $ref: "#/components/schemas/Count"
+    GuildSettings:
+      type: object
+      properties:
+        guild:
+          type: string
+        settings:
+          type: array
+          items:
+            type: object
+            properties:
+              name:
+                type: string
+              type:
+                type: string
+              value:
+                type: string
+              default:
+                type: string
+              is_set:
+                type: boolean
+              description:
+                type: string
+`
@@ -0,0 +1,250 @@
+package httpapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/apiservice"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type userSummaryJSON struct {
+	ID         string   `json:"id"`
+	Guilds     []string `json:"guilds"`
+	Characters []string `json:"characters"`
+}
+
+type userJSON struct {
+	ID         string          `json:"id"`
+	Guilds     []string        `json:"guilds"`
+	Privacy    string          `json:"privacy"`
+	NotifyMode string          `json:"notify_mode"`
+	DMReplies  bool            `json:"dm_replies"`
+	Characters []characterJSON `json:"characters"`
+}
+
+type characterJSON struct {
+	Name             string      `json:"name"`
+	Privacy          string      `json:"privacy"`
+	NeededItems      []countJSON `json:"needed_items"`
+	NeededSkills     []countJSON `json:"needed_skills"`
+	NeededTransmutes []countJSON `json:"needed_transmutes"`
+	SurplusItems     []countJSON `json:"surplus_items"`
+}
+
+type countJSON struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+type createCharacterJSON struct {
+	Name string `json:"name"`
+}
+
+type setCountJSON struct {
+	Count *uint64 `json:"count"`
+}
+
+func newUserJSON(u storage.User) userJSON {
+	uj := userJSON{
+		ID:         u.GetName(),
+		Guilds:     nonNil(u.GetGuilds()),
+		Privacy:    u.GetPrivacy(),
+		NotifyMode: u.GetNotifyMode(),
+		DMReplies:  u.GetDMReplies(),
+		Characters: []characterJSON{},
+	}
+
+	for _, c := range u.GetCharacters() {
+		uj.Characters = append(uj.Characters, newCharacterJSON(c))
+	}
+	sort.Slice(uj.Characters, func(i, j int) bool {
+		return strings.ToLower(uj.Characters[i].Name) < strings.ToLower(uj.Characters[j].Name)
+	})
+
+	return uj
+}
+
+func newCharacterJSON(c storage.Character) characterJSON {
+	cj := characterJSON{
+		Name:             c.GetName(),
+		Privacy:          c.GetPrivacy(),
+		NeededItems:      []countJSON{},
+		NeededSkills:     []countJSON{},
+		NeededTransmutes: []countJSON{},
+		SurplusItems:     []countJSON{},
+	}
+
+	for _, i := range c.GetNeededItems() {
+		cj.NeededItems = append(cj.NeededItems, countJSON{Name: i.Name(), Count: i.Count()})
+	}
+	for _, s := range c.GetNeededSkills() {
+		cj.NeededSkills = append(cj.NeededSkills, countJSON{Name: s.Name(), Count: s.Points()})
+	}
+	for _, t := range c.GetNeededTransmutes() {
+		cj.NeededTransmutes = append(cj.NeededTransmutes, countJSON{Name: t.Name(), Count: t.Count()})
+	}
+	for _, i := range c.GetSurplusItems() {
+		cj.SurplusItems = append(cj.SurplusItems, countJSON{Name: i.Name(), Count: i.Count()})
+	}
+
+	sortCounts(cj.NeededItems)
+	sortCounts(cj.NeededSkills)
+	sortCounts(cj.NeededTransmutes)
+	sortCounts(cj.SurplusItems)
+
+	return cj
+}
+
+func sortCounts(cs []countJSON) {
+	sort.Slice(cs, func(i, j int) bool { return strings.ToLower(cs[i].Name) < strings.ToLower(cs[j].Name) })
+}
+
+func nonNil(ss []string) []string {
+	if ss == nil {
+		return []string{}
+	}
+	return ss
+}
+
+// serveUsers routes the requests under /api/v1/users
+func (a *api) serveUsers(w http.ResponseWriter, r *http.Request, parts []string) {
+	var err error
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		err = a.listUsers(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		err = a.getUser(w, parts[0])
+	case len(parts) == 2 && parts[1] == "characters" && r.Method == http.MethodPost:
+		err = a.createCharacter(w, r, parts[0])
+	case len(parts) == 3 && parts[1] == "characters" && r.Method == http.MethodGet:
+		err = a.getCharacter(w, parts[0], parts[2])
+	case len(parts) == 3 && parts[1] == "characters" && r.Method == http.MethodDelete:
+		err = a.deleteCharacter(w, parts[0], parts[2])
+	case len(parts) == 6 && parts[1] == "characters" && parts[3] == "needs" && r.Method == http.MethodPut:
+		err = a.setNeed(w, r, parts[0], parts[2], parts[4], parts[5])
+	case len(parts) == 6 && parts[1] == "characters" && parts[3] == "needs" && r.Method == http.MethodDelete:
+		err = a.setNeed(w, nil, parts[0], parts[2], parts[4], parts[5])
+	case len(parts) <= 3 || (len(parts) == 6 && parts[3] == "needs"):
+		err = errMethodNotAllowed
+	default:
+		err = errNotFound
+	}
+
+	if err != nil {
+		a.writeError(w, r, err)
+	}
+}
+
+// needKinds maps the kinds of need in the api paths to the service's
+var needKinds = map[string]string{
+	"items":      apiservice.NeedItem,
+	"skills":     apiservice.NeedSkill,
+	"transmutes": apiservice.NeedTransmute,
+}
+
+// listUsers lists the users with their guilds and character names; the
+// `guild` query parameter restricts it to the members of one guild
+func (a *api) listUsers(w http.ResponseWriter, r *http.Request) error {
+	found, err := a.service.ListUsers(r.URL.Query().Get("guild"))
+	if err != nil {
+		return err
+	}
+
+	users := []userSummaryJSON{}
+	for _, u := range found {
+		us := userSummaryJSON{
+			ID:         u.GetName(),
+			Guilds:     nonNil(u.GetGuilds()),
+			Characters: []string{},
+		}
+		for _, c := range u.GetCharacters() {
+			us.Characters = append(us.Characters, c.GetName())
+		}
+		sort.Slice(us.Characters, func(i, j int) bool {
+			return strings.ToLower(us.Characters[i]) < strings.ToLower(us.Characters[j])
+		})
+
+		users = append(users, us)
+	}
+
+	a.writeJSON(w, http.StatusOK, users)
+	return nil
+}
+
+func (a *api) getUser(w http.ResponseWriter, userID string) error {
+	bUser, err := a.service.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, newUserJSON(bUser))
+	return nil
+}
+
+func (a *api) getCharacter(w http.ResponseWriter, userID, charName string) error {
+	_, char, err := a.service.GetCharacter(userID, charName)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, newCharacterJSON(char))
+	return nil
+}
+
+func (a *api) createCharacter(w http.ResponseWriter, r *http.Request, userID string) error {
+	var body createCharacterJSON
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+
+	_, char, err := a.service.CreateCharacter(userID, body.Name)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusCreated, newCharacterJSON(char))
+	return nil
+}
+
+func (a *api) deleteCharacter(w http.ResponseWriter, userID, charName string) error {
+	if err := a.service.DeleteCharacter(userID, charName); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// setNeed reads the count for a need from the body; a DELETE (when r is nil)
+// sets it to 0, removing the need
+func (a *api) setNeed(w http.ResponseWriter, r *http.Request, userID, charName, kind, name string) error {
+	needKind, ok := needKinds[kind]
+	if !ok {
+		return errors.Wrap(errNotFound, "unknown kind of need")
+	}
+
+	var count uint64
+	if r != nil {
+		var body setCountJSON
+		if err := readJSON(r, &body); err != nil {
+			return err
+		}
+		if body.Count == nil {
+			return errors.Wrap(errBadRequest, "missing count")
+		}
+		count = *body.Count
+	}
+
+	_, char, err := a.service.SetNeed(userID, charName, needKind, name, count)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, newCharacterJSON(char))
+	return nil
+}
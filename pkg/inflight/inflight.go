@@ -0,0 +1,95 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracker counts the work (command handlers, scheduled deliveries) in
+// progress, so that shutdown can wait for it to finish
+type Tracker interface {
+	// Start registers a new piece of work; it returns false once draining has
+	// begun, in which case the work should be dropped (and Done not called)
+	Start() bool
+
+	// Done marks a piece of work registered with Start as finished
+	Done()
+
+	// Drain stops new work from starting and waits until the work in progress
+	// is done or ctx is; it returns how much work was in progress when it
+	// began and how much was still unfinished when it returned
+	Drain(ctx context.Context) (waited, abandoned int)
+
+	// Dropped returns how much work was refused since draining began
+	Dropped() int
+}
+
+type tracker struct {
+	mu       sync.Mutex
+	running  int
+	dropped  int
+	draining bool
+	idle     chan struct{}
+}
+
+// NewTracker creates a new Tracker
+func NewTracker() Tracker {
+	return &tracker{}
+}
+
+func (t *tracker) Start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		t.dropped++
+		return false
+	}
+
+	t.running++
+	return true
+}
+
+func (t *tracker) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running--
+	if t.running == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+func (t *tracker) Drain(ctx context.Context) (waited, abandoned int) {
+	t.mu.Lock()
+	t.draining = true
+	waited = t.running
+	if waited == 0 {
+		t.mu.Unlock()
+		return 0, 0
+	}
+
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return waited, 0
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return waited, t.running
+}
+
+func (t *tracker) Dropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dropped
+}
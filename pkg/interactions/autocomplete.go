@@ -0,0 +1,126 @@
+package interactions
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// focusedOption finds the option being typed, the subcommand it belongs to and its siblings
+func focusedOption(data InteractionData) (focused InteractionOption, sub string, siblings []InteractionOption) {
+	opts := data.Options
+	if len(opts) == 1 && opts[0].Type == OptionSubCommand {
+		sub = opts[0].Name
+		opts = opts[0].Options
+	}
+
+	for _, o := range opts {
+		if o.Focused {
+			focused = o
+		}
+	}
+	return focused, sub, opts
+}
+
+// neededNames lists the names of the kind of need a subcommand works with
+func neededNames(char storage.Character, sub string) []string {
+	var names []string
+	switch sub {
+	case "pts":
+		for _, s := range char.GetNeededSkills() {
+			names = append(names, s.Name())
+		}
+	case "trans":
+		for _, t := range char.GetNeededTransmutes() {
+			names = append(names, t.Name())
+		}
+	default:
+		for _, it := range char.GetNeededItems() {
+			names = append(names, it.Name())
+		}
+	}
+	return names
+}
+
+// matchChoices returns up to maxChoices names containing the typed prefix, best matches first
+func matchChoices(names []string, typed string) []Choice {
+	typed = strings.ToLower(strings.TrimSpace(typed))
+
+	seen := map[string]bool{}
+	var prefixed, contained []string
+	for _, n := range names {
+		ln := strings.ToLower(n)
+		if seen[ln] {
+			continue
+		}
+		seen[ln] = true
+
+		switch {
+		case strings.HasPrefix(ln, typed):
+			prefixed = append(prefixed, n)
+		case strings.Contains(ln, typed):
+			contained = append(contained, n)
+		}
+	}
+	sort.Strings(prefixed)
+	sort.Strings(contained)
+
+	choices := make([]Choice, 0, maxChoices)
+	for _, n := range append(prefixed, contained...) {
+		if len(choices) == maxChoices {
+			break
+		}
+		choices = append(choices, Choice{Name: n, Value: n})
+	}
+	return choices
+}
+
+// autocomplete suggests character and item names from the user's own lists
+func (h *handlers) autocomplete(i Interaction) (Response, error) {
+	focused, sub, siblings := focusedOption(i.Data)
+
+	var names []string
+
+	t, err := h.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(string(i.UserID()))
+	if err == nil {
+		switch focused.Name {
+		case "character":
+			for _, char := range bUser.GetCharacters() {
+				names = append(names, char.GetName())
+			}
+		case "item", "skill":
+			charName := ""
+			for _, o := range siblings {
+				if o.Name == "character" {
+					charName = o.StringValue()
+				}
+			}
+
+			for _, char := range bUser.GetCharacters() {
+				if charName != "" && !strings.EqualFold(char.GetName(), charName) {
+					continue
+				}
+				names = append(names, neededNames(char, sub)...)
+			}
+		}
+	}
+
+	data, err := json.Marshal(map[string][]Choice{
+		"choices": matchChoices(names, focused.StringValue()),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{Type: CallbackAutocomplete, Data: data}, nil
+}
@@ -0,0 +1,177 @@
+package interactions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-bot-lib/logging"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/commands"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/permissions"
+)
+
+// neededCount looks up how many of a need the owner's character still has
+func (h *handlers) neededCount(state commands.ComponentState) (uint64, error) {
+	t, err := h.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return 0, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(state.Owner)
+	if err != nil {
+		return 0, err
+	}
+
+	char, err := bUser.GetCharacter(state.Char)
+	if err != nil {
+		return 0, err
+	}
+
+	switch state.Kind {
+	case "pts":
+		s, err := char.GetNeededSkill(state.Name)
+		if err != nil {
+			return 0, err
+		}
+		return s.Points(), nil
+	case "trans":
+		tr, err := char.GetNeededTransmute(state.Name)
+		if err != nil {
+			return 0, err
+		}
+		return tr.Count(), nil
+	default:
+		it, err := char.GetNeededItem(state.Name)
+		if err != nil {
+			return 0, err
+		}
+		return it.Count(), nil
+	}
+}
+
+// component answers a button press or select menu choice on a char show or
+// paged list message. Changes go through the same text commands as typing them
+// would, then the message is re-rendered in place.
+func (h *handlers) component(ctx context.Context, i Interaction) (Response, error) {
+	uid, _ := snowflake.FromString(string(i.UserID()))
+	gid, _ := snowflake.FromString(string(i.GuildID))
+	cid, _ := snowflake.FromString(string(i.ChannelID))
+	iid, _ := snowflake.FromString(string(i.ID))
+
+	msg := cmdhandler.NewSimpleMessage(ctx, uid, gid, cid, iid, "")
+	errResp := &cmdhandler.SimpleEmbedResponse{To: cmdhandler.UserMentionString(uid)}
+
+	state, err := commands.DecodeComponentState(i.Data.CustomID)
+	if err != nil {
+		errResp.IncludeError(err)
+		return h.messageResponse(errResp, true)
+	}
+
+	capability, channelOK := h.capability(i)
+	if capability < permissions.Member {
+		errResp.IncludeError(errUnauthorized)
+		return h.messageResponse(errResp, true)
+	}
+	if !channelOK {
+		errResp.IncludeError(errChannel)
+		return h.messageResponse(errResp, true)
+	}
+	h.recordGuild(msg)
+
+	owner := fmt.Sprintf("<@%s>", state.Owner)
+	charName := state.Char
+
+	switch state.Action {
+	case commands.ComponentGotOne, commands.ComponentGotAll:
+		if string(i.UserID()) != state.Owner {
+			errResp.IncludeError(errNotOwner)
+			return h.messageResponse(errResp, true)
+		}
+
+		ct := uint64(1)
+		if state.Action == commands.ComponentGotAll {
+			ct, err = h.neededCount(state)
+			if err != nil {
+				errResp.IncludeError(err)
+				return h.messageResponse(errResp, true)
+			}
+		}
+
+		text := fmt.Sprintf("got %s %s %s %d", state.Kind, commands.QuoteArg(state.Char), commands.QuoteArg(state.Name), ct)
+		resp, err := h.commands.HandleMessage(cmdhandler.NewWithContents(msg, h.cmdIndicator+text))
+		if err != nil {
+			_ = level.Error(logging.WithMessage(msg, h.deps.Logger())).Log("message", "error handling component", "contents", text, "err", err)
+			resp.IncludeError(err)
+			return h.messageResponse(resp, true)
+		}
+	case commands.ComponentSelectChar:
+		if len(i.Data.Values) == 0 {
+			errResp.IncludeError(commands.ErrBadComponentState)
+			return h.messageResponse(errResp, true)
+		}
+		charName = i.Data.Values[0]
+		state.Page = 1
+	case commands.ComponentPage:
+	case commands.ComponentListPage:
+		text := fmt.Sprintf("%s %s %s --page %d", state.Name, owner, commands.QuoteArg(state.Char), state.Page)
+		resp, err := h.commands.HandleMessage(cmdhandler.NewWithContents(msg, h.cmdIndicator+text))
+		if err != nil {
+			resp.IncludeError(err)
+			return h.messageResponse(resp, true)
+		}
+		if string(i.UserID()) != state.Owner {
+			return h.messageResponse(resp, true)
+		}
+		return h.updateResponse(resp)
+	default:
+		errResp.IncludeError(commands.ErrBadComponentState)
+		return h.messageResponse(errResp, true)
+	}
+
+	text := fmt.Sprintf("char show %s %s --page %d", owner, commands.QuoteArg(charName), state.Page)
+	resp, err := h.commands.HandleMessage(cmdhandler.NewWithContents(msg, h.cmdIndicator+text))
+	if err != nil {
+		resp.IncludeError(err)
+		return h.messageResponse(resp, true)
+	}
+
+	// only the owner's presses change the message; anyone else gets their own
+	// copy, rendered for them, so the owner's buttons are not taken away
+	if string(i.UserID()) != state.Owner {
+		return h.messageResponse(resp, true)
+	}
+
+	return h.updateResponse(resp)
+}
+
+// updateResponse wraps a command response as a callback that edits the message
+// the component is attached to
+func (h *handlers) updateResponse(resp cmdhandler.Response) (Response, error) {
+	r, err := h.messageResponse(resp, false)
+	if err != nil {
+		return r, err
+	}
+
+	// a re-render without components must clear the old ones rather than keep them
+	var data map[string]interface{}
+	if err = json.Unmarshal(r.Data, &data); err != nil {
+		return Response{}, err
+	}
+	if _, ok := data["components"]; !ok {
+		data["components"] = []interface{}{}
+	}
+
+	r.Data, err = json.Marshal(data)
+	if err != nil {
+		return Response{}, err
+	}
+
+	r.Type = CallbackUpdateMessage
+	return r, nil
+}
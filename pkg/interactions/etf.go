@@ -0,0 +1,61 @@
+package interactions
+
+import (
+	"encoding/json"
+
+	"github.com/gsmcwhirter/discord-bot-lib/etfapi"
+)
+
+// elementValue converts a gateway etf element into plain values that can be
+// re-encoded as json
+func elementValue(e etfapi.Element) interface{} {
+	if e.IsNil() {
+		return nil
+	}
+
+	if m, err := e.ToMap(); err == nil {
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[k] = elementValue(v)
+		}
+		return out
+	}
+
+	if l, err := e.ToList(); err == nil {
+		out := make([]interface{}, len(l))
+		for i, v := range l {
+			out[i] = elementValue(v)
+		}
+		return out
+	}
+
+	if b, err := e.ToBool(); err == nil {
+		return b
+	}
+
+	if i, err := e.ToInt64(); err == nil {
+		return i
+	}
+
+	if s, err := e.ToString(); err == nil {
+		return s
+	}
+
+	return nil
+}
+
+// interactionFromPayload decodes an INTERACTION_CREATE payload
+func interactionFromPayload(p *etfapi.Payload) (i Interaction, err error) {
+	data := make(map[string]interface{}, len(p.Data))
+	for k, v := range p.Data {
+		data[k] = elementValue(v)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(raw, &i)
+	return
+}
@@ -0,0 +1,362 @@
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/bot"
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-bot-lib/etfapi"
+	"github.com/gsmcwhirter/discord-bot-lib/httpclient"
+	"github.com/gsmcwhirter/discord-bot-lib/logging"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/discord-bot-lib/wsclient"
+	"github.com/gsmcwhirter/go-util/parser"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/commands"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/inflight"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/permissions"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// ErrCallbackFailed is the error returned when discord rejects an interaction callback
+var ErrCallbackFailed = errors.New("discord rejected the interaction callback")
+
+var errUnauthorized = errors.New("you are not allowed to use that command here")
+
+var errChannel = errors.New("commands are not allowed in this channel")
+
+var errNotOwner = errors.New("only the character's owner can change its needs")
+
+type dependencies interface {
+	Logger() log.Logger
+	UserAPI() storage.UserAPI
+	GuildAPI() storage.GuildAPI
+	HTTPClient() httpclient.HTTPClient
+	CommandHandler() *cmdhandler.CommandHandler
+	ConfigHandler() *cmdhandler.CommandHandler
+	InFlight() inflight.Tracker
+}
+
+// poster is the part of the http client the handlers rely on
+type poster interface {
+	PostBody(ctx context.Context, url string, header *http.Header, body io.Reader) (*http.Response, []byte, error)
+}
+
+// messageHandler is the part of a cmdhandler.CommandHandler the adapter relies on
+type messageHandler interface {
+	HandleMessage(cmdhandler.Message) (cmdhandler.Response, error)
+}
+
+// Handlers answers discord application command interactions
+type Handlers interface {
+	ConnectToBot(bot.DiscordBot)
+	RegisterCommands(ctx context.Context) error
+	HandleInteraction(ctx context.Context, i Interaction) (Response, error)
+
+	// SetOptions changes the colors while running; the api settings are
+	// fixed when the Handlers are created
+	SetOptions(opts Options)
+}
+
+// Options is how to point Handlers at the discord api and set response colors
+type Options struct {
+	APIURL        string
+	ApplicationID string
+	SuccessColor  int
+	ErrorColor    int
+}
+
+type handlers struct {
+	deps          dependencies
+	http          poster
+	commands      messageHandler
+	config        messageHandler
+	cmdIndicator  string
+	cfgIndicator  string
+	apiURL        string
+	applicationID string
+	successColor  int
+	errorColor    int
+
+	// guards the colors, which can change with SetOptions
+	colorLock sync.RWMutex
+}
+
+// NewHandlers creates a new Handlers object
+func NewHandlers(deps dependencies, opts Options) Handlers {
+	return &handlers{
+		deps:          deps,
+		http:          deps.HTTPClient(),
+		commands:      deps.CommandHandler(),
+		config:        deps.ConfigHandler(),
+		cmdIndicator:  deps.CommandHandler().CommandIndicator(),
+		cfgIndicator:  deps.ConfigHandler().CommandIndicator(),
+		apiURL:        opts.APIURL,
+		applicationID: opts.ApplicationID,
+		successColor:  opts.SuccessColor,
+		errorColor:    opts.ErrorColor,
+	}
+}
+
+func (h *handlers) ConnectToBot(bot bot.DiscordBot) {
+	bot.AddMessageHandler("INTERACTION_CREATE", h.handleInteractionPayload)
+}
+
+func (h *handlers) SetOptions(opts Options) {
+	h.colorLock.Lock()
+	defer h.colorLock.Unlock()
+
+	h.successColor = opts.SuccessColor
+	h.errorColor = opts.ErrorColor
+}
+
+func (h *handlers) post(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Add("Content-Type", "application/json")
+
+	resp, respBody, err := h.http.PostBody(ctx, url, &header, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Wrap(ErrCallbackFailed, fmt.Sprintf("status %d: %s", resp.StatusCode, string(respBody)))
+	}
+
+	return nil
+}
+
+// RegisterCommands creates or updates the application command schema with discord
+func (h *handlers) RegisterCommands(ctx context.Context) error {
+	url := fmt.Sprintf("%s/applications/%s/commands", h.apiURL, h.applicationID)
+	for _, c := range Commands() {
+		if err := h.post(ctx, url, c); err != nil {
+			return errors.Wrapf(err, "could not register command %s", c.Name)
+		}
+	}
+	return nil
+}
+
+func (h *handlers) handleInteractionPayload(p *etfapi.Payload, req wsclient.WSMessage, respChan chan<- wsclient.WSMessage) {
+	logger := logging.WithContext(req.Ctx, h.deps.Logger())
+
+	if !h.deps.InFlight().Start() {
+		_ = level.Info(logger).Log("message", "shutting down; dropping interaction")
+		return
+	}
+	defer h.deps.InFlight().Done()
+
+	i, err := interactionFromPayload(p)
+	if err != nil {
+		_ = level.Error(logger).Log("message", "error inflating interaction", "err", err)
+		return
+	}
+
+	resp, err := h.HandleInteraction(req.Ctx, i)
+	if err != nil {
+		_ = level.Error(logger).Log("message", "error handling interaction", "name", i.Data.Name, "err", err)
+		return
+	}
+
+	err = h.post(req.Ctx, fmt.Sprintf("%s/interactions/%s/%s/callback", h.apiURL, i.ID, i.Token), resp)
+	if err != nil {
+		_ = level.Error(logger).Log("message", "could not send interaction callback", "err", err)
+		return
+	}
+
+	_ = level.Info(logger).Log("message", "successfully answered interaction", "name", i.Data.Name)
+}
+
+// HandleInteraction builds the callback for an interaction
+func (h *handlers) HandleInteraction(ctx context.Context, i Interaction) (Response, error) {
+	switch i.Type {
+	case InteractionPing:
+		return Response{Type: CallbackPong}, nil
+	case InteractionAutocomplete:
+		return h.autocomplete(i)
+	case InteractionCommand:
+		return h.command(ctx, i)
+	case InteractionComponent:
+		return h.component(ctx, i)
+	default:
+		return Response{}, errors.Errorf("unsupported interaction type %d", i.Type)
+	}
+}
+
+// commandText turns the interaction's command and options back into the text
+// form understood by the cmdhandler command handlers
+func commandText(data InteractionData) string {
+	parts := []string{data.Name}
+	opts := data.Options
+	key := data.Name
+
+	if len(opts) == 1 && opts[0].Type == OptionSubCommand {
+		parts = append(parts, opts[0].Name)
+		key += " " + opts[0].Name
+		opts = opts[0].Options
+	}
+
+	if keyValueSubcommands[key] {
+		var k, v string
+		for _, o := range opts {
+			if o.Name == "value" {
+				v = o.StringValue()
+			} else {
+				k = o.StringValue()
+			}
+		}
+		return strings.Join(append(parts, k+"="+commands.QuoteArg(v)), " ")
+	}
+
+	// the text commands take a leading user mention, then the remaining arguments
+	// in order, with any flags (page and switches) last
+	var args, flags []string
+	for _, o := range opts {
+		switch {
+		case o.Type == OptionUser:
+			parts = append(parts, fmt.Sprintf("<@%s>", o.StringValue()))
+		case o.Name == "page":
+			flags = append(flags, "--page", o.StringValue())
+		case o.Type == OptionBoolean:
+			if o.StringValue() == "true" {
+				flags = append(flags, "--"+o.Name)
+			}
+		case o.Type == OptionString:
+			args = append(args, commands.QuoteArg(o.StringValue()))
+		default:
+			args = append(args, o.StringValue())
+		}
+	}
+	args = append(args, flags...)
+
+	return strings.Join(append(parts, args...), " ")
+}
+
+// capability resolves the user's capability in the interaction's guild, and
+// whether the guild's channel restrictions allow commands where it was sent
+func (h *handlers) capability(i Interaction) (permissions.Capability, bool) {
+	if i.GuildID == "" || i.Member == nil {
+		return permissions.Member, true
+	}
+
+	gid, err := snowflake.FromString(string(i.GuildID))
+	if err != nil {
+		return permissions.Banned, false
+	}
+
+	s, err := storage.GetSettings(h.deps.GuildAPI(), gid)
+	if err != nil {
+		return permissions.Banned, false
+	}
+
+	roles := make([]string, len(i.Member.Roles))
+	for j, r := range i.Member.Roles {
+		roles[j] = string(r)
+	}
+
+	c := permissions.Resolve(s, i.Member.IsAdmin(), roles)
+	return c, permissions.ChannelAllowed(s, string(i.ChannelID), c)
+}
+
+func (h *handlers) command(ctx context.Context, i Interaction) (Response, error) {
+	uid, _ := snowflake.FromString(string(i.UserID()))
+	gid, _ := snowflake.FromString(string(i.GuildID))
+	cid, _ := snowflake.FromString(string(i.ChannelID))
+	iid, _ := snowflake.FromString(string(i.ID))
+
+	msg := cmdhandler.NewSimpleMessage(ctx, uid, gid, cid, iid, "")
+	text := commandText(i.Data)
+
+	capability, channelOK := h.capability(i)
+
+	var resp cmdhandler.Response
+	var err error
+	switch {
+	case capability < permissions.Member:
+		resp, err = &cmdhandler.SimpleEmbedResponse{To: cmdhandler.UserMentionString(uid)}, errUnauthorized
+	case !channelOK:
+		resp, err = &cmdhandler.SimpleEmbedResponse{To: cmdhandler.UserMentionString(uid)}, errChannel
+	case configCommands[i.Data.Name]:
+		if gid == 0 || capability < permissions.Required(i.Data.Name) {
+			resp, err = &cmdhandler.SimpleEmbedResponse{To: cmdhandler.UserMentionString(uid)}, errUnauthorized
+			break
+		}
+		resp, err = h.config.HandleMessage(cmdhandler.NewWithContents(msg, h.cfgIndicator+text))
+	default:
+		resp, err = h.commands.HandleMessage(cmdhandler.NewWithContents(msg, h.cmdIndicator+text))
+	}
+
+	// after the command, so users it has just created are included
+	if capability >= permissions.Member && channelOK {
+		h.recordGuild(msg)
+	}
+
+	if err != nil && err != parser.ErrUnknownCommand {
+		_ = level.Error(logging.WithMessage(msg, h.deps.Logger())).Log("message", "error handling command", "contents", text, "err", err)
+	}
+	if err != nil {
+		resp.IncludeError(err)
+	}
+
+	return h.messageResponse(resp, configCommands[i.Data.Name] || resp.HasErrors())
+}
+
+// recordGuild notes that the user has used the bot in the interaction's guild
+func (h *handlers) recordGuild(msg cmdhandler.Message) {
+	if err := storage.RecordGuild(h.deps.UserAPI(), msg.UserID(), msg.GuildID()); err != nil {
+		_ = level.Error(logging.WithMessage(msg, h.deps.Logger())).Log("message", "could not record the user's guild", "err", err)
+	}
+}
+
+// messageResponse wraps a command response as an interaction message callback
+func (h *handlers) messageResponse(resp cmdhandler.Response, ephemeral bool) (Response, error) {
+	h.colorLock.RLock()
+	successColor, errorColor := h.successColor, h.errorColor
+	h.colorLock.RUnlock()
+
+	if resp.HasErrors() {
+		resp.SetColor(errorColor)
+	} else {
+		resp.SetColor(successColor)
+	}
+
+	raw, err := resp.ToMessage().MarshalJSON()
+	if err != nil {
+		return Response{}, err
+	}
+
+	// channel messages carry a single embed; interaction callbacks take a list
+	var data map[string]interface{}
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return Response{}, err
+	}
+	if embed, ok := data["embed"]; ok {
+		delete(data, "embed")
+		data["embeds"] = []interface{}{embed}
+	}
+	if ephemeral {
+		data["flags"] = flagEphemeral
+	}
+
+	raw, err = json.Marshal(data)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{Type: CallbackMessage, Data: raw}, nil
+}
@@ -0,0 +1,519 @@
+package interactions
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/go-kit/kit/log"
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/commands"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// fakeGateway stands in for the discord http api, recording every request
+type fakeGateway struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests map[string][]json.RawMessage
+}
+
+func newFakeGateway() *fakeGateway {
+	g := &fakeGateway{requests: map[string][]json.RawMessage{}}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		g.lock.Lock()
+		g.requests[r.URL.Path] = append(g.requests[r.URL.Path], body)
+		g.lock.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{}"))
+	}))
+	return g
+}
+
+func (g *fakeGateway) received(path string) []json.RawMessage {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.requests[path]
+}
+
+type httpPoster struct{}
+
+func (httpPoster) PostBody(ctx context.Context, url string, header *http.Header, body io.Reader) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header = *header
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	return resp, respBody, err
+}
+
+// fakeCommands records the text commands the adapter produces
+type fakeCommands struct {
+	contents []string
+}
+
+func (f *fakeCommands) HandleMessage(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	f.contents = append(f.contents, msg.Contents())
+	return &cmdhandler.SimpleEmbedResponse{
+		To:          cmdhandler.UserMentionString(msg.UserID()),
+		Description: "ok",
+	}, nil
+}
+
+type fakeDeps struct {
+	dependencies
+	userAPI  storage.UserAPI
+	guildAPI storage.GuildAPI
+}
+
+func (d *fakeDeps) Logger() log.Logger         { return log.NewNopLogger() }
+func (d *fakeDeps) UserAPI() storage.UserAPI   { return d.userAPI }
+func (d *fakeDeps) GuildAPI() storage.GuildAPI { return d.guildAPI }
+
+func newTestHandlers(t *testing.T, apiURL string) (*handlers, *fakeCommands, *fakeDeps, func()) {
+	dir, err := ioutil.TempDir("", "interactions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0660, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := &fakeDeps{}
+	deps.userAPI, err = storage.NewBoltUserAPI(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.guildAPI, err = storage.NewBoltGuildAPI(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := &fakeCommands{}
+	h := &handlers{
+		deps:          deps,
+		http:          httpPoster{},
+		commands:      cmds,
+		config:        cmds,
+		cmdIndicator:  " ",
+		cfgIndicator:  " ",
+		apiURL:        apiURL,
+		applicationID: "42",
+	}
+
+	return h, cmds, deps, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestCommandText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"need item",
+			`{"name":"need","options":[{"name":"item","type":1,"options":[{"name":"character","type":3,"value":"Bob"},{"name":"item","type":3,"value":"Iron Ore"},{"name":"count","type":4,"value":3}]}]}`,
+			`need item Bob "Iron Ore" 3`,
+		},
+		{
+			"got item surplus",
+			`{"name":"got","options":[{"name":"item","type":1,"options":[{"name":"character","type":3,"value":"Bob"},{"name":"item","type":3,"value":"Iron Ore"},{"name":"count","type":4,"value":3},{"name":"surplus","type":5,"value":true}]}]}`,
+			`got item Bob "Iron Ore" 3 --surplus`,
+		},
+		{
+			"char show other user",
+			`{"name":"char","options":[{"name":"show","type":1,"options":[{"name":"character","type":3,"value":"Bob"},{"name":"user","type":6,"value":"123"}]}]}`,
+			"char show <@123> Bob",
+		},
+		{
+			"config set",
+			`{"name":"config-hw","options":[{"name":"set","type":1,"options":[{"name":"setting","type":3,"value":"lootsystem"},{"name":"value","type":3,"value":"rotation"}]}]}`,
+			"config-hw set lootsystem=rotation",
+		},
+		{
+			"list no options",
+			`{"name":"list","options":[{"name":"items","type":1}]}`,
+			"list items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data InteractionData
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal(err)
+			}
+			if got := commandText(data); got != tt.want {
+				t.Errorf("commandText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterCommands(t *testing.T) {
+	g := newFakeGateway()
+	defer g.Close()
+
+	h, _, _, cleanup := newTestHandlers(t, g.URL)
+	defer cleanup()
+
+	if err := h.RegisterCommands(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := g.received("/applications/42/commands")
+	if len(reqs) != len(Commands()) {
+		t.Fatalf("registered %d commands, want %d", len(reqs), len(Commands()))
+	}
+
+	var c Command
+	if err := json.Unmarshal(reqs[0], &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "char" || len(c.Options) == 0 {
+		t.Errorf("first command = %+v, want the char schema", c)
+	}
+}
+
+func TestHandleCommandInteraction(t *testing.T) {
+	g := newFakeGateway()
+	defer g.Close()
+
+	h, cmds, _, cleanup := newTestHandlers(t, g.URL)
+	defer cleanup()
+
+	var i Interaction
+	err := json.Unmarshal([]byte(`{
+		"id": 1001, "type": 2, "token": "tok", "channel_id": "55",
+		"user": {"id": "77"},
+		"data": {"name": "got", "options": [{"name": "trans", "type": 1, "options": [
+			{"name": "character", "type": 3, "value": "Bob"},
+			{"name": "item", "type": 3, "value": "Rubedite"}
+		]}]}
+	}`), &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := h.HandleInteraction(context.Background(), i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cmds.contents) != 1 || cmds.contents[0] != " got trans Bob Rubedite" {
+		t.Errorf("command contents = %q, want %q", cmds.contents, " got trans Bob Rubedite")
+	}
+	if resp.Type != CallbackMessage {
+		t.Errorf("response type = %d, want %d", resp.Type, CallbackMessage)
+	}
+	if !strings.Contains(string(resp.Data), `"embeds"`) {
+		t.Errorf("response data %s does not carry an embeds list", resp.Data)
+	}
+
+	if err = h.post(context.Background(), g.URL+"/interactions/1001/tok/callback", resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.received("/interactions/1001/tok/callback")) != 1 {
+		t.Error("callback was not delivered to the gateway")
+	}
+}
+
+func TestConfigInteractionNeedsGuild(t *testing.T) {
+	h, cmds, _, cleanup := newTestHandlers(t, "")
+	defer cleanup()
+
+	i := Interaction{
+		Type: InteractionCommand,
+		User: &User{ID: "77"},
+		Data: InteractionData{Name: "config-hw", Options: []InteractionOption{{Name: "list", Type: OptionSubCommand}}},
+	}
+
+	resp, err := h.HandleInteraction(context.Background(), i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds.contents) != 0 {
+		t.Errorf("config command ran in a DM: %q", cmds.contents)
+	}
+	if !strings.Contains(string(resp.Data), `"flags":64`) {
+		t.Errorf("config response %s is not ephemeral", resp.Data)
+	}
+}
+
+func TestInteractionIgnoredChannel(t *testing.T) {
+	h, cmds, deps, cleanup := newTestHandlers(t, "")
+	defer cleanup()
+
+	tx, err := deps.guildAPI.NewTransaction(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deferutil.CheckDefer(tx.Rollback)
+
+	bGuild, err := tx.AddGuild("99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := bGuild.GetSettings()
+	if err = s.Set(storage.SettingIgnoredChannels, "55"); err != nil {
+		t.Fatal(err)
+	}
+	bGuild.SetSettings(s)
+	if err = tx.SaveGuild(bGuild); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	customID, err := commands.ComponentState{Action: commands.ComponentPage, Owner: "77", Char: "Bob", Page: 2}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		i    Interaction
+	}{
+		{
+			name: "command",
+			i: Interaction{
+				Type: InteractionCommand,
+				Data: InteractionData{Name: "list"},
+			},
+		},
+		{
+			name: "component",
+			i: Interaction{
+				Type: InteractionComponent,
+				Data: InteractionData{CustomID: customID},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.i.GuildID = "99"
+			tt.i.ChannelID = "55"
+			tt.i.Member = &Member{User: User{ID: "77"}}
+
+			resp, err := h.HandleInteraction(context.Background(), tt.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds.contents) != 0 {
+				t.Errorf("command ran in an ignored channel: %q", cmds.contents)
+			}
+			if !strings.Contains(string(resp.Data), `"flags":64`) {
+				t.Errorf("response %s is not ephemeral", resp.Data)
+			}
+		})
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	h, _, deps, cleanup := newTestHandlers(t, "")
+	defer cleanup()
+
+	tx, err := deps.userAPI.NewTransaction(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deferutil.CheckDefer(tx.Rollback)
+
+	bUser, err := tx.AddUser("77")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := bUser.AddCharacter("Bob")
+	bob.IncrNeededItem("Iron Ore", 2)
+	bob.IncrNeededItem("Iron Ingot", 1)
+	bob.IncrNeededItem("Cloth", 1)
+	alice := bUser.AddCharacter("Alice")
+	alice.IncrNeededItem("Iridium", 1)
+
+	if err = tx.SaveUser(bUser); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	choices := func(i Interaction) []Choice {
+		resp, err := h.HandleInteraction(context.Background(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Type != CallbackAutocomplete {
+			t.Fatalf("response type = %d, want %d", resp.Type, CallbackAutocomplete)
+		}
+		var data struct {
+			Choices []Choice `json:"choices"`
+		}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		return data.Choices
+	}
+
+	got := choices(Interaction{
+		Type: InteractionAutocomplete,
+		User: &User{ID: "77"},
+		Data: InteractionData{Name: "got", Options: []InteractionOption{{Name: "item", Type: OptionSubCommand, Options: []InteractionOption{
+			{Name: "character", Type: OptionString, Value: "bob"},
+			{Name: "item", Type: OptionString, Value: "ir", Focused: true},
+		}}}},
+	})
+	if len(got) != 2 || got[0].Name != "Iron Ingot" || got[1].Name != "Iron Ore" {
+		t.Errorf("item choices = %+v, want Iron Ingot and Iron Ore", got)
+	}
+
+	got = choices(Interaction{
+		Type: InteractionAutocomplete,
+		User: &User{ID: "77"},
+		Data: InteractionData{Name: "list", Options: []InteractionOption{{Name: "items", Type: OptionSubCommand, Options: []InteractionOption{
+			{Name: "character", Type: OptionString, Value: "", Focused: true},
+		}}}},
+	})
+	if len(got) != 2 || got[0].Name != "Alice" || got[1].Name != "Bob" {
+		t.Errorf("character choices = %+v, want Alice and Bob", got)
+	}
+}
+
+func TestComponentState(t *testing.T) {
+	state := commands.ComponentState{Action: commands.ComponentGotOne, Owner: "77", Char: "Bob: the 2nd", Kind: "item", Name: "Iron:Ore %x", Page: 2}
+
+	id, err := state.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := commands.DecodeComponentState(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != state {
+		t.Errorf("decoded %+v, want %+v", got, state)
+	}
+
+	state.Name = strings.Repeat("x", 100)
+	if _, err = state.Encode(); err != commands.ErrBadComponentState {
+		t.Errorf("overlong state encoded with err %v", err)
+	}
+	if _, err = commands.DecodeComponentState("hw1:g1:77"); err != commands.ErrBadComponentState {
+		t.Errorf("truncated id decoded with err %v", err)
+	}
+}
+
+func TestComponentInteraction(t *testing.T) {
+	h, cmds, deps, cleanup := newTestHandlers(t, "")
+	defer cleanup()
+
+	tx, err := deps.userAPI.NewTransaction(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deferutil.CheckDefer(tx.Rollback)
+
+	bUser, err := tx.AddUser("77")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bUser.AddCharacter("Bob").IncrNeededItem("Iron Ore", 3)
+	if err = tx.SaveUser(bUser); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	press := func(user string, state commands.ComponentState) Response {
+		id, err := state.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := h.HandleInteraction(context.Background(), Interaction{
+			Type: InteractionComponent,
+			User: &User{ID: ID(user)},
+			Data: InteractionData{CustomID: id, ComponentType: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := press("77", commands.ComponentState{Action: commands.ComponentGotAll, Owner: "77", Char: "Bob", Kind: "item", Name: "Iron Ore", Page: 1})
+	want := []string{` got item Bob "Iron Ore" 3`, " char show <@77> Bob --page 1"}
+	if strings.Join(cmds.contents, "|") != strings.Join(want, "|") {
+		t.Errorf("command contents = %q, want %q", cmds.contents, want)
+	}
+	if resp.Type != CallbackUpdateMessage {
+		t.Errorf("response type = %d, want %d", resp.Type, CallbackUpdateMessage)
+	}
+
+	cmds.contents = nil
+	resp = press("88", commands.ComponentState{Action: commands.ComponentGotOne, Owner: "77", Char: "Bob", Kind: "item", Name: "Iron Ore", Page: 1})
+	if len(cmds.contents) != 0 {
+		t.Errorf("non-owner press ran commands: %q", cmds.contents)
+	}
+	if resp.Type != CallbackMessage || !strings.Contains(string(resp.Data), `"flags":64`) {
+		t.Errorf("non-owner press response = %d %s, want an ephemeral message", resp.Type, resp.Data)
+	}
+
+	resp = press("88", commands.ComponentState{Action: commands.ComponentPage, Owner: "77", Char: "Bob", Page: 2})
+	if len(cmds.contents) != 1 || cmds.contents[0] != " char show <@77> Bob --page 2" {
+		t.Errorf("command contents = %q, want the page for the non-owner", cmds.contents)
+	}
+	if resp.Type != CallbackMessage || !strings.Contains(string(resp.Data), `"flags":64`) {
+		t.Errorf("non-owner page response = %d %s, want an ephemeral message", resp.Type, resp.Data)
+	}
+
+	cmds.contents = nil
+	resp = press("77", commands.ComponentState{Action: commands.ComponentPage, Owner: "77", Char: "Bob", Page: 2})
+	if resp.Type != CallbackUpdateMessage {
+		t.Errorf("owner page response type = %d, want %d", resp.Type, CallbackUpdateMessage)
+	}
+
+	cmds.contents = nil
+	resp = press("88", commands.ComponentState{Action: commands.ComponentListPage, Owner: "77", Name: "list items", Page: 2})
+	if len(cmds.contents) != 1 || cmds.contents[0] != " list items <@77>  --page 2" {
+		t.Errorf("command contents = %q, want the list page for the non-owner", cmds.contents)
+	}
+	if resp.Type != CallbackMessage || !strings.Contains(string(resp.Data), `"flags":64`) {
+		t.Errorf("non-owner list page response = %d %s, want an ephemeral message", resp.Type, resp.Data)
+	}
+
+	resp = press("77", commands.ComponentState{Action: commands.ComponentListPage, Owner: "77", Name: "list items", Page: 2})
+	if resp.Type != CallbackUpdateMessage {
+		t.Errorf("owner list page response type = %d, want %d", resp.Type, CallbackUpdateMessage)
+	}
+}
@@ -0,0 +1,171 @@
+package interactions
+
+import (
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+func characterOption(required bool) CommandOption {
+	return CommandOption{
+		Type:         OptionString,
+		Name:         "character",
+		Description:  "The character name",
+		Required:     required,
+		Autocomplete: true,
+	}
+}
+
+func countOption() CommandOption {
+	return CommandOption{
+		Type:        OptionInteger,
+		Name:        "count",
+		Description: "How many (default 1)",
+	}
+}
+
+func pageOption() CommandOption {
+	return CommandOption{
+		Type:        OptionInteger,
+		Name:        "page",
+		Description: "Which page of a long list to show (default 1)",
+	}
+}
+
+func userOption() CommandOption {
+	return CommandOption{
+		Type:        OptionUser,
+		Name:        "user",
+		Description: "Another guild member (default you)",
+	}
+}
+
+// needGotOptions builds the item/pts/trans subcommands shared by need and got
+func needGotOptions(verb string) []CommandOption {
+	sub := func(name, what, arg string) CommandOption {
+		return CommandOption{
+			Type:        OptionSubCommand,
+			Name:        name,
+			Description: verb + " " + what,
+			Options: []CommandOption{
+				characterOption(true),
+				{
+					Type:         OptionString,
+					Name:         arg,
+					Description:  "The " + arg + " name",
+					Required:     true,
+					Autocomplete: true,
+				},
+				countOption(),
+			},
+		}
+	}
+
+	item := sub("item", "items", "item")
+	if verb == "Got" {
+		item.Options = append(item.Options, CommandOption{
+			Type:        OptionBoolean,
+			Name:        "surplus",
+			Description: "Keep anything beyond what was needed as spare items",
+		})
+	}
+
+	return []CommandOption{
+		item,
+		sub("pts", "skill points", "skill"),
+		sub("trans", "transmutes", "item"),
+	}
+}
+
+func listOptions() []CommandOption {
+	sub := func(name, what string) CommandOption {
+		return CommandOption{
+			Type:        OptionSubCommand,
+			Name:        name,
+			Description: "Show needed " + what,
+			Options:     []CommandOption{userOption(), characterOption(false), pageOption()},
+		}
+	}
+
+	return []CommandOption{
+		sub("items", "items"),
+		sub("pts", "skill points"),
+		sub("trans", "transmutes"),
+	}
+}
+
+func settingChoices() []Choice {
+	defs := storage.SettingDefs()
+	choices := make([]Choice, 0, len(defs))
+	for _, d := range defs {
+		if len(choices) == maxChoices {
+			break
+		}
+		choices = append(choices, Choice{Name: d.Name, Value: d.Name})
+	}
+	return choices
+}
+
+func settingOption(required bool) CommandOption {
+	return CommandOption{
+		Type:        OptionString,
+		Name:        "setting",
+		Description: "The setting name",
+		Required:    required,
+		Choices:     settingChoices(),
+	}
+}
+
+// Commands returns the application command schema for the slash commands that
+// mirror the text commands
+func Commands() []Command {
+	return []Command{
+		{
+			Name:        "char",
+			Description: "Manage your characters",
+			Options: []CommandOption{
+				{Type: OptionSubCommand, Name: "list", Description: "List characters", Options: []CommandOption{userOption()}},
+				{Type: OptionSubCommand, Name: "show", Description: "Show a character's needs", Options: []CommandOption{characterOption(true), userOption(), pageOption()}},
+				{Type: OptionSubCommand, Name: "create", Description: "Create a character", Options: []CommandOption{{Type: OptionString, Name: "character", Description: "The new character name", Required: true}}},
+				{Type: OptionSubCommand, Name: "delete", Description: "Delete a character", Options: []CommandOption{characterOption(true)}},
+			},
+		},
+		{
+			Name:        "need",
+			Description: "Add to a character's needs",
+			Options:     needGotOptions("Need"),
+		},
+		{
+			Name:        "got",
+			Description: "Remove from a character's needs",
+			Options:     needGotOptions("Got"),
+		},
+		{
+			Name:        "list",
+			Description: "List needs across characters",
+			Options:     listOptions(),
+		},
+		{
+			Name:        "config-hw",
+			Description: "Configure the bot for this guild",
+			Options: []CommandOption{
+				{Type: OptionSubCommand, Name: "list", Description: "Show all settings"},
+				{Type: OptionSubCommand, Name: "get", Description: "Show a setting", Options: []CommandOption{settingOption(true)}},
+				{Type: OptionSubCommand, Name: "set", Description: "Change a setting", Options: []CommandOption{
+					settingOption(true),
+					{Type: OptionString, Name: "value", Description: "The new value", Required: true},
+				}},
+				{Type: OptionSubCommand, Name: "reset", Description: "Reset one or all settings", Options: []CommandOption{settingOption(false)}},
+				{Type: OptionSubCommand, Name: "describe", Description: "Explain one or all settings", Options: []CommandOption{settingOption(false)}},
+			},
+		},
+	}
+}
+
+// configCommands are the slash commands handled by the config handler
+var configCommands = map[string]bool{
+	"config-hw": true,
+}
+
+// keyValueSubcommands are subcommands whose options are passed as a single key=value argument
+var keyValueSubcommands = map[string]bool{
+	"config-hw set": true,
+}
@@ -0,0 +1,156 @@
+package interactions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Interaction types sent by discord
+const (
+	InteractionPing         = 1
+	InteractionCommand      = 2
+	InteractionComponent    = 3
+	InteractionAutocomplete = 4
+)
+
+// Interaction callback types sent back to discord
+const (
+	CallbackPong          = 1
+	CallbackMessage       = 4
+	CallbackUpdateMessage = 7
+	CallbackAutocomplete  = 8
+)
+
+// Application command option types
+const (
+	OptionSubCommand = 1
+	OptionString     = 3
+	OptionInteger    = 4
+	OptionBoolean    = 5
+	OptionUser       = 6
+)
+
+const flagEphemeral = 64
+
+const permissionAdministrator = 0x8
+
+const maxChoices = 25
+
+// ID is a discord snowflake that may arrive as either a json string or number
+type ID string
+
+// UnmarshalJSON accepts both quoted and bare ids
+func (id *ID) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	if string(b) == "null" {
+		*id = ""
+		return nil
+	}
+	*id = ID(b)
+	return nil
+}
+
+// Interaction is an INTERACTION_CREATE event
+type Interaction struct {
+	ID        ID              `json:"id"`
+	Type      int             `json:"type"`
+	Token     string          `json:"token"`
+	GuildID   ID              `json:"guild_id,omitempty"`
+	ChannelID ID              `json:"channel_id,omitempty"`
+	Member    *Member         `json:"member,omitempty"`
+	User      *User           `json:"user,omitempty"`
+	Data      InteractionData `json:"data"`
+}
+
+// UserID returns the id of the user that triggered the interaction, in a guild or a DM
+func (i Interaction) UserID() ID {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// Member is the guild member that triggered an interaction
+type Member struct {
+	User        User   `json:"user"`
+	Roles       []ID   `json:"roles"`
+	Permissions string `json:"permissions"`
+}
+
+// IsAdmin checks the member's computed permissions for ADMINISTRATOR
+func (m *Member) IsAdmin() bool {
+	perms, err := strconv.ParseUint(m.Permissions, 10, 64)
+	return err == nil && perms&permissionAdministrator != 0
+}
+
+// User is a discord user
+type User struct {
+	ID ID `json:"id"`
+}
+
+// InteractionData is the command (or component) data of an interaction
+type InteractionData struct {
+	Name          string              `json:"name,omitempty"`
+	Options       []InteractionOption `json:"options,omitempty"`
+	CustomID      string              `json:"custom_id,omitempty"`
+	ComponentType int                 `json:"component_type,omitempty"`
+	Values        []string            `json:"values,omitempty"`
+}
+
+// InteractionOption is an argument value supplied to an application command
+type InteractionOption struct {
+	Name    string              `json:"name"`
+	Type    int                 `json:"type"`
+	Value   interface{}         `json:"value,omitempty"`
+	Focused bool                `json:"focused,omitempty"`
+	Options []InteractionOption `json:"options,omitempty"`
+}
+
+// StringValue returns the option value formatted as command text
+func (o InteractionOption) StringValue() string {
+	switch v := o.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Response is an interaction callback
+type Response struct {
+	Type int             `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Choice is an autocomplete suggestion
+type Choice struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CommandOption is an argument in an application command schema
+type CommandOption struct {
+	Type         int             `json:"type"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Required     bool            `json:"required,omitempty"`
+	Autocomplete bool            `json:"autocomplete,omitempty"`
+	Choices      []Choice        `json:"choices,omitempty"`
+	Options      []CommandOption `json:"options,omitempty"`
+}
+
+// Command is an application command schema registered with discord
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
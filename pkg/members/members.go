@@ -0,0 +1,70 @@
+package members
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gsmcwhirter/discord-bot-lib/httpclient"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/pkg/errors"
+)
+
+// ErrLookupFailed is the error returned when discord does not answer a member lookup
+var ErrLookupFailed = errors.New("could not look up the guild member")
+
+type dependencies interface {
+	HTTPClient() httpclient.HTTPClient
+}
+
+// Lookup finds a user's membership of a guild, for commands that arrive
+// without it (direct messages naming a guild)
+type Lookup interface {
+	// Roles returns the user's role ids in the guild, and whether they are a
+	// member of it at all
+	Roles(ctx context.Context, gid, uid snowflake.Snowflake) (roles []string, member bool, err error)
+}
+
+// Options is how to point a Lookup at the discord api
+type Options struct {
+	APIURL string
+}
+
+type lookup struct {
+	deps   dependencies
+	apiURL string
+}
+
+// NewLookup creates a Lookup that asks the discord api
+func NewLookup(deps dependencies, opts Options) Lookup {
+	return &lookup{
+		deps:   deps,
+		apiURL: opts.APIURL,
+	}
+}
+
+func (l *lookup) Roles(ctx context.Context, gid, uid snowflake.Snowflake) ([]string, bool, error) {
+	header := http.Header{}
+	resp, body, err := l.deps.HTTPClient().GetBody(ctx, fmt.Sprintf("%s/guilds/%s/members/%s", l.apiURL, gid.ToString(), uid.ToString()), &header)
+	if err != nil {
+		return nil, false, errors.Wrap(err, ErrLookupFailed.Error())
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, false, errors.Wrap(ErrLookupFailed, fmt.Sprintf("status %d", resp.StatusCode))
+	}
+
+	member := struct {
+		Roles []string `json:"roles"`
+	}{}
+	if err = json.Unmarshal(body, &member); err != nil {
+		return nil, false, errors.Wrap(err, "could not parse guild member")
+	}
+
+	return member.Roles, true, nil
+}
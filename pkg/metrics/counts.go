@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CountFunc reports how many users, characters and guilds are stored
+type CountFunc func() (users, characters, guilds int, err error)
+
+var (
+	usersDesc      = prometheus.NewDesc(namespace+"_users", "Users stored.", nil, nil)
+	charactersDesc = prometheus.NewDesc(namespace+"_characters", "Characters stored.", nil, nil)
+	guildsDesc     = prometheus.NewDesc(namespace+"_guilds", "Guilds the stored users have used the bot in.", nil, nil)
+)
+
+// countCollector reads the counts fresh on each scrape rather than keeping
+// gauges up to date on every change
+type countCollector struct {
+	counts CountFunc
+}
+
+func (c countCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usersDesc
+	ch <- charactersDesc
+	ch <- guildsDesc
+}
+
+func (c countCollector) Collect(ch chan<- prometheus.Metric) {
+	users, characters, guilds, err := c.counts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(usersDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(usersDesc, prometheus.GaugeValue, float64(users))
+	ch <- prometheus.MustNewConstMetric(charactersDesc, prometheus.GaugeValue, float64(characters))
+	ch <- prometheus.MustNewConstMetric(guildsDesc, prometheus.GaugeValue, float64(guilds))
+}
+
+// RegisterCounts adds the user, character and guild gauges, read with counts
+// whenever the metrics are scraped
+func RegisterCounts(counts CountFunc) error {
+	return registry.Register(countCollector{counts: counts})
+}
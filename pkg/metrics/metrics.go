@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "havewant"
+
+// Command results, for CommandHandled
+const (
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultUnknown = "unknown"
+	ResultIgnored = "ignored"
+)
+
+// Storage transaction results, for StorageTx
+const (
+	TxCommit   = "commit"
+	TxRollback = "rollback"
+	TxError    = "error"
+)
+
+// Webhook delivery results, for WebhookDelivery
+const (
+	WebhookSent    = "sent"
+	WebhookRetry   = "retry"
+	WebhookDropped = "dropped"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	commandsHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_handled_total",
+		Help:      "Commands handled, by command name and result.",
+	}, []string{"command", "result"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time taken to handle a command, not counting sending the reply.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	storageTxDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_tx_duration_seconds",
+		Help:      "Time storage transactions were held open, by api, mode and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"api", "mode", "result"})
+
+	storageTxConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_tx_conflicts_total",
+		Help:      "Write transactions that had to wait for another open write transaction, by api.",
+	}, []string{"api"})
+
+	rateLimitWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time spent waiting on the message rate limiter, by sender.",
+		Buckets:   []float64{.001, .01, .1, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"sender"})
+
+	sendFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "send_failures_total",
+		Help:      "Messages discord did not accept, by http status code (or \"error\" when there was no response).",
+	}, []string{"status"})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		commandsHandled,
+		commandDuration,
+		storageTxDuration,
+		storageTxConflicts,
+		rateLimitWait,
+		sendFailures,
+		webhookDeliveries,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// CommandHandled records that a command was handled with the given result, and how long it took
+func CommandHandled(command, result string, took time.Duration) {
+	commandsHandled.WithLabelValues(command, result).Inc()
+	commandDuration.WithLabelValues(command).Observe(took.Seconds())
+}
+
+// StorageTx records how long a storage transaction was open, and how it ended
+func StorageTx(api string, writable bool, result string, took time.Duration) {
+	mode := "read"
+	if writable {
+		mode = "write"
+	}
+	storageTxDuration.WithLabelValues(api, mode, result).Observe(took.Seconds())
+}
+
+// StorageTxConflict records that a write transaction had to wait for another one
+func StorageTxConflict(api string) {
+	storageTxConflicts.WithLabelValues(api).Inc()
+}
+
+// RateLimitWait records how long a sender waited on the message rate limiter
+func RateLimitWait(sender string, took time.Duration) {
+	rateLimitWait.WithLabelValues(sender).Observe(took.Seconds())
+}
+
+// SendFailed records a message that discord did not accept; a status of 0
+// means the request failed without a response
+func SendFailed(status int) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	sendFailures.WithLabelValues(label).Inc()
+}
+
+// WebhookDelivery records the result of an attempt to deliver a webhook event
+func WebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -13,9 +15,15 @@ import (
 	"github.com/gsmcwhirter/discord-bot-lib/logging"
 	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
 	"github.com/gsmcwhirter/discord-bot-lib/wsclient"
+	"github.com/gsmcwhirter/go-util/deferutil"
 	"github.com/gsmcwhirter/go-util/parser"
 	"golang.org/x/time/rate"
 
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/dm"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/inflight"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/members"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/metrics"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/permissions"
 	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
 )
 
@@ -25,27 +33,64 @@ var errUnauthorized = errors.New("unauthorized")
 // if the bot should not provide a response
 var ErrNoResponse = errors.New("no response")
 
+// reloadCommand is the command bot owners use to reload the configuration
+const reloadCommand = "reload-hw"
+
+// webCommand is the command to get a login link for the web dashboard
+const webCommand = "web"
+
 type dependencies interface {
 	Logger() log.Logger
 	GuildAPI() storage.GuildAPI
+	UserAPI() storage.UserAPI
+	DMSender() dm.Sender
 	CommandHandler() *cmdhandler.CommandHandler
 	ConfigHandler() *cmdhandler.CommandHandler
 	MessageRateLimiter() *rate.Limiter
 	BotSession() *etfapi.Session
+	InFlight() inflight.Tracker
+	Reloader() Reloader
+	Dashboard() LoginLinker
+	Members() members.Lookup
+}
+
+// Reloader re-reads the bot configuration, applying what can change while running
+type Reloader interface {
+	IsOwner(uid snowflake.Snowflake) bool
+	Reload() (applied, restart []string, err error)
+}
+
+// LoginLinker creates one-time login links for the web dashboard; Dashboard()
+// returns nil when the dashboard is not enabled
+type LoginLinker interface {
+	LoginLink(userID, guildID string, officer bool) (string, error)
 }
 
 // Handlers is the dependency interface for a set of message handlers that connect themselves
 // to a discord bot
 type Handlers interface {
 	ConnectToBot(bot.DiscordBot)
+
+	// SetOptions changes the default command indicator and colors while running
+	SetOptions(opts Options)
+}
+
+// session is the part of the bot session state that the handlers rely on
+type session interface {
+	GuildOfChannel(cid snowflake.Snowflake) (snowflake.Snowflake, bool)
+	IsGuildAdmin(gid, uid snowflake.Snowflake) bool
 }
 
 type handlers struct {
 	bot                     bot.DiscordBot
 	deps                    dependencies
+	session                 session
 	defaultCommandIndicator string
 	successColor            int
 	errorColor              int
+
+	// guards the options, which can change with SetOptions
+	optsLock sync.RWMutex
 }
 
 // Options is how to set response colors etc. when creating a Handlers
@@ -58,7 +103,8 @@ type Options struct {
 // NewHandlers creates a new Handlers object
 func NewHandlers(deps dependencies, opts Options) Handlers {
 	h := handlers{
-		deps: deps,
+		deps:                    deps,
+		session:                 deps.BotSession(),
 		defaultCommandIndicator: opts.DefaultCommandIndicator,
 		successColor:            opts.SuccessColor,
 		errorColor:              opts.ErrorColor,
@@ -71,47 +117,233 @@ func (h *handlers) ConnectToBot(bot bot.DiscordBot) {
 	h.bot = bot
 
 	bot.AddMessageHandler("MESSAGE_CREATE", h.handleMessage)
+	bot.AddMessageHandler("GUILD_MEMBER_REMOVE", h.handleMemberRemove)
+}
+
+func (h *handlers) SetOptions(opts Options) {
+	h.optsLock.Lock()
+	defer h.optsLock.Unlock()
+
+	h.defaultCommandIndicator = opts.DefaultCommandIndicator
+	h.successColor = opts.SuccessColor
+	h.errorColor = opts.ErrorColor
+}
+
+func (h *handlers) defaultIndicator() string {
+	h.optsLock.RLock()
+	defer h.optsLock.RUnlock()
+
+	return h.defaultCommandIndicator
+}
+
+func (h *handlers) colors() (success, failure int) {
+	h.optsLock.RLock()
+	defer h.optsLock.RUnlock()
+
+	return h.successColor, h.errorColor
 }
 
 func (h *handlers) channelGuild(cid snowflake.Snowflake) (gid snowflake.Snowflake) {
-	gid, _ = h.deps.BotSession().GuildOfChannel(cid)
+	gid, _ = h.session.GuildOfChannel(cid)
 	return
 }
 
-func (h *handlers) guildCommandIndicator(gid snowflake.Snowflake) string {
+func (h *handlers) guildSettings(gid snowflake.Snowflake) storage.GuildSettings {
 	if gid == 0 {
-		return h.defaultCommandIndicator
+		return storage.GuildSettings{}
 	}
 
 	s, err := storage.GetSettings(h.deps.GuildAPI(), gid)
 	if err != nil {
-		return h.defaultCommandIndicator
+		_ = level.Error(h.deps.Logger()).Log("message", "could not load guild settings", "guild_id", gid.ToString(), "err", err)
+		return storage.GuildSettings{}
+	}
+
+	return s
+}
+
+func (h *handlers) guildCommandIndicator(s storage.GuildSettings) string {
+	if s.ControlSequence() == "" {
+		return h.defaultIndicator()
+	}
+
+	return s.ControlSequence()
+}
+
+// memberRoles extracts the author's role ids from a guild MESSAGE_CREATE payload
+func memberRoles(p *etfapi.Payload) []string {
+	memberEl, ok := p.Data["member"]
+	if !ok || memberEl.IsNil() {
+		return nil
+	}
+
+	member, err := memberEl.ToMap()
+	if err != nil {
+		return nil
+	}
+
+	rolesEl, ok := member["roles"]
+	if !ok {
+		return nil
+	}
+
+	roleList, err := rolesEl.ToList()
+	if err != nil {
+		return nil
+	}
+
+	roles := make([]string, 0, len(roleList))
+	for _, roleEl := range roleList {
+		rid, err := etfapi.SnowflakeFromElement(roleEl)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, rid.ToString())
+	}
+
+	return roles
+}
+
+// userCapability resolves the user's capability in the guild the message was
+// routed to; a DM carries no member, so its roles come from the route
+func (h *handlers) userCapability(rt route, uid snowflake.Snowflake, p *etfapi.Payload) permissions.Capability {
+	if rt.gid == 0 {
+		return permissions.Member
+	}
+
+	roles := rt.roles
+	if !rt.isDM {
+		roles = memberRoles(p)
+	}
+
+	return permissions.Resolve(rt.settings, h.session.IsGuildAdmin(rt.gid, uid), roles)
+}
+
+// channelAllowed checks the guild's channel restrictions; DMs have none
+func (h *handlers) channelAllowed(rt route, cid snowflake.Snowflake, capability permissions.Capability) bool {
+	if rt.isDM || rt.gid == 0 {
+		return true
+	}
+
+	return permissions.ChannelAllowed(rt.settings, cid.ToString(), capability)
+}
+
+func (h *handlers) wantsDMReplies(uid snowflake.Snowflake) bool {
+	t, err := h.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return false
 	}
+	defer deferutil.CheckDefer(t.Rollback)
 
-	if s.ControlSequence == "" {
-		return h.defaultCommandIndicator
+	bUser, err := t.GetUser(uid.ToString())
+	if err != nil {
+		return false
 	}
 
-	return s.ControlSequence
+	return bUser.GetDMReplies()
 }
 
-func (h *handlers) attemptConfigAndAdminHandlers(msg cmdhandler.Message, req wsclient.WSMessage, cmdIndicator string, content string, m etfapi.Message, gid snowflake.Snowflake) (resp cmdhandler.Response, err error) {
-	// TODO: check auth
+func (h *handlers) attemptConfigAndAdminHandlers(msg cmdhandler.Message, rt route, capability permissions.Capability) (resp cmdhandler.Response, err error) {
 	logger := logging.WithMessage(msg, h.deps.Logger())
 
-	if !h.deps.BotSession().IsGuildAdmin(gid, m.AuthorID()) {
-		_ = level.Debug(logging.WithContext(req.Ctx, h.deps.Logger())).Log("message", "non-admin trying to config", "author_id", m.AuthorID().ToString(), "guild_id", gid.ToString())
+	if capability < permissions.Required(rt.command) || capability < permissions.Officer {
+		_ = level.Debug(logger).Log("message", "user lacks capability for admin command", "command", rt.command, "capability", capability.String())
 
 		err = errUnauthorized
 		return
 	}
 
-	_ = level.Debug(logger).Log("message", "admin trying to config")
-	cmdContent := h.deps.ConfigHandler().CommandIndicator() + strings.TrimPrefix(content, cmdIndicator)
+	_ = level.Debug(logger).Log("message", "privileged user trying admin command", "command", rt.command, "capability", capability.String())
+	cmdContent := h.deps.ConfigHandler().CommandIndicator() + rt.content
 	resp, err = h.deps.ConfigHandler().HandleMessage(cmdhandler.NewWithContents(msg, cmdContent))
 	return
 }
 
+// reload re-reads the configuration for a bot owner, listing what changed
+func (h *handlers) reload(msg cmdhandler.Message) (cmdhandler.Response, error) {
+	r := &cmdhandler.EmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	applied, restart, err := h.deps.Reloader().Reload()
+	if err != nil {
+		return r, err
+	}
+
+	r.Description = "reloaded the configuration"
+	if len(applied) == 0 && len(restart) == 0 {
+		r.Description += "; nothing changed"
+	}
+
+	if len(applied) > 0 {
+		r.Fields = append(r.Fields, cmdhandler.EmbedField{
+			Name: "*Applied*",
+			Val:  strings.Join(applied, "\n"),
+		})
+	}
+
+	if len(restart) > 0 {
+		r.Fields = append(r.Fields, cmdhandler.EmbedField{
+			Name: "*Changed, but needs a restart*",
+			Val:  strings.Join(restart, "\n"),
+		})
+	}
+
+	return r, nil
+}
+
+// webLogin gives the user a one-time link to log in to the web dashboard, with
+// officer access if they are an officer of the guild the command was sent for.
+// Anyone holding the link can use it, so it is only ever sent by DM.
+func (h *handlers) webLogin(msg cmdhandler.Message, rt route, capability permissions.Capability) (cmdhandler.Response, error) {
+	r := &cmdhandler.SimpleEmbedResponse{
+		To: cmdhandler.UserMentionString(msg.UserID()),
+	}
+
+	guildID := ""
+	if rt.gid != 0 {
+		guildID = rt.gid.ToString()
+	}
+
+	link, err := h.deps.Dashboard().LoginLink(msg.UserID().ToString(), guildID, guildID != "" && capability >= permissions.Officer)
+	if err != nil {
+		return r, err
+	}
+
+	text := fmt.Sprintf("Log in to the dashboard here: %s\nThe link works once, and only for a few minutes; do not share it.", link)
+	if rt.isDM {
+		r.Description = text
+		return r, nil
+	}
+
+	successColor, _ := h.colors()
+	err = h.deps.DMSender().SendDM(msg.Context(), msg.UserID(), (&cmdhandler.SimpleEmbedResponse{
+		Description: text,
+		Color:       successColor,
+	}).ToMessage())
+	if err != nil {
+		_ = level.Error(logging.WithMessage(msg, h.deps.Logger())).Log("message", "could not send login link by DM", "err", err)
+		return r, errors.New("could not send you a login link; check that you allow direct messages from server members")
+	}
+
+	r.Description = "sent you a login link by direct message"
+	return r, nil
+}
+
+// recordCommand reports a handled command to the metrics. Unknown commands are
+// all counted under one name, since anyone can send any text.
+func recordCommand(command string, err error, took time.Duration) {
+	result := metrics.ResultOK
+	switch {
+	case err == parser.ErrUnknownCommand:
+		command, result = "unknown", metrics.ResultUnknown
+	case err != nil && err != ErrNoResponse:
+		result = metrics.ResultError
+	}
+
+	metrics.CommandHandled(command, result, took)
+}
+
 func (h *handlers) handleMessage(p *etfapi.Payload, req wsclient.WSMessage, respChan chan<- wsclient.WSMessage) {
 	if h.bot == nil {
 		return
@@ -125,6 +357,12 @@ func (h *handlers) handleMessage(p *etfapi.Payload, req wsclient.WSMessage, resp
 
 	logger := logging.WithContext(req.Ctx, h.deps.Logger())
 
+	if !h.deps.InFlight().Start() {
+		_ = level.Info(logger).Log("message", "shutting down; dropping message")
+		return
+	}
+	defer h.deps.InFlight().Done()
+
 	m, err := etfapi.MessageFromElementMap(p.Data)
 	if err != nil {
 		_ = level.Error(logger).Log("message", "error inflating message", "err", err)
@@ -142,22 +380,63 @@ func (h *handlers) handleMessage(p *etfapi.Payload, req wsclient.WSMessage, resp
 		return
 	}
 
-	gid := h.channelGuild(m.ChannelID())
-	cmdIndicator := h.guildCommandIndicator(gid)
+	if authorIsBot(p) {
+		_ = level.Debug(logger).Log("message", "ignoring message from a bot")
+		return
+	}
 
-	if !strings.HasPrefix(content, cmdIndicator) {
+	rt, ok, err := h.routeMessage(req.Ctx, m.AuthorID(), m.ChannelID(), content)
+	if !ok {
 		_ = level.Info(logger).Log("message", "not a command")
 		return
 	}
 
-	msg := cmdhandler.NewSimpleMessage(req.Ctx, m.AuthorID(), gid, m.ChannelID(), m.ID(), "")
+	start := time.Now()
+	msg := cmdhandler.NewSimpleMessage(req.Ctx, m.AuthorID(), rt.gid, m.ChannelID(), m.ID(), "")
 	logger = logging.WithMessage(msg, h.deps.Logger())
-	resp, err := h.attemptConfigAndAdminHandlers(msg, req, cmdIndicator, content, m, gid)
 
-	if err != nil && (err == errUnauthorized || err == parser.ErrUnknownCommand) {
-		_ = level.Debug(logger).Log("message", "admin not successful; processing as real message")
-		cmdContent := h.deps.CommandHandler().CommandIndicator() + strings.TrimPrefix(content, cmdIndicator)
-		resp, err = h.deps.CommandHandler().HandleMessage(cmdhandler.NewWithContents(msg, cmdContent))
+	var resp cmdhandler.Response
+	if err != nil {
+		resp = &cmdhandler.SimpleEmbedResponse{
+			To: cmdhandler.UserMentionString(m.AuthorID()),
+		}
+	} else if rt.command == reloadCommand && h.deps.Reloader().IsOwner(m.AuthorID()) {
+		_ = level.Info(logger).Log("message", "bot owner reloading the configuration")
+		resp, err = h.reload(msg)
+	} else {
+		capability := h.userCapability(rt, m.AuthorID(), p)
+		if capability < permissions.Member {
+			_ = level.Info(logger).Log("message", "ignoring command from banned user")
+			metrics.CommandHandled("unknown", metrics.ResultIgnored, time.Since(start))
+			return
+		}
+
+		if !h.channelAllowed(rt, m.ChannelID(), capability) {
+			_ = level.Info(logger).Log("message", "ignoring command in restricted channel")
+			metrics.CommandHandled("unknown", metrics.ResultIgnored, time.Since(start))
+			return
+		}
+
+		if rt.command == webCommand && h.deps.Dashboard() != nil {
+			resp, err = h.webLogin(msg, rt, capability)
+		} else {
+			resp, err = h.attemptConfigAndAdminHandlers(msg, rt, capability)
+
+			if err != nil && (err == errUnauthorized || err == parser.ErrUnknownCommand) {
+				_ = level.Debug(logger).Log("message", "admin not successful; processing as real message")
+				cmdContent := h.deps.CommandHandler().CommandIndicator() + rt.content
+				resp, err = h.deps.CommandHandler().HandleMessage(cmdhandler.NewWithContents(msg, cmdContent))
+			}
+		}
+	}
+
+	recordCommand(rt.command, err, time.Since(start))
+
+	// after the command, so users it has just created are included
+	if rt.gid != 0 {
+		if rerr := storage.RecordGuild(h.deps.UserAPI(), m.AuthorID(), rt.gid); rerr != nil {
+			_ = level.Error(logger).Log("message", "could not record the user's guild", "err", rerr)
+		}
 	}
 
 	if err == ErrNoResponse {
@@ -169,30 +448,105 @@ func (h *handlers) handleMessage(p *etfapi.Payload, req wsclient.WSMessage, resp
 		resp.IncludeError(err)
 	}
 
+	successColor, errorColor := h.colors()
 	if resp.HasErrors() {
-		resp.SetColor(h.errorColor)
+		resp.SetColor(errorColor)
 	} else {
-		resp.SetColor(h.successColor)
+		resp.SetColor(successColor)
 	}
 
-	err = h.deps.MessageRateLimiter().Wait(req.Ctx)
-	if err != nil {
-		_ = level.Error(logger).Log("message", "error waiting for ratelimiting", "err", err)
+	sendTo := resp.Channel()
+	if sendTo == 0 && !rt.isDM && h.wantsDMReplies(m.AuthorID()) {
+		err = h.deps.DMSender().SendDM(req.Ctx, m.AuthorID(), resp.ToMessage())
+		if err != nil {
+			_ = level.Error(logger).Log("message", "could not send reply by DM", "err", err)
+			return
+		}
+
+		_ = level.Info(logger).Log("message", "successfully sent reply by DM")
 		return
 	}
 
-	_ = level.Info(logger).Log("message", "sending message", "resp", fmt.Sprintf("%+v", resp))
+	if sendTo == 0 && !rt.isDM {
+		if rc := rt.settings.IDs(storage.SettingReplyChannel); len(rc) > 0 {
+			sendTo, err = snowflake.FromString(rc[0])
+			if err != nil {
+				_ = level.Error(logger).Log("message", "bad reply channel setting", "err", err)
+			}
+		}
+	}
 
-	sendTo := resp.Channel()
 	if sendTo == 0 {
 		sendTo = m.ChannelID()
 	}
 
+	waitStart := time.Now()
+	err = h.deps.MessageRateLimiter().Wait(req.Ctx)
+	metrics.RateLimitWait("reply", time.Since(waitStart))
+	if err != nil {
+		_ = level.Error(logger).Log("message", "error waiting for ratelimiting", "err", err)
+		return
+	}
+
+	_ = level.Info(logger).Log("message", "sending message", "resp", fmt.Sprintf("%+v", resp))
+
 	sendResp, body, err := h.bot.SendMessage(req.Ctx, sendTo, resp.ToMessage())
 	if err != nil {
-		_ = level.Error(logger).Log("message", "could not send message", "err", err, "resp_body", string(body), "status_code", sendResp.StatusCode)
+		status := 0
+		if sendResp != nil {
+			status = sendResp.StatusCode
+		}
+		metrics.SendFailed(status)
+
+		_ = level.Error(logger).Log("message", "could not send message", "err", err, "resp_body", string(body), "status_code", status)
 		return
 	}
 
 	_ = level.Info(logger).Log("message", "successfully sent message to channel", "channel_id", sendTo.ToString())
 }
+
+// handleMemberRemove forgets the guild of users who leave it, so their
+// guild-private characters stop showing there
+func (h *handlers) handleMemberRemove(p *etfapi.Payload, req wsclient.WSMessage, respChan chan<- wsclient.WSMessage) {
+	logger := logging.WithContext(req.Ctx, h.deps.Logger())
+
+	gidEl, ok := p.Data["guild_id"]
+	if !ok {
+		_ = level.Error(logger).Log("message", "member remove without a guild_id")
+		return
+	}
+
+	gid, err := etfapi.SnowflakeFromElement(gidEl)
+	if err != nil {
+		_ = level.Error(logger).Log("message", "could not read member remove guild_id", "err", err)
+		return
+	}
+
+	userEl, ok := p.Data["user"]
+	if !ok || userEl.IsNil() {
+		_ = level.Error(logger).Log("message", "member remove without a user")
+		return
+	}
+
+	user, err := userEl.ToMap()
+	if err != nil {
+		_ = level.Error(logger).Log("message", "could not read member remove user", "err", err)
+		return
+	}
+
+	uidEl, ok := user["id"]
+	if !ok {
+		_ = level.Error(logger).Log("message", "member remove user without an id")
+		return
+	}
+
+	uid, err := etfapi.SnowflakeFromElement(uidEl)
+	if err != nil {
+		_ = level.Error(logger).Log("message", "could not read member remove user id", "err", err)
+		return
+	}
+
+	if err = storage.ForgetGuild(h.deps.UserAPI(), uid, gid); err != nil {
+		_ = level.Error(logger).Log("message", "could not forget the user's guild", "guild_id", gid.ToString(), "user_id", uid.ToString(), "err", err)
+	}
+}
@@ -1 +1,318 @@
 package msghandler
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/go-kit/kit/log"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/members"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/permissions"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+const (
+	testGuild   snowflake.Snowflake = 100
+	testChannel snowflake.Snowflake = 200
+	testDM      snowflake.Snowflake = 300
+	testUser    snowflake.Snowflake = 400
+)
+
+type fakeSession struct {
+	channels map[snowflake.Snowflake]snowflake.Snowflake
+	admins   map[snowflake.Snowflake]bool
+}
+
+func (s *fakeSession) GuildOfChannel(cid snowflake.Snowflake) (snowflake.Snowflake, bool) {
+	gid, ok := s.channels[cid]
+	return gid, ok
+}
+
+func (s *fakeSession) IsGuildAdmin(gid, uid snowflake.Snowflake) bool {
+	return s.admins[uid]
+}
+
+// fakeMembers knows the roles of the members of testGuild, or fails every lookup when err is set
+type fakeMembers struct {
+	roles map[snowflake.Snowflake][]string
+	err   error
+}
+
+func (m *fakeMembers) Roles(ctx context.Context, gid, uid snowflake.Snowflake) ([]string, bool, error) {
+	if m.err != nil {
+		return nil, false, m.err
+	}
+	roles, ok := m.roles[uid]
+	return roles, ok && gid == testGuild, nil
+}
+
+type fakeDeps struct {
+	dependencies
+	guildAPI storage.GuildAPI
+	userAPI  storage.UserAPI
+	members  *fakeMembers
+}
+
+func (d *fakeDeps) Logger() log.Logger         { return log.NewNopLogger() }
+func (d *fakeDeps) GuildAPI() storage.GuildAPI { return d.guildAPI }
+func (d *fakeDeps) UserAPI() storage.UserAPI   { return d.userAPI }
+func (d *fakeDeps) Members() members.Lookup    { return d.members }
+
+func newTestHandlers(t *testing.T) (*handlers, *fakeDeps, func()) {
+	dir, err := ioutil.TempDir("", "msghandler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0660, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := &fakeDeps{members: &fakeMembers{roles: map[snowflake.Snowflake][]string{}}}
+	deps.guildAPI, err = storage.NewBoltGuildAPI(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.userAPI, err = storage.NewBoltUserAPI(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &handlers{
+		deps: deps,
+		session: &fakeSession{
+			channels: map[snowflake.Snowflake]snowflake.Snowflake{testChannel: testGuild},
+		},
+		defaultCommandIndicator: "!",
+	}
+
+	return h, deps, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func addUserToGuild(t *testing.T, deps *fakeDeps, uid, gid snowflake.Snowflake) {
+	tx, err := deps.userAPI.NewTransaction(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deferutil.CheckDefer(tx.Rollback)
+
+	bUser, err := tx.AddUser(uid.ToString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bUser.AddGuild(gid.ToString())
+
+	if err = tx.SaveUser(bUser); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouteMessage(t *testing.T) {
+	h, _, cleanup := newTestHandlers(t)
+	defer cleanup()
+
+	tests := []struct {
+		name        string
+		channel     snowflake.Snowflake
+		content     string
+		wantOK      bool
+		wantErr     error
+		wantDM      bool
+		wantGuild   snowflake.Snowflake
+		wantCommand string
+		wantContent string
+	}{
+		{"guild command", testChannel, "!need item Foo x2", true, nil, false, testGuild, "need", "need item Foo x2"},
+		{"guild chatter", testChannel, "need item Foo", false, nil, false, testGuild, "", ""},
+		{"dm without prefix", testDM, "list chars", true, nil, true, 0, "list", "list chars"},
+		{"dm with prefix", testDM, "!char create Bob", true, nil, true, 0, "char", "char create Bob"},
+		{"dm empty", testDM, "!", false, nil, true, 0, "", ""},
+		{"dm guild command", testDM, "bank list", true, errNeedsGuild, true, 0, "bank", "bank list"},
+		{"dm unknown guild", testDM, "bank list guild=100", true, errNotGuildMember, true, 0, "", "bank list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, ok, err := h.routeMessage(context.Background(), testUser, tt.channel, tt.content)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !ok {
+				return
+			}
+			if rt.isDM != tt.wantDM {
+				t.Errorf("isDM = %v, want %v", rt.isDM, tt.wantDM)
+			}
+			if rt.gid != tt.wantGuild {
+				t.Errorf("gid = %v, want %v", rt.gid, tt.wantGuild)
+			}
+			if rt.command != tt.wantCommand {
+				t.Errorf("command = %q, want %q", rt.command, tt.wantCommand)
+			}
+			if rt.content != tt.wantContent {
+				t.Errorf("content = %q, want %q", rt.content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestRouteMessageExplicitGuild(t *testing.T) {
+	h, deps, cleanup := newTestHandlers(t)
+	defer cleanup()
+
+	deps.members.roles[testUser] = nil
+
+	rt, ok, err := h.routeMessage(context.Background(), testUser, testDM, "bank list guild=100")
+	if !ok || err != nil {
+		t.Fatalf("routeMessage = %v, %v; want ok", ok, err)
+	}
+	if !rt.isDM || rt.gid != testGuild {
+		t.Errorf("route = dm %v guild %v; want dm in guild %v", rt.isDM, rt.gid, testGuild)
+	}
+	if rt.content != "bank list" {
+		t.Errorf("content = %q, want %q", rt.content, "bank list")
+	}
+}
+
+func setGuildSetting(t *testing.T, deps *fakeDeps, name, val string) {
+	tx, err := deps.guildAPI.NewTransaction(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deferutil.CheckDefer(tx.Rollback)
+
+	bGuild, err := tx.AddGuild(testGuild.ToString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := bGuild.GetSettings()
+	if err = s.Set(name, val); err != nil {
+		t.Fatal(err)
+	}
+	bGuild.SetSettings(s)
+	if err = tx.SaveGuild(bGuild); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDMGuildBannedRole(t *testing.T) {
+	h, deps, cleanup := newTestHandlers(t)
+	defer cleanup()
+
+	setGuildSetting(t, deps, storage.SettingBannedRoles, "999")
+	deps.members.roles[testUser] = []string{"999"}
+
+	rt, ok, err := h.routeMessage(context.Background(), testUser, testDM, "need item Foo guild=100")
+	if !ok || err != nil {
+		t.Fatalf("routeMessage = %v, %v; want ok", ok, err)
+	}
+	if c := h.userCapability(rt, testUser, nil); c != permissions.Banned {
+		t.Errorf("capability = %v, want %v", c, permissions.Banned)
+	}
+
+	// without the role the same DM is a member's
+	deps.members.roles[testUser] = []string{"1"}
+	rt, _, _ = h.routeMessage(context.Background(), testUser, testDM, "need item Foo guild=100")
+	if c := h.userCapability(rt, testUser, nil); c != permissions.Member {
+		t.Errorf("capability = %v, want %v", c, permissions.Member)
+	}
+
+	// roles that cannot be looked up are not assumed away
+	deps.members.err = errors.New("discord is down")
+	addUserToGuild(t, deps, testUser, testGuild)
+	if _, _, err = h.routeMessage(context.Background(), testUser, testDM, "need item Foo guild=100"); err != errRolesUnknown {
+		t.Errorf("err = %v, want %v", err, errRolesUnknown)
+	}
+}
+
+func TestDMGuildLookupFallback(t *testing.T) {
+	h, deps, cleanup := newTestHandlers(t)
+	defer cleanup()
+
+	// with no role settings, the stored guild list stands in for discord
+	deps.members.err = errors.New("discord is down")
+	if _, _, err := h.routeMessage(context.Background(), testUser, testDM, "bank list guild=100"); err != errNotGuildMember {
+		t.Errorf("err = %v, want %v", err, errNotGuildMember)
+	}
+
+	addUserToGuild(t, deps, testUser, testGuild)
+	rt, ok, err := h.routeMessage(context.Background(), testUser, testDM, "bank list guild=100")
+	if !ok || err != nil || rt.gid != testGuild {
+		t.Errorf("routeMessage = %v %v %v; want ok in guild %v", rt.gid, ok, err, testGuild)
+	}
+}
+
+func TestRouteMessageGuildIndicator(t *testing.T) {
+	h, deps, cleanup := newTestHandlers(t)
+	defer cleanup()
+
+	setGuildSetting(t, deps, storage.SettingControlSequence, "?")
+
+	if _, ok, _ := h.routeMessage(context.Background(), testUser, testChannel, "!list chars"); ok {
+		t.Error("default indicator accepted in a guild with a custom one")
+	}
+
+	rt, ok, err := h.routeMessage(context.Background(), testUser, testChannel, "?list chars")
+	if !ok || err != nil {
+		t.Fatalf("routeMessage = %v, %v; want ok", ok, err)
+	}
+	if rt.content != "list chars" {
+		t.Errorf("content = %q, want %q", rt.content, "list chars")
+	}
+
+	// DMs keep the bot-wide default
+	rt, ok, _ = h.routeMessage(context.Background(), testUser, testDM, "!list chars")
+	if !ok || rt.content != "list chars" {
+		t.Errorf("dm route = %v %q; want ok %q", ok, rt.content, "list chars")
+	}
+}
+
+func TestGuildArg(t *testing.T) {
+	for cmd := range guildCommands {
+		if _, _, ok := guildArg(cmd + " list"); ok {
+			t.Errorf("guildArg found a guild in %q", cmd+" list")
+		}
+	}
+
+	tests := []struct {
+		content string
+		want    string
+		wantGID snowflake.Snowflake
+	}{
+		{"loot points guild=123 extra", "loot points extra", 123},
+		{"loot points GUILD=123", "loot points", 123},
+		{"guild=123 loot points", "loot points", 123},
+		{"need item Bob guild=123\n```\nA x2\nB\n```", "need item Bob\n```\nA x2\nB\n```", 123},
+		{"need item Bob\nguild=123 A x2, B", "need item Bob\nA x2, B", 123},
+		{`char create "Bob  the  Brave" guild=123`, `char create "Bob  the  Brave"`, 123},
+		{"char create Guildford", "char create Guildford", 0},
+		{"bank list guild=abc", "bank list guild=abc", 0},
+	}
+
+	for _, tt := range tests {
+		content, gid, ok := guildArg(tt.content)
+		if ok != (tt.wantGID != 0) || gid != tt.wantGID || content != tt.want {
+			t.Errorf("guildArg(%q) = %q %v %v; want %q %v", tt.content, content, gid, ok, tt.want, tt.wantGID)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package msghandler
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/etfapi"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+var errNeedsGuild = errors.New("that command needs a guild; run it in a guild channel, or add `guild=[guild id]` when messaging the bot directly")
+var errNotGuildMember = errors.New("you are not a member of that guild")
+var errRolesUnknown = errors.New("could not check your roles in that guild; try again later, or run the command in a guild channel")
+
+// guildCommands are the commands that only make sense in the context of a guild
+var guildCommands = map[string]bool{
+	"config-hw": true,
+	"bank":      true,
+	"loot":      true,
+}
+
+// route describes where an incoming message should be dispatched
+type route struct {
+	gid      snowflake.Snowflake
+	settings storage.GuildSettings
+	isDM     bool
+	roles    []string // the user's roles in gid, for DMs naming a guild
+	command  string
+	content  string // the message contents without the command indicator
+}
+
+// guildArgPattern matches a guild=[id] argument as a word of its own
+var guildArgPattern = regexp.MustCompile(`(?i)(?:^|\s)(guild=(\S*))`)
+
+// guildArg finds and removes an explicit guild=[id] argument from a command.
+// Only the argument and the spaces on one side of it are cut, so line breaks
+// (batches) and spacing inside names are left as they were sent.
+func guildArg(content string) (string, snowflake.Snowflake, bool) {
+	loc := guildArgPattern.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return content, 0, false
+	}
+
+	gid, err := snowflake.FromString(content[loc[4]:loc[5]])
+	if err != nil || gid == 0 {
+		return content, 0, false
+	}
+
+	start, end := loc[2], loc[3]
+	if after := strings.TrimLeft(content[end:], " \t"); len(after) < len(content[end:]) {
+		end = len(content) - len(after)
+	} else {
+		start = len(strings.TrimRight(content[:start], " \t"))
+	}
+
+	return content[:start] + content[end:], gid, true
+}
+
+func (h *handlers) userInGuild(uid, gid snowflake.Snowflake) bool {
+	t, err := h.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return false
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(uid.ToString())
+	if err != nil {
+		return false
+	}
+
+	return bUser.InGuild(gid.ToString())
+}
+
+// hasRoleSettings reports whether any of the guild's access depends on roles
+func hasRoleSettings(s storage.GuildSettings) bool {
+	for _, name := range []string{storage.SettingBannedRoles, storage.SettingMemberRoles, storage.SettingOfficerRoles, storage.SettingConfigRoles} {
+		if len(s.IDs(name)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dmGuildMember checks that the user of a DM naming a guild is a member of it,
+// and finds their roles there. If discord cannot be asked, the bot's own record
+// of the guilds the user has used it in stands in, but only when the guild
+// grants nothing by role; otherwise the roles are needed and the command is refused.
+func (h *handlers) dmGuildMember(ctx context.Context, r *route, uid snowflake.Snowflake) error {
+	roles, member, err := h.deps.Members().Roles(ctx, r.gid, uid)
+	switch {
+	case err == nil && !member:
+		return errNotGuildMember
+	case err == nil:
+		r.roles = roles
+		return nil
+	}
+
+	_ = level.Warn(h.deps.Logger()).Log("message", "could not look up guild member", "guild_id", r.gid.ToString(), "user_id", uid.ToString(), "err", err)
+	if hasRoleSettings(r.settings) {
+		return errRolesUnknown
+	}
+	if !h.userInGuild(uid, r.gid) {
+		return errNotGuildMember
+	}
+	return nil
+}
+
+// routeMessage works out whether content is a command and which guild it applies to.
+// In guild channels commands need the guild's indicator; in DMs the indicator is
+// optional, and guild commands need an explicit guild=[id] argument.
+func (h *handlers) routeMessage(ctx context.Context, uid, cid snowflake.Snowflake, content string) (r route, ok bool, err error) {
+	r.gid = h.channelGuild(cid)
+
+	if r.gid != 0 {
+		r.settings = h.guildSettings(r.gid)
+		cmdIndicator := h.guildCommandIndicator(r.settings)
+		if !strings.HasPrefix(content, cmdIndicator) {
+			return r, false, nil
+		}
+		r.content = strings.TrimPrefix(content, cmdIndicator)
+	} else {
+		r.isDM = true
+		r.content = strings.TrimPrefix(content, h.defaultIndicator())
+
+		var gid snowflake.Snowflake
+		var found bool
+		r.content, gid, found = guildArg(r.content)
+		if found {
+			r.gid = gid
+			r.settings = h.guildSettings(gid)
+			if err = h.dmGuildMember(ctx, &r, uid); err != nil {
+				r.gid, r.settings = 0, storage.GuildSettings{}
+				return r, true, err
+			}
+		}
+	}
+
+	if fields := strings.Fields(r.content); len(fields) > 0 {
+		r.command = strings.ToLower(fields[0])
+	}
+
+	if r.command == "" {
+		return r, false, nil
+	}
+
+	if r.gid == 0 && guildCommands[r.command] {
+		return r, true, errNeedsGuild
+	}
+
+	return r, true, nil
+}
+
+// authorIsBot checks whether a MESSAGE_CREATE payload was sent by a bot account,
+// so the bot does not answer its own DM replies
+func authorIsBot(p *etfapi.Payload) bool {
+	authorEl, ok := p.Data["author"]
+	if !ok || authorEl.IsNil() {
+		return false
+	}
+
+	author, err := authorEl.ToMap()
+	if err != nil {
+		return false
+	}
+
+	botEl, ok := author["bot"]
+	if !ok || botEl.IsNil() {
+		return false
+	}
+
+	isBot, err := botEl.ToBool()
+	return err == nil && isBot
+}
@@ -0,0 +1,292 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/dm"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type dependencies interface {
+	Logger() log.Logger
+	UserAPI() storage.UserAPI
+	GuildAPI() storage.GuildAPI
+	DMSender() dm.Sender
+}
+
+// Notifier tells guild members when an item they want becomes available
+type Notifier interface {
+	HaveAdded(gid snowflake.Snowflake, holder, item string, count uint64)
+	BankItemAdded(gid snowflake.Snowflake, item string, count uint64)
+	Run(ctx context.Context) error
+	Flush(ctx context.Context) (sent, left int)
+
+	// SetOptions changes the color while running; the queue size and throttle
+	// are fixed when the Notifier is created
+	SetOptions(opts Options)
+}
+
+// Options is how to configure a Notifier
+type Options struct {
+	QueueSize    int
+	Throttle     time.Duration
+	SuccessColor int
+}
+
+type event struct {
+	gid    snowflake.Snowflake
+	holder string
+	item   string
+	count  uint64
+}
+
+type target struct {
+	user   string
+	mode   string
+	char   string
+	needCt uint64
+}
+
+type notifier struct {
+	deps         dependencies
+	events       chan event
+	throttle     time.Duration
+	successColor int64 // read and set atomically
+
+	// held while an event is being delivered, so Flush can wait for Run
+	delivering sync.Mutex
+}
+
+// NewNotifier creates a new Notifier; events are queued and delivered by Run
+func NewNotifier(deps dependencies, opts Options) Notifier {
+	n := notifier{
+		deps:         deps,
+		throttle:     opts.Throttle,
+		successColor: int64(opts.SuccessColor),
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	n.events = make(chan event, opts.QueueSize)
+
+	if n.throttle <= 0 {
+		n.throttle = 24 * time.Hour
+	}
+
+	return &n
+}
+
+func (n *notifier) SetOptions(opts Options) {
+	atomic.StoreInt64(&n.successColor, int64(opts.SuccessColor))
+}
+
+func (n *notifier) enqueue(e event) {
+	if e.gid == 0 {
+		return
+	}
+
+	select {
+	case n.events <- e:
+	default:
+		_ = level.Warn(n.deps.Logger()).Log("message", "notification queue full; dropping event", "item", e.item, "guild_id", e.gid.ToString())
+	}
+}
+
+// HaveAdded queues notifications for a member registering a surplus item
+func (n *notifier) HaveAdded(gid snowflake.Snowflake, holder, item string, count uint64) {
+	n.enqueue(event{gid: gid, holder: holder, item: item, count: count})
+}
+
+// BankItemAdded queues notifications for an item being added to the guild bank
+func (n *notifier) BankItemAdded(gid snowflake.Snowflake, item string, count uint64) {
+	n.enqueue(event{gid: gid, item: item, count: count})
+}
+
+// Run delivers queued notifications until the context is cancelled
+func (n *notifier) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-n.events:
+			n.deliver(ctx, e)
+		}
+	}
+}
+
+// Flush delivers the queued notifications, after waiting for any that Run is
+// delivering, until the queue is empty or the context is done. It returns how
+// many were delivered and how many were left in the queue.
+func (n *notifier) Flush(ctx context.Context) (sent, left int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return sent, len(n.events)
+		case e := <-n.events:
+			if n.deliver(ctx, e) {
+				sent++
+			}
+		default:
+			// wait for anything Run is in the middle of delivering
+			n.delivering.Lock()
+			n.delivering.Unlock() // nolint: staticcheck
+			return sent, 0
+		}
+	}
+}
+
+func (n *notifier) deliver(ctx context.Context, e event) bool {
+	n.delivering.Lock()
+	defer n.delivering.Unlock()
+
+	if err := n.process(ctx, e); err != nil {
+		_ = level.Error(n.deps.Logger()).Log("message", "could not process notification", "item", e.item, "err", err)
+		return false
+	}
+	return true
+}
+
+// findTargets marks every opted-in guild member wanting the item as notified
+// (unless they were notified about it within the throttle window) and returns them
+func (n *notifier) findTargets(e event, now time.Time) ([]target, error) {
+	t, err := n.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	gid := e.gid.ToString()
+	var targets []target
+	for _, bUser := range t.GetUsers() {
+		if bUser.GetName() == e.holder || !bUser.InGuild(gid) || bUser.GetNotifyMode() == storage.NotifyOff {
+			continue
+		}
+
+		if now.Sub(bUser.GetLastNotified(e.item)) < n.throttle {
+			continue
+		}
+
+		for _, char := range bUser.GetCharacters() {
+			item, ok := wantedItem(char, e.item)
+			if !ok {
+				continue
+			}
+
+			targets = append(targets, target{
+				user:   bUser.GetName(),
+				mode:   bUser.GetNotifyMode(),
+				char:   char.GetName(),
+				needCt: item.Count(),
+			})
+
+			bUser.SetLastNotified(e.item, now)
+			if err = t.SaveUser(bUser); err != nil {
+				return nil, errors.Wrap(err, "could not save notification state")
+			}
+			break
+		}
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not save notification state")
+	}
+
+	return targets, nil
+}
+
+func wantedItem(char storage.Character, name string) (storage.Item, bool) {
+	for _, item := range char.GetNeededItems() {
+		if strings.EqualFold(item.Name(), name) {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+func (n *notifier) process(ctx context.Context, e event) error {
+	logger := log.With(n.deps.Logger(), "guild_id", e.gid.ToString(), "item", e.item)
+
+	s, err := storage.GetSettings(n.deps.GuildAPI(), e.gid)
+	if err != nil {
+		return err
+	}
+
+	var channel snowflake.Snowflake
+	if s.NotificationChannel() != "" {
+		channel, err = snowflake.FromString(s.NotificationChannel())
+		if err != nil {
+			_ = level.Error(logger).Log("message", "bad notification channel setting", "err", err)
+		}
+	}
+
+	targets, err := n.findTargets(e, time.Now())
+	if err != nil {
+		return err
+	}
+
+	source := "the guild bank"
+	if e.holder != "" {
+		source = fmt.Sprintf("<@%s>", e.holder)
+	}
+
+	for _, tgt := range targets {
+		uid, err := snowflake.FromString(tgt.user)
+		if err != nil {
+			_ = level.Error(logger).Log("message", "bad user id", "user_id", tgt.user, "err", err)
+			continue
+		}
+
+		r := &cmdhandler.SimpleEmbedResponse{
+			Description: fmt.Sprintf("%s has %s x%d available; %s needs %d.", source, e.item, e.count, tgt.char, tgt.needCt),
+		}
+		r.SetColor(int(atomic.LoadInt64(&n.successColor)))
+
+		if tgt.mode == storage.NotifyChannel && channel != 0 {
+			r.To = cmdhandler.UserMentionString(uid)
+			err = n.deps.DMSender().SendMessage(ctx, channel, r.ToMessage())
+		} else {
+			err = n.deps.DMSender().SendDM(ctx, uid, r.ToMessage())
+		}
+
+		if err != nil {
+			_ = level.Error(logger).Log("message", "could not send notification", "user_id", tgt.user, "err", err)
+			continue
+		}
+
+		_ = level.Info(logger).Log("message", "sent notification", "user_id", tgt.user)
+	}
+
+	return nil
+}
+
+type nopNotifier struct{}
+
+// NewNopNotifier creates a Notifier that discards every event
+func NewNopNotifier() Notifier {
+	return nopNotifier{}
+}
+
+func (nopNotifier) HaveAdded(snowflake.Snowflake, string, string, uint64) {}
+func (nopNotifier) BankItemAdded(snowflake.Snowflake, string, uint64)     {}
+
+func (nopNotifier) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (nopNotifier) Flush(ctx context.Context) (int, int) { return 0, 0 }
+func (nopNotifier) SetOptions(Options)                   {}
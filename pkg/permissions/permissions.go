@@ -0,0 +1,106 @@
+package permissions
+
+import (
+	"strings"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// Capability is a level of access to bot commands; each level includes the ones below it
+type Capability int
+
+// Valid Capability values
+const (
+	Banned Capability = iota
+	Member
+	Officer
+	Config
+)
+
+func (c Capability) String() string {
+	switch c {
+	case Banned:
+		return "banned"
+	case Member:
+		return "member"
+	case Officer:
+		return "officer"
+	case Config:
+		return "config"
+	default:
+		return "unknown"
+	}
+}
+
+// commandCapabilities lists the commands that need more than Member access to
+// run their privileged subcommands; a user without the capability gets the
+// member-facing version of the command instead
+var commandCapabilities = map[string]Capability{
+	"config-hw": Config,
+	"bank":      Officer,
+	"loot":      Officer,
+}
+
+// Required returns the capability needed to run the privileged version of a command
+func Required(command string) Capability {
+	if c, ok := commandCapabilities[strings.ToLower(command)]; ok {
+		return c
+	}
+	return Member
+}
+
+// Resolve works out the capability of a guild member from their roles.
+// Discord admins always have Config; otherwise banned roles win over everything,
+// and if member roles are configured a user must hold one of them to use the bot.
+func Resolve(s storage.GuildSettings, isAdmin bool, roles []string) Capability {
+	if isAdmin {
+		return Config
+	}
+
+	has := map[string]bool{}
+	for _, r := range roles {
+		has[r] = true
+	}
+	hasAny := func(setting string) bool {
+		for _, r := range s.IDs(setting) {
+			if has[r] {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case hasAny(storage.SettingBannedRoles):
+		return Banned
+	case hasAny(storage.SettingConfigRoles):
+		return Config
+	case hasAny(storage.SettingOfficerRoles):
+		return Officer
+	case len(s.IDs(storage.SettingMemberRoles)) == 0, hasAny(storage.SettingMemberRoles):
+		return Member
+	default:
+		return Banned
+	}
+}
+
+// ChannelAllowed checks the guild's channel restrictions on commands. Ignored
+// channels are always refused; if command channels are configured only those
+// are allowed, except to users with Config so they can still fix the settings.
+func ChannelAllowed(s storage.GuildSettings, cid string, c Capability) bool {
+	contains := func(ids []string) bool {
+		for _, id := range ids {
+			if id == cid {
+				return true
+			}
+		}
+		return false
+	}
+
+	if contains(s.IDs(storage.SettingIgnoredChannels)) {
+		return false
+	}
+
+	allowed := s.IDs(storage.SettingCommandChannels)
+	return len(allowed) == 0 || c >= Config || contains(allowed)
+}
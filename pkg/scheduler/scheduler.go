@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/cmdhandler"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/commands"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/dm"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/inflight"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+type dependencies interface {
+	Logger() log.Logger
+	ScheduleAPI() storage.ScheduleAPI
+	DMSender() dm.Sender
+	InFlight() inflight.Tracker
+}
+
+// Scheduler sends digests and reminders when they come due
+type Scheduler interface {
+	Run(ctx context.Context) error
+
+	// SetOptions changes the color while running; the tick interval is fixed
+	// when the Scheduler is created
+	SetOptions(opts Options)
+}
+
+// Options is how to configure a Scheduler
+type Options struct {
+	TickInterval time.Duration
+	SuccessColor int
+}
+
+type scheduler struct {
+	deps         dependencies
+	tickInterval time.Duration
+	successColor int64 // read and set atomically
+}
+
+type delivery struct {
+	user string
+	resp cmdhandler.Response
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(deps dependencies, opts Options) Scheduler {
+	s := scheduler{
+		deps:         deps,
+		tickInterval: opts.TickInterval,
+		successColor: int64(opts.SuccessColor),
+	}
+
+	if s.tickInterval <= 0 {
+		s.tickInterval = time.Minute
+	}
+
+	return &s
+}
+
+func (s *scheduler) SetOptions(opts Options) {
+	atomic.StoreInt64(&s.successColor, int64(opts.SuccessColor))
+}
+
+// Run checks for due schedules every tick until the context is cancelled. A
+// tick counts as in-flight work, so shutdown waits for its deliveries; once
+// draining has begun the due schedules are left for the next start.
+func (s *scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if !s.deps.InFlight().Start() {
+				continue
+			}
+
+			if err := s.tick(ctx, now); err != nil {
+				_ = level.Error(s.deps.Logger()).Log("message", "error running schedules", "err", err)
+			}
+			s.deps.InFlight().Done()
+		}
+	}
+}
+
+// collectDue advances every due schedule and builds the messages to send; the
+// schedule changes are committed before anything is sent, so a crash
+// mid-delivery skips rather than repeats a message
+func (s *scheduler) collectDue(now time.Time) ([]delivery, error) {
+	t, err := s.deps.ScheduleAPI().NewTransaction(true)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	var deliveries []delivery
+	for _, schedule := range t.GetSchedules() {
+		reminders := schedule.PopDueReminders(now)
+
+		digestDue := schedule.GetDigestInterval() > 0 && !schedule.GetNextDigest().After(now)
+		if digestDue {
+			schedule.SetDigest(schedule.GetDigestInterval(), now.Add(schedule.GetDigestInterval()))
+		}
+
+		if len(reminders) == 0 && !digestDue {
+			continue
+		}
+
+		err = t.SaveSchedule(schedule)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not save schedule")
+		}
+
+		user, err := t.UserTx().AddUser(schedule.GetUser()) // add or get empty (don't save)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find user")
+		}
+
+		for _, r := range reminders {
+			deliveries = append(deliveries, delivery{user: schedule.GetUser(), resp: commands.ReminderResponse(user, r)})
+		}
+
+		if digestDue {
+			for _, resp := range commands.DigestResponses(user) {
+				deliveries = append(deliveries, delivery{user: schedule.GetUser(), resp: resp})
+			}
+		}
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not save schedules")
+	}
+
+	return deliveries, nil
+}
+
+func (s *scheduler) tick(ctx context.Context, now time.Time) error {
+	deliveries, err := s.collectDue(now)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		uid, err := snowflake.FromString(d.user)
+		if err != nil {
+			_ = level.Error(s.deps.Logger()).Log("message", "bad user id in schedule", "user_id", d.user, "err", err)
+			continue
+		}
+
+		d.resp.SetColor(int(atomic.LoadInt64(&s.successColor)))
+		err = s.deps.DMSender().SendDM(ctx, uid, d.resp.ToMessage())
+		if err != nil {
+			_ = level.Error(s.deps.Logger()).Log("message", "could not send scheduled message", "user_id", d.user, "err", err)
+			continue
+		}
+
+		_ = level.Info(s.deps.Logger()).Log("message", "sent scheduled message", "user_id", d.user)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+//go:generate protoc --go_out=. --proto_path=. ./bankapi.proto
+
+import (
+	"time"
+)
+
+// LedgerKind is the type of a bank ledger entry
+type LedgerKind string
+
+// Valid LedgerKind values
+const (
+	LedgerDonation   LedgerKind = "donation"
+	LedgerWithdrawal LedgerKind = "withdrawal"
+)
+
+// LedgerEntry is a record of an item entering or leaving a guild bank
+type LedgerEntry struct {
+	Time      time.Time
+	Kind      LedgerKind
+	Actor     string
+	User      string
+	Character string
+	Item      string
+	Count     uint64
+}
+
+// BankAPI is the api for managing guild bank transactions
+type BankAPI interface {
+	NewTransaction(writable bool) (BankAPITx, error)
+}
+
+// BankAPITx is the api for managing guild banks within a transaction
+type BankAPITx interface {
+	Commit() error
+	Rollback() error
+
+	GetBank(guild string) (Bank, error)
+	AddBank(guild string) (Bank, error)
+	SaveBank(bank Bank) error
+
+	AddLedgerEntry(guild string, entry LedgerEntry) error
+	GetLedger(guild string, limit int) ([]LedgerEntry, error)
+
+	// UserTx returns a UserAPITx that shares this transaction, so that user
+	// and bank changes are committed (or rolled back) together
+	UserTx() UserAPITx
+}
+
+// Bank is the api for managing a particular guild's bank inventory
+type Bank interface {
+	GetGuild() string
+	GetItem(name string) (Item, error)
+	GetItems() []Item
+
+	IncrItem(name string, amt uint64)
+	DecrItem(name string, amt uint64) error
+
+	Serialize() ([]byte, error)
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ErrInsufficientBankItems is the error returned if the bank does not hold enough of an item
+var ErrInsufficientBankItems = errors.New("not enough of that item in the bank")
+
+type boltBank struct {
+	protoBank *ProtoBank
+}
+
+func (b *boltBank) GetGuild() string {
+	return b.protoBank.Guild
+}
+
+func (b *boltBank) GetItem(name string) (Item, error) {
+	if b.protoBank.Items == nil {
+		return nil, ErrItemNotExist
+	}
+
+	protoItem, ok := b.protoBank.Items[name]
+	if !ok {
+		return nil, ErrItemNotExist
+	}
+
+	return boltItem{protoItem}, nil
+}
+
+func (b *boltBank) GetItems() []Item {
+	if b.protoBank.Items == nil {
+		return []Item{}
+	}
+
+	items := make([]Item, len(b.protoBank.Items))
+	i := 0
+	for _, protoItem := range b.protoBank.Items {
+		items[i] = boltItem{protoItem}
+		i++
+	}
+
+	return items
+}
+
+func (b *boltBank) IncrItem(name string, amt uint64) {
+	if b.protoBank.Items == nil {
+		b.protoBank.Items = map[string]*ProtoItem{}
+	}
+
+	s, ok := b.protoBank.Items[name]
+	if !ok {
+		b.protoBank.Items[name] = &ProtoItem{Description: name, Count: amt}
+	} else {
+		s.Count += amt
+	}
+}
+
+func (b *boltBank) DecrItem(name string, amt uint64) error {
+	if b.protoBank.Items == nil {
+		return ErrItemNotExist
+	}
+
+	s, ok := b.protoBank.Items[name]
+	if !ok {
+		return ErrItemNotExist
+	}
+
+	if s.Count < amt {
+		return ErrInsufficientBankItems
+	}
+
+	s.Count -= amt
+	if s.Count == 0 {
+		delete(b.protoBank.Items, name)
+	}
+
+	return nil
+}
+
+func (b *boltBank) Serialize() (out []byte, err error) {
+	out, err = proto.Marshal(b.protoBank)
+	return
+}
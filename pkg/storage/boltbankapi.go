@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrBankNotExist is the error returned if a guild bank does not exist
+var ErrBankNotExist = errors.New("bank does not exist")
+
+type boltBankAPI struct {
+	db               *bolt.DB
+	bucketName       []byte
+	ledgerBucketName []byte
+}
+
+// NewBoltBankAPI constructs a boltDB-backed BankAPI
+func NewBoltBankAPI(db *bolt.DB) (BankAPI, error) {
+	b := boltBankAPI{
+		db:               db,
+		bucketName:       []byte("BankRecords"),
+		ledgerBucketName: []byte("BankLedgers"),
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{b.bucketName, b.ledgerBucketName, userBucketName} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return errors.Wrap(err, "could not create bucket")
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *boltBankAPI) NewTransaction(writable bool) (BankAPITx, error) {
+	tx, timer, err := beginTx(b.db, "bank", writable)
+	if err != nil {
+		return nil, err
+	}
+	return &boltBankAPITx{
+		bucketName:       b.bucketName,
+		ledgerBucketName: b.ledgerBucketName,
+		tx:               tx,
+		timer:            timer,
+	}, nil
+}
+
+type boltBankAPITx struct {
+	bucketName       []byte
+	ledgerBucketName []byte
+	tx               *bolt.Tx
+	timer            *txTimer
+}
+
+func (b *boltBankAPITx) Commit() error {
+	return commitTx(b.tx, b.timer)
+}
+
+func (b *boltBankAPITx) Rollback() error {
+	return rollbackTx(b.tx, b.timer)
+}
+
+func (b *boltBankAPITx) UserTx() UserAPITx {
+	return &boltUserAPITx{
+		bucketName: userBucketName,
+		tx:         b.tx,
+	}
+}
+
+func (b *boltBankAPITx) AddBank(guild string) (Bank, error) {
+	bank, err := b.GetBank(guild)
+	if err == ErrBankNotExist {
+		bank = &boltBank{
+			protoBank: &ProtoBank{Guild: guild},
+		}
+		err = nil
+	}
+	return bank, err
+}
+
+func (b *boltBankAPITx) SaveBank(bank Bank) error {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	serial, err := bank.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(bank.GetGuild()), serial)
+}
+
+func (b *boltBankAPITx) GetBank(guild string) (Bank, error) {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	val := bucket.Get([]byte(guild))
+
+	if val == nil {
+		return nil, ErrBankNotExist
+	}
+
+	protoBank := ProtoBank{}
+	err := proto.Unmarshal(val, &protoBank)
+	if err != nil {
+		return nil, errors.Wrap(err, "bank record is corrupt")
+	}
+
+	return &boltBank{&protoBank}, nil
+}
+
+func (b *boltBankAPITx) AddLedgerEntry(guild string, entry LedgerEntry) error {
+	serial, err := proto.Marshal(&ProtoLedgerEntry{
+		Timestamp: entry.Time.Unix(),
+		Kind:      string(entry.Kind),
+		Actor:     entry.Actor,
+		User:      entry.User,
+		Character: entry.Character,
+		Item:      entry.Item,
+		Count:     entry.Count,
+	})
+	if err != nil {
+		return err
+	}
+
+	return putSequenced(b.tx.Bucket(b.ledgerBucketName), guild, serial)
+}
+
+// GetLedger returns up to limit of the most recent ledger entries, newest first
+func (b *boltBankAPITx) GetLedger(guild string, limit int) ([]LedgerEntry, error) {
+	entries := make([]LedgerEntry, 0, limit)
+	err := lastSequenced(b.tx.Bucket(b.ledgerBucketName), guild, limit, func(v []byte) error {
+		protoEntry := ProtoLedgerEntry{}
+		err := proto.Unmarshal(v, &protoEntry)
+		if err != nil {
+			return errors.Wrap(err, "ledger record is corrupt")
+		}
+
+		entries = append(entries, LedgerEntry{
+			Time:      time.Unix(protoEntry.Timestamp, 0),
+			Kind:      LedgerKind(protoEntry.Kind),
+			Actor:     protoEntry.Actor,
+			User:      protoEntry.User,
+			Character: protoEntry.Character,
+			Item:      protoEntry.Item,
+			Count:     protoEntry.Count,
+		})
+		return nil
+	})
+
+	return entries, err
+}
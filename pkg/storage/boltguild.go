@@ -21,11 +21,17 @@ func (g *boltGuild) Serialize() (out []byte, err error) {
 	return
 }
 
-func (g *boltGuild) GetSettings() (s GuildSettings) {
-	s.ControlSequence = g.protoGuild.CommandIndicator
-	return
+func (g *boltGuild) GetSettings() GuildSettings {
+	vals := map[string]string{
+		SettingControlSequence: g.protoGuild.CommandIndicator,
+	}
+	for k, v := range g.protoGuild.Settings {
+		vals[k] = v
+	}
+	return NewGuildSettings(vals)
 }
 
 func (g *boltGuild) SetSettings(s GuildSettings) {
-	g.protoGuild.CommandIndicator = s.ControlSequence
+	g.protoGuild.CommandIndicator = ""
+	g.protoGuild.Settings = s.Values()
 }
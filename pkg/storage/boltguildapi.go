@@ -37,31 +37,29 @@ func NewBoltGuildAPI(db *bolt.DB) (GuildAPI, error) {
 }
 
 func (b *boltGuildAPI) NewTransaction(writable bool) (GuildAPITx, error) {
-	tx, err := b.db.Begin(writable)
+	tx, timer, err := beginTx(b.db, "guild", writable)
 	if err != nil {
 		return nil, err
 	}
 	return &boltGuildAPITx{
 		bucketName: b.bucketName,
 		tx:         tx,
+		timer:      timer,
 	}, nil
 }
 
 type boltGuildAPITx struct {
 	bucketName []byte
 	tx         *bolt.Tx
+	timer      *txTimer
 }
 
 func (b *boltGuildAPITx) Commit() error {
-	return b.tx.Commit()
+	return commitTx(b.tx, b.timer)
 }
 
 func (b *boltGuildAPITx) Rollback() error {
-	err := b.tx.Rollback()
-	if err != nil && err != bolt.ErrTxClosed {
-		return err
-	}
-	return nil
+	return rollbackTx(b.tx, b.timer)
 }
 
 func (b *boltGuildAPITx) AddGuild(name string) (Guild, error) {
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrLootTableNotExist is the error returned if a guild loot table does not exist
+var ErrLootTableNotExist = errors.New("loot table does not exist")
+
+type boltLootAPI struct {
+	db               *bolt.DB
+	bucketName       []byte
+	awardsBucketName []byte
+}
+
+// NewBoltLootAPI constructs a boltDB-backed LootAPI
+func NewBoltLootAPI(db *bolt.DB) (LootAPI, error) {
+	b := boltLootAPI{
+		db:               db,
+		bucketName:       []byte("LootRecords"),
+		awardsBucketName: []byte("LootAwards"),
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{b.bucketName, b.awardsBucketName, userBucketName} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return errors.Wrap(err, "could not create bucket")
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *boltLootAPI) NewTransaction(writable bool) (LootAPITx, error) {
+	tx, timer, err := beginTx(b.db, "loot", writable)
+	if err != nil {
+		return nil, err
+	}
+	return &boltLootAPITx{
+		bucketName:       b.bucketName,
+		awardsBucketName: b.awardsBucketName,
+		tx:               tx,
+		timer:            timer,
+	}, nil
+}
+
+type boltLootAPITx struct {
+	bucketName       []byte
+	awardsBucketName []byte
+	tx               *bolt.Tx
+	timer            *txTimer
+}
+
+func (b *boltLootAPITx) Commit() error {
+	return commitTx(b.tx, b.timer)
+}
+
+func (b *boltLootAPITx) Rollback() error {
+	return rollbackTx(b.tx, b.timer)
+}
+
+func (b *boltLootAPITx) UserTx() UserAPITx {
+	return &boltUserAPITx{
+		bucketName: userBucketName,
+		tx:         b.tx,
+	}
+}
+
+func (b *boltLootAPITx) AddLootTable(guild string) (LootTable, error) {
+	table, err := b.GetLootTable(guild)
+	if err == ErrLootTableNotExist {
+		table = &boltLootTable{
+			protoTable: &ProtoLootTable{Guild: guild},
+		}
+		err = nil
+	}
+	return table, err
+}
+
+func (b *boltLootAPITx) SaveLootTable(table LootTable) error {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	serial, err := table.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(table.GetGuild()), serial)
+}
+
+func (b *boltLootAPITx) GetLootTable(guild string) (LootTable, error) {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	val := bucket.Get([]byte(guild))
+
+	if val == nil {
+		return nil, ErrLootTableNotExist
+	}
+
+	protoTable := ProtoLootTable{}
+	err := proto.Unmarshal(val, &protoTable)
+	if err != nil {
+		return nil, errors.Wrap(err, "loot table record is corrupt")
+	}
+
+	return &boltLootTable{&protoTable}, nil
+}
+
+func (b *boltLootAPITx) AddAward(guild string, award LootAward) error {
+	serial, err := proto.Marshal(&ProtoLootAward{
+		Timestamp: award.Time.Unix(),
+		Awarder:   award.Awarder,
+		User:      award.User,
+		Character: award.Character,
+		Item:      award.Item,
+		Cost:      award.Cost,
+	})
+	if err != nil {
+		return err
+	}
+
+	return putSequenced(b.tx.Bucket(b.awardsBucketName), guild, serial)
+}
+
+// GetAwards returns up to limit of the most recent loot awards, newest first
+func (b *boltLootAPITx) GetAwards(guild string, limit int) ([]LootAward, error) {
+	awards := make([]LootAward, 0, limit)
+	err := lastSequenced(b.tx.Bucket(b.awardsBucketName), guild, limit, func(v []byte) error {
+		protoAward := ProtoLootAward{}
+		err := proto.Unmarshal(v, &protoAward)
+		if err != nil {
+			return errors.Wrap(err, "loot award record is corrupt")
+		}
+
+		awards = append(awards, LootAward{
+			Time:      time.Unix(protoAward.Timestamp, 0),
+			Awarder:   protoAward.Awarder,
+			User:      protoAward.User,
+			Character: protoAward.Character,
+			Item:      protoAward.Item,
+			Cost:      protoAward.Cost,
+		})
+		return nil
+	})
+
+	return awards, err
+}
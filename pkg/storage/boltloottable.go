@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type boltLootTable struct {
+	protoTable *ProtoLootTable
+}
+
+func (l *boltLootTable) GetGuild() string {
+	return l.protoTable.Guild
+}
+
+func (l *boltLootTable) GetMembers() []string {
+	members := make([]string, 0, len(l.protoTable.Members))
+	for user := range l.protoTable.Members {
+		members = append(members, user)
+	}
+	return members
+}
+
+func (l *boltLootTable) GetPoints(user string) int64 {
+	m, ok := l.protoTable.Members[user]
+	if !ok {
+		return 0
+	}
+	return m.Points
+}
+
+func (l *boltLootTable) GetLastAward(user string) time.Time {
+	m, ok := l.protoTable.Members[user]
+	if !ok || m.LastAward == 0 {
+		return time.Time{}
+	}
+	return time.Unix(m.LastAward, 0)
+}
+
+func (l *boltLootTable) member(user string) *ProtoLootMember {
+	if l.protoTable.Members == nil {
+		l.protoTable.Members = map[string]*ProtoLootMember{}
+	}
+
+	m, ok := l.protoTable.Members[user]
+	if !ok {
+		m = &ProtoLootMember{User: user}
+		l.protoTable.Members[user] = m
+	}
+	return m
+}
+
+func (l *boltLootTable) AddPoints(user string, amt int64) {
+	l.member(user).Points += amt
+}
+
+func (l *boltLootTable) RecordAward(user string, at time.Time, cost int64) {
+	m := l.member(user)
+	m.Points -= cost
+	m.LastAward = at.Unix()
+}
+
+func (l *boltLootTable) Serialize() (out []byte, err error) {
+	out, err = proto.Marshal(l.protoTable)
+	return
+}
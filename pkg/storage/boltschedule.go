@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type boltSchedule struct {
+	protoSchedule *ProtoSchedule
+}
+
+func (s *boltSchedule) GetUser() string {
+	return s.protoSchedule.User
+}
+
+func (s *boltSchedule) GetDigestInterval() time.Duration {
+	return time.Duration(s.protoSchedule.DigestInterval) * time.Second
+}
+
+func (s *boltSchedule) GetNextDigest() time.Time {
+	if s.protoSchedule.NextDigest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.protoSchedule.NextDigest, 0)
+}
+
+func (s *boltSchedule) GetReminders() []Reminder {
+	reminders := make([]Reminder, len(s.protoSchedule.Reminders))
+	for i, r := range s.protoSchedule.Reminders {
+		reminders[i] = reminderFromProto(r)
+	}
+	return reminders
+}
+
+func (s *boltSchedule) SetDigest(interval time.Duration, next time.Time) {
+	s.protoSchedule.DigestInterval = int64(interval / time.Second)
+	if interval == 0 {
+		s.protoSchedule.NextDigest = 0
+		return
+	}
+	s.protoSchedule.NextDigest = next.Unix()
+}
+
+func (s *boltSchedule) AddReminder(character, item string, due time.Time) Reminder {
+	s.protoSchedule.NextReminderId++
+	r := &ProtoReminder{
+		Id:        s.protoSchedule.NextReminderId,
+		Character: character,
+		Item:      item,
+		Due:       due.Unix(),
+	}
+	s.protoSchedule.Reminders = append(s.protoSchedule.Reminders, r)
+	return reminderFromProto(r)
+}
+
+func (s *boltSchedule) PopDueReminders(now time.Time) []Reminder {
+	var due []Reminder
+	remaining := s.protoSchedule.Reminders[:0]
+	for _, r := range s.protoSchedule.Reminders {
+		if r.Due <= now.Unix() {
+			due = append(due, reminderFromProto(r))
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	s.protoSchedule.Reminders = remaining
+	return due
+}
+
+func (s *boltSchedule) Serialize() (out []byte, err error) {
+	out, err = proto.Marshal(s.protoSchedule)
+	return
+}
+
+func reminderFromProto(r *ProtoReminder) Reminder {
+	return Reminder{
+		ID:        r.Id,
+		Character: r.Character,
+		Item:      r.Item,
+		Due:       time.Unix(r.Due, 0),
+	}
+}
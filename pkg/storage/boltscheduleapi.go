@@ -0,0 +1,130 @@
+package storage
+
+import (
+	bolt "github.com/coreos/bbolt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrScheduleNotExist is the error returned if a user schedule does not exist
+var ErrScheduleNotExist = errors.New("schedule does not exist")
+
+type boltScheduleAPI struct {
+	db         *bolt.DB
+	bucketName []byte
+}
+
+// NewBoltScheduleAPI constructs a boltDB-backed ScheduleAPI
+func NewBoltScheduleAPI(db *bolt.DB) (ScheduleAPI, error) {
+	b := boltScheduleAPI{
+		db:         db,
+		bucketName: []byte("ScheduleRecords"),
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{b.bucketName, userBucketName} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return errors.Wrap(err, "could not create bucket")
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *boltScheduleAPI) NewTransaction(writable bool) (ScheduleAPITx, error) {
+	tx, timer, err := beginTx(b.db, "schedule", writable)
+	if err != nil {
+		return nil, err
+	}
+	return &boltScheduleAPITx{
+		bucketName: b.bucketName,
+		tx:         tx,
+		timer:      timer,
+	}, nil
+}
+
+type boltScheduleAPITx struct {
+	bucketName []byte
+	tx         *bolt.Tx
+	timer      *txTimer
+}
+
+func (b *boltScheduleAPITx) Commit() error {
+	return commitTx(b.tx, b.timer)
+}
+
+func (b *boltScheduleAPITx) Rollback() error {
+	return rollbackTx(b.tx, b.timer)
+}
+
+func (b *boltScheduleAPITx) UserTx() UserAPITx {
+	return &boltUserAPITx{
+		bucketName: userBucketName,
+		tx:         b.tx,
+	}
+}
+
+func (b *boltScheduleAPITx) AddSchedule(user string) (Schedule, error) {
+	schedule, err := b.GetSchedule(user)
+	if err == ErrScheduleNotExist {
+		schedule = &boltSchedule{
+			protoSchedule: &ProtoSchedule{User: user},
+		}
+		err = nil
+	}
+	return schedule, err
+}
+
+func (b *boltScheduleAPITx) SaveSchedule(schedule Schedule) error {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	serial, err := schedule.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(schedule.GetUser()), serial)
+}
+
+func (b *boltScheduleAPITx) GetSchedule(user string) (Schedule, error) {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	val := bucket.Get([]byte(user))
+
+	if val == nil {
+		return nil, ErrScheduleNotExist
+	}
+
+	protoSchedule := ProtoSchedule{}
+	err := proto.Unmarshal(val, &protoSchedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "schedule record is corrupt")
+	}
+
+	return &boltSchedule{&protoSchedule}, nil
+}
+
+// GetSchedules returns every stored schedule; corrupt records are skipped
+func (b *boltScheduleAPITx) GetSchedules() []Schedule {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	schedules := []Schedule{}
+	_ = bucket.ForEach(func(k, v []byte) error {
+		protoSchedule := ProtoSchedule{}
+		if err := proto.Unmarshal(v, &protoSchedule); err != nil {
+			return nil
+		}
+
+		schedules = append(schedules, &boltSchedule{&protoSchedule})
+		return nil
+	})
+
+	return schedules
+}
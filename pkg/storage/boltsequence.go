@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"encoding/binary"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/pkg/errors"
+)
+
+// putSequenced appends val to the per-guild sub-bucket of parent, keyed by
+// an increasing sequence number
+func putSequenced(parent *bolt.Bucket, guild string, val []byte) error {
+	bucket, err := parent.CreateBucketIfNotExists([]byte(guild))
+	if err != nil {
+		return errors.Wrap(err, "could not create sequence bucket")
+	}
+
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return errors.Wrap(err, "could not get next sequence")
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	return bucket.Put(key, val)
+}
+
+// lastSequenced calls fn for up to limit of the most recent values in the
+// per-guild sub-bucket of parent, newest first
+func lastSequenced(parent *bolt.Bucket, guild string, limit int, fn func(val []byte) error) error {
+	bucket := parent.Bucket([]byte(guild))
+	if bucket == nil {
+		return nil
+	}
+
+	c := bucket.Cursor()
+	i := 0
+	for k, v := c.Last(); k != nil && i < limit; k, v = c.Prev() {
+		if err := fn(v); err != nil {
+			return err
+		}
+		i++
+	}
+
+	return nil
+}
@@ -1,80 +1,167 @@
-package storage
-
-import (
-	"errors"
-
-	"github.com/golang/protobuf/proto"
-)
-
-// ErrCharacterNotExist is the error returned if a character does not exist
-var ErrCharacterNotExist = errors.New("character does not exist")
-
-type boltUser struct {
-	protoUser *ProtoUser
-}
-
-func (u *boltUser) GetName() string {
-	return u.protoUser.Name
-}
-
-func (u *boltUser) GetCharacter(name string) (Character, error) {
-	if u.protoUser.Characters == nil {
-		return nil, ErrCharacterNotExist
-	}
-
-	protoChar, ok := u.protoUser.Characters[name]
-	if !ok {
-		return nil, ErrCharacterNotExist
-	}
-
-	return &boltCharacter{protoChar}, nil
-}
-
-func (u *boltUser) GetCharacters() []Character {
-	if u.protoUser.Characters == nil {
-		return []Character{}
-	}
-
-	chars := make([]Character, len(u.protoUser.Characters))
-	i := 0
-	for _, protoChar := range u.protoUser.Characters {
-		chars[i] = &boltCharacter{protoChar}
-		i++
-	}
-	return chars
-}
-
-func (u *boltUser) SetName(name string) {
-	u.protoUser.Name = name
-}
-
-func (u *boltUser) AddCharacter(name string) Character {
-	if u.protoUser.Characters == nil {
-		u.protoUser.Characters = map[string]*ProtoCharacter{}
-	}
-
-	char, err := u.GetCharacter(name)
-	if err != nil {
-		protoChar := &ProtoCharacter{Name: name}
-		u.protoUser.Characters[name] = protoChar
-		char = &boltCharacter{protoChar}
-	}
-
-	return char
-}
-
-func (u *boltUser) DeleteCharacter(name string) {
-	if u.protoUser.Characters == nil {
-		return
-	}
-
-	_, ok := u.protoUser.Characters[name]
-	if ok {
-		delete(u.protoUser.Characters, name)
-	}
-}
-
-func (u *boltUser) Serialize() (out []byte, err error) {
-	out, err = proto.Marshal(u.protoUser)
-	return
-}
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ErrCharacterNotExist is the error returned if a character does not exist
+var ErrCharacterNotExist = errors.New("character does not exist")
+
+type boltUser struct {
+	protoUser *ProtoUser
+}
+
+func (u *boltUser) GetName() string {
+	return u.protoUser.Name
+}
+
+func (u *boltUser) GetCharacter(name string) (Character, error) {
+	if u.protoUser.Characters == nil {
+		return nil, ErrCharacterNotExist
+	}
+
+	protoChar, ok := u.protoUser.Characters[name]
+	if !ok {
+		return nil, ErrCharacterNotExist
+	}
+
+	return &boltCharacter{protoChar}, nil
+}
+
+func (u *boltUser) GetCharacters() []Character {
+	if u.protoUser.Characters == nil {
+		return []Character{}
+	}
+
+	chars := make([]Character, len(u.protoUser.Characters))
+	i := 0
+	for _, protoChar := range u.protoUser.Characters {
+		chars[i] = &boltCharacter{protoChar}
+		i++
+	}
+	return chars
+}
+
+func (u *boltUser) GetGuilds() []string {
+	guilds := make([]string, len(u.protoUser.Guilds))
+	copy(guilds, u.protoUser.Guilds)
+	return guilds
+}
+
+func (u *boltUser) InGuild(guild string) bool {
+	for _, g := range u.protoUser.Guilds {
+		if g == guild {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *boltUser) AddGuild(guild string) {
+	if u.InGuild(guild) {
+		return
+	}
+
+	u.protoUser.Guilds = append(u.protoUser.Guilds, guild)
+	u.protoUser.GuildsRecorded = true
+}
+
+func (u *boltUser) RemoveGuild(guild string) {
+	for i, g := range u.protoUser.Guilds {
+		if g == guild {
+			u.protoUser.Guilds = append(u.protoUser.Guilds[:i], u.protoUser.Guilds[i+1:]...)
+			u.protoUser.GuildsRecorded = true
+			return
+		}
+	}
+}
+
+// GuildsRecorded is false for users stored before their guilds were recorded,
+// whose guild list is empty rather than accurate
+func (u *boltUser) GuildsRecorded() bool {
+	return u.protoUser.GuildsRecorded || len(u.protoUser.Guilds) > 0
+}
+
+func (u *boltUser) GetNotifyMode() string {
+	if u.protoUser.NotifyMode == "" {
+		return NotifyOff
+	}
+	return u.protoUser.NotifyMode
+}
+
+func (u *boltUser) SetNotifyMode(mode string) {
+	u.protoUser.NotifyMode = mode
+}
+
+func (u *boltUser) GetDMReplies() bool {
+	return u.protoUser.DmReplies
+}
+
+func (u *boltUser) SetDMReplies(dm bool) {
+	u.protoUser.DmReplies = dm
+}
+
+func (u *boltUser) GetLastNotified(item string) time.Time {
+	ts, ok := u.protoUser.LastNotified[strings.ToLower(item)]
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+func (u *boltUser) SetLastNotified(item string, at time.Time) {
+	if u.protoUser.LastNotified == nil {
+		u.protoUser.LastNotified = map[string]int64{}
+	}
+	u.protoUser.LastNotified[strings.ToLower(item)] = at.Unix()
+}
+
+func (u *boltUser) GetPrivacy() string {
+	if u.protoUser.Privacy == "" {
+		return PrivacyGuild
+	}
+	return u.protoUser.Privacy
+}
+
+func (u *boltUser) SetPrivacy(privacy string) {
+	u.protoUser.Privacy = privacy
+}
+
+func (u *boltUser) SetName(name string) {
+	u.protoUser.Name = name
+}
+
+func (u *boltUser) AddCharacter(name string) Character {
+	if u.protoUser.Characters == nil {
+		u.protoUser.Characters = map[string]*ProtoCharacter{}
+	}
+
+	char, err := u.GetCharacter(name)
+	if err != nil {
+		protoChar := &ProtoCharacter{Name: name}
+		u.protoUser.Characters[name] = protoChar
+		char = &boltCharacter{protoChar}
+	}
+
+	return char
+}
+
+func (u *boltUser) DeleteCharacter(name string) {
+	if u.protoUser.Characters == nil {
+		return
+	}
+
+	_, ok := u.protoUser.Characters[name]
+	if ok {
+		delete(u.protoUser.Characters, name)
+	}
+}
+
+func (u *boltUser) Serialize() (out []byte, err error) {
+	out, err = proto.Marshal(u.protoUser)
+	return
+}
@@ -1,109 +1,124 @@
-package storage
-
-import (
-	bolt "github.com/coreos/bbolt"
-	"github.com/golang/protobuf/proto"
-	"github.com/pkg/errors"
-)
-
-// ErrUserNotExist is the error returned if a user does not exist
-var ErrUserNotExist = errors.New("user does not exist")
-
-type boltUserAPI struct {
-	db         *bolt.DB
-	bucketName []byte
-}
-
-// NewBoltUserAPI constructs a boltDB-backed UserAPI
-func NewBoltUserAPI(db *bolt.DB) (UserAPI, error) {
-	b := boltUserAPI{
-		db:         db,
-		bucketName: []byte("UserRecords"),
-	}
-
-	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(b.bucketName)
-		if err != nil {
-			return errors.Wrap(err, "could not create bucket")
-		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &b, nil
-}
-
-func (b *boltUserAPI) NewTransaction(writable bool) (UserAPITx, error) {
-	tx, err := b.db.Begin(writable)
-	if err != nil {
-		return nil, err
-	}
-	return &boltUserAPITx{
-		bucketName: b.bucketName,
-		tx:         tx,
-	}, nil
-}
-
-type boltUserAPITx struct {
-	bucketName []byte
-	tx         *bolt.Tx
-}
-
-func (b *boltUserAPITx) Commit() error {
-	return b.tx.Commit()
-}
-
-func (b *boltUserAPITx) Rollback() error {
-	err := b.tx.Rollback()
-	if err != nil && err != bolt.ErrTxClosed {
-		return err
-	}
-	return nil
-}
-
-func (b *boltUserAPITx) AddUser(name string) (User, error) {
-	user, err := b.GetUser(name)
-	if err == ErrUserNotExist {
-		user = &boltUser{
-			protoUser: &ProtoUser{Name: name},
-		}
-		err = nil
-	}
-	return user, err
-}
-
-func (b *boltUserAPITx) SaveUser(user User) error {
-	bucket := b.tx.Bucket(b.bucketName)
-
-	serial, err := user.Serialize()
-	if err != nil {
-		return err
-	}
-
-	return bucket.Put([]byte(user.GetName()), serial)
-}
-
-func (b *boltUserAPITx) GetUser(name string) (User, error) {
-	bucket := b.tx.Bucket(b.bucketName)
-
-	val := bucket.Get([]byte(name))
-
-	if val == nil {
-		return nil, ErrUserNotExist
-	}
-
-	protoUser := ProtoUser{}
-	err := proto.Unmarshal(val, &protoUser)
-	if err != nil {
-		return nil, errors.Wrap(err, "user record is corrupt")
-	}
-
-	return &boltUser{&protoUser}, nil
-}
-
-func (b *boltUserAPITx) GetUsers() []User {
-	return nil
-}
+package storage
+
+import (
+	bolt "github.com/coreos/bbolt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrUserNotExist is the error returned if a user does not exist
+var ErrUserNotExist = errors.New("user does not exist")
+
+var userBucketName = []byte("UserRecords")
+
+type boltUserAPI struct {
+	db         *bolt.DB
+	bucketName []byte
+}
+
+// NewBoltUserAPI constructs a boltDB-backed UserAPI
+func NewBoltUserAPI(db *bolt.DB) (UserAPI, error) {
+	b := boltUserAPI{
+		db:         db,
+		bucketName: userBucketName,
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(b.bucketName)
+		if err != nil {
+			return errors.Wrap(err, "could not create bucket")
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *boltUserAPI) NewTransaction(writable bool) (UserAPITx, error) {
+	tx, timer, err := beginTx(b.db, "user", writable)
+	if err != nil {
+		return nil, err
+	}
+	return &boltUserAPITx{
+		bucketName: b.bucketName,
+		tx:         tx,
+		timer:      timer,
+	}, nil
+}
+
+type boltUserAPITx struct {
+	bucketName []byte
+	tx         *bolt.Tx
+	timer      *txTimer
+}
+
+func (b *boltUserAPITx) Commit() error {
+	return commitTx(b.tx, b.timer)
+}
+
+func (b *boltUserAPITx) Rollback() error {
+	return rollbackTx(b.tx, b.timer)
+}
+
+func (b *boltUserAPITx) AddUser(name string) (User, error) {
+	user, err := b.GetUser(name)
+	if err == ErrUserNotExist {
+		// new users have every guild they use recorded from the start
+		user = &boltUser{
+			protoUser: &ProtoUser{Name: name, GuildsRecorded: true},
+		}
+		err = nil
+	}
+	return user, err
+}
+
+func (b *boltUserAPITx) SaveUser(user User) error {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	serial, err := user.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(user.GetName()), serial)
+}
+
+func (b *boltUserAPITx) GetUser(name string) (User, error) {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	val := bucket.Get([]byte(name))
+
+	if val == nil {
+		return nil, ErrUserNotExist
+	}
+
+	protoUser := ProtoUser{}
+	err := proto.Unmarshal(val, &protoUser)
+	if err != nil {
+		return nil, errors.Wrap(err, "user record is corrupt")
+	}
+
+	return &boltUser{&protoUser}, nil
+}
+
+// GetUsers returns every stored user; corrupt records are skipped
+func (b *boltUserAPITx) GetUsers() []User {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	users := []User{}
+	_ = bucket.ForEach(func(k, v []byte) error {
+		protoUser := ProtoUser{}
+		if err := proto.Unmarshal(v, &protoUser); err != nil {
+			return nil
+		}
+
+		users = append(users, &boltUser{&protoUser})
+		return nil
+	})
+
+	return users
+}
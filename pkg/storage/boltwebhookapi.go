@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrWebhooksNotExist is the error returned if a guild has no webhook record
+var ErrWebhooksNotExist = errors.New("webhooks do not exist")
+
+// ErrWebhookNotExist is the error returned if a guild has no webhook with an id
+var ErrWebhookNotExist = errors.New("webhook does not exist")
+
+type boltWebhookAPI struct {
+	db          *bolt.DB
+	bucketName  []byte
+	queueBucket []byte
+}
+
+// NewBoltWebhookAPI constructs a boltDB-backed WebhookAPI
+func NewBoltWebhookAPI(db *bolt.DB) (WebhookAPI, error) {
+	b := boltWebhookAPI{
+		db:          db,
+		bucketName:  []byte("WebhookRecords"),
+		queueBucket: []byte("WebhookQueue"),
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{b.bucketName, b.queueBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return errors.Wrap(err, "could not create bucket")
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *boltWebhookAPI) NewTransaction(writable bool) (WebhookAPITx, error) {
+	tx, timer, err := beginTx(b.db, "webhook", writable)
+	if err != nil {
+		return nil, err
+	}
+	return &boltWebhookAPITx{
+		bucketName:  b.bucketName,
+		queueBucket: b.queueBucket,
+		tx:          tx,
+		timer:       timer,
+	}, nil
+}
+
+type boltWebhookAPITx struct {
+	bucketName  []byte
+	queueBucket []byte
+	tx          *bolt.Tx
+	timer       *txTimer
+}
+
+func (b *boltWebhookAPITx) Commit() error {
+	return commitTx(b.tx, b.timer)
+}
+
+func (b *boltWebhookAPITx) Rollback() error {
+	return rollbackTx(b.tx, b.timer)
+}
+
+func (b *boltWebhookAPITx) AddWebhooks(guild string) (Webhooks, error) {
+	hooks, err := b.GetWebhooks(guild)
+	if err == ErrWebhooksNotExist {
+		hooks = &boltWebhooks{
+			protoWebhooks: &ProtoWebhooks{Guild: guild},
+		}
+		err = nil
+	}
+	return hooks, err
+}
+
+func (b *boltWebhookAPITx) SaveWebhooks(hooks Webhooks) error {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	serial, err := hooks.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(hooks.GetGuild()), serial)
+}
+
+func (b *boltWebhookAPITx) GetWebhooks(guild string) (Webhooks, error) {
+	bucket := b.tx.Bucket(b.bucketName)
+
+	val := bucket.Get([]byte(guild))
+
+	if val == nil {
+		return nil, ErrWebhooksNotExist
+	}
+
+	protoWebhooks := ProtoWebhooks{}
+	err := proto.Unmarshal(val, &protoWebhooks)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhooks record is corrupt")
+	}
+
+	return &boltWebhooks{&protoWebhooks}, nil
+}
+
+func deliveryKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+func (b *boltWebhookAPITx) Enqueue(d *WebhookDelivery) error {
+	bucket := b.tx.Bucket(b.queueBucket)
+
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return errors.Wrap(err, "could not get next sequence")
+	}
+	d.ID = seq
+
+	return b.SaveDelivery(*d)
+}
+
+func (b *boltWebhookAPITx) SaveDelivery(d WebhookDelivery) error {
+	bucket := b.tx.Bucket(b.queueBucket)
+
+	serial, err := proto.Marshal(deliveryToProto(d))
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put(deliveryKey(d.ID), serial)
+}
+
+func (b *boltWebhookAPITx) DeleteDelivery(id uint64) error {
+	return b.tx.Bucket(b.queueBucket).Delete(deliveryKey(id))
+}
+
+// DueDeliveries skips corrupt records, which DeleteDelivery can still remove
+func (b *boltWebhookAPITx) DueDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	c := b.tx.Bucket(b.queueBucket).Cursor()
+
+	var due []WebhookDelivery
+	for k, v := c.First(); k != nil && len(due) < limit; k, v = c.Next() {
+		pd := ProtoWebhookDelivery{}
+		if err := proto.Unmarshal(v, &pd); err != nil {
+			continue
+		}
+
+		if pd.NextAttempt > now.Unix() {
+			continue
+		}
+		due = append(due, deliveryFromProto(&pd))
+	}
+
+	return due, nil
+}
+
+func (b *boltWebhookAPITx) QueueLength() int {
+	return b.tx.Bucket(b.queueBucket).Stats().KeyN
+}
+
+func deliveryToProto(d WebhookDelivery) *ProtoWebhookDelivery {
+	return &ProtoWebhookDelivery{
+		Id:          d.ID,
+		Guild:       d.Guild,
+		HookId:      d.HookID,
+		Event:       d.Event,
+		Payload:     d.Payload,
+		Attempts:    int32(d.Attempts),
+		NextAttempt: d.NextAttempt.Unix(),
+		Created:     d.Created.Unix(),
+		LastError:   d.LastError,
+	}
+}
+
+func deliveryFromProto(pd *ProtoWebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:          pd.Id,
+		Guild:       pd.Guild,
+		HookID:      pd.HookId,
+		Event:       pd.Event,
+		Payload:     pd.Payload,
+		Attempts:    int(pd.Attempts),
+		NextAttempt: time.Unix(pd.NextAttempt, 0),
+		Created:     time.Unix(pd.Created, 0),
+		LastError:   pd.LastError,
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type boltWebhooks struct {
+	protoWebhooks *ProtoWebhooks
+}
+
+func (w *boltWebhooks) GetGuild() string {
+	return w.protoWebhooks.Guild
+}
+
+func (w *boltWebhooks) GetWebhooks() []Webhook {
+	hooks := make([]Webhook, len(w.protoWebhooks.Hooks))
+	for i, h := range w.protoWebhooks.Hooks {
+		hooks[i] = webhookFromProto(h)
+	}
+	return hooks
+}
+
+func (w *boltWebhooks) GetWebhook(id uint64) (Webhook, error) {
+	for _, h := range w.protoWebhooks.Hooks {
+		if h.Id == id {
+			return webhookFromProto(h), nil
+		}
+	}
+	return Webhook{}, ErrWebhookNotExist
+}
+
+func (w *boltWebhooks) AddWebhook(url, secret string, events []string, creator string, at time.Time) Webhook {
+	w.protoWebhooks.NextHookId++
+	h := &ProtoWebhook{
+		Id:      w.protoWebhooks.NextHookId,
+		Url:     url,
+		Secret:  secret,
+		Events:  append([]string(nil), events...),
+		Created: at.Unix(),
+		Creator: creator,
+	}
+	w.protoWebhooks.Hooks = append(w.protoWebhooks.Hooks, h)
+	return webhookFromProto(h)
+}
+
+func (w *boltWebhooks) RemoveWebhook(id uint64) error {
+	for i, h := range w.protoWebhooks.Hooks {
+		if h.Id == id {
+			w.protoWebhooks.Hooks = append(w.protoWebhooks.Hooks[:i], w.protoWebhooks.Hooks[i+1:]...)
+			return nil
+		}
+	}
+	return ErrWebhookNotExist
+}
+
+func (w *boltWebhooks) Serialize() (out []byte, err error) {
+	out, err = proto.Marshal(w.protoWebhooks)
+	return
+}
+
+func webhookFromProto(h *ProtoWebhook) Webhook {
+	return Webhook{
+		ID:      h.Id,
+		URL:     h.Url,
+		Secret:  h.Secret,
+		Events:  append([]string(nil), h.Events...),
+		Created: time.Unix(h.Created, 0),
+		Creator: h.Creator,
+	}
+}
@@ -3,51 +3,14 @@ package storage
 //go:generate protoc --go_out=. --proto_path=. ./guildapi.proto
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/pkg/errors"
 )
 
 // ErrBadSetting is the error returned if an unknown setting is accessed
 var ErrBadSetting = errors.New("bad setting")
 
-// GuildSettings is the configuration settings set for a guild
-type GuildSettings struct {
-	ControlSequence string
-}
-
-// PrettyString returns a multi-line string representation of the guild settings
-func (s *GuildSettings) PrettyString() string {
-	return fmt.Sprintf(`
-%[1]s
-GuildSettings{
-	ControlSequence: '%[2]s',
-}
-%[1]s
-	`, "```", s.ControlSequence)
-}
-
-// GetSettingString returns the value of the requested setting
-func (s *GuildSettings) GetSettingString(name string) (string, error) {
-	switch strings.ToLower(name) {
-	case "controlsequence":
-		return s.ControlSequence, nil
-	default:
-		return "", ErrBadSetting
-	}
-}
-
-// SetSettingString sets the value of the requested setting
-func (s *GuildSettings) SetSettingString(name, val string) error {
-	switch strings.ToLower(name) {
-	case "controlsequence":
-		s.ControlSequence = val
-		return nil
-	default:
-		return ErrBadSetting
-	}
-}
+// ErrBadSettingValue is the error returned if a setting is given an invalid value
+var ErrBadSettingValue = errors.New("bad setting value")
 
 // GuildAPI is the api for managing guilds transactions
 type GuildAPI interface {
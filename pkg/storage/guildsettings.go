@@ -0,0 +1,413 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingType is the kind of value a guild setting holds
+type SettingType string
+
+// Valid SettingType values
+const (
+	SettingString   SettingType = "string"
+	SettingInt      SettingType = "int"
+	SettingBool     SettingType = "bool"
+	SettingChannel  SettingType = "channel"
+	SettingRole     SettingType = "role"
+	SettingRoles    SettingType = "roles"
+	SettingChannels SettingType = "channels"
+	SettingDuration SettingType = "duration"
+)
+
+// Names of the registered guild settings
+const (
+	SettingControlSequence     = "controlsequence"
+	SettingLootSystem          = "lootsystem"
+	SettingNotificationChannel = "notificationchannel"
+	SettingConfigRoles         = "configroles"
+	SettingOfficerRoles        = "officerroles"
+	SettingMemberRoles         = "memberroles"
+	SettingBannedRoles         = "bannedroles"
+	SettingCommandChannels     = "commandchannels"
+	SettingIgnoredChannels     = "ignoredchannels"
+	SettingReplyChannel        = "replychannel"
+	SettingAutoCorrect         = "autocorrect"
+)
+
+// SettingDef describes a single guild setting
+type SettingDef struct {
+	Name        string
+	Type        SettingType
+	Default     string
+	Description string
+
+	// Validate is an optional extra check run on the normalized value
+	Validate func(val string) error
+}
+
+// Normalize parses val according to the setting type and returns the canonical
+// form to be stored. An empty value is always allowed and means "use the default".
+func (d SettingDef) Normalize(val string) (string, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return "", nil
+	}
+
+	switch d.Type {
+	case SettingInt:
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return "", ErrBadSettingValue
+		}
+		val = strconv.Itoa(i)
+	case SettingBool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return "", ErrBadSettingValue
+		}
+		val = strconv.FormatBool(b)
+	case SettingChannel:
+		val = strings.TrimSuffix(strings.TrimPrefix(val, "<#"), ">")
+		if _, err := strconv.ParseUint(val, 10, 64); err != nil {
+			return "", ErrBadSettingValue
+		}
+	case SettingRole:
+		val = strings.TrimSuffix(strings.TrimPrefix(val, "<@&"), ">")
+		if _, err := strconv.ParseUint(val, 10, 64); err != nil {
+			return "", ErrBadSettingValue
+		}
+	case SettingRoles:
+		var err error
+		val, err = normalizeIDs(val, "<@&")
+		if err != nil {
+			return "", err
+		}
+	case SettingChannels:
+		var err error
+		val, err = normalizeIDs(val, "<#")
+		if err != nil {
+			return "", err
+		}
+	case SettingDuration:
+		dur, err := time.ParseDuration(val)
+		if err != nil {
+			return "", ErrBadSettingValue
+		}
+		val = dur.String()
+	}
+
+	if d.Validate != nil {
+		if err := d.Validate(val); err != nil {
+			return "", err
+		}
+	}
+
+	return val, nil
+}
+
+// Display returns val formatted for showing to a discord user
+func (d SettingDef) Display(val string) string {
+	if val == "" {
+		return ""
+	}
+
+	switch d.Type {
+	case SettingChannel:
+		return fmt.Sprintf("<#%s>", val)
+	case SettingRole:
+		return fmt.Sprintf("<@&%s>", val)
+	case SettingRoles:
+		return mentionIDs(val, "<@&")
+	case SettingChannels:
+		return mentionIDs(val, "<#")
+	default:
+		return val
+	}
+}
+
+func mentionIDs(val, mentionPrefix string) string {
+	ids := splitIDs(val)
+	for i, id := range ids {
+		ids[i] = mentionPrefix + id + ">"
+	}
+	return strings.Join(ids, " ")
+}
+
+func splitIDs(val string) []string {
+	return strings.FieldsFunc(val, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// normalizeIDs turns a list of mentions or ids into a comma-separated list of ids
+func normalizeIDs(val, mentionPrefix string) (string, error) {
+	ids := splitIDs(val)
+	for i, id := range ids {
+		id = strings.TrimSuffix(strings.TrimPrefix(id, mentionPrefix), ">")
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return "", ErrBadSettingValue
+		}
+		ids[i] = id
+	}
+	return strings.Join(ids, ","), nil
+}
+
+func oneOf(vals ...string) func(string) error {
+	return func(val string) error {
+		for _, v := range vals {
+			if val == v {
+				return nil
+			}
+		}
+		return ErrBadSettingValue
+	}
+}
+
+var settingDefs = []SettingDef{
+	{
+		Name:        SettingControlSequence,
+		Type:        SettingString,
+		Description: "The prefix for bot commands in this guild. Empty means the bot-wide default.",
+	},
+	{
+		Name:        SettingLootSystem,
+		Type:        SettingString,
+		Default:     LootSystemDKP,
+		Description: "How `loot drop` ranks candidates: `dkp` or `rotation`.",
+		Validate:    oneOf(LootSystemDKP, LootSystemRotation),
+	},
+	{
+		Name:        SettingNotificationChannel,
+		Type:        SettingChannel,
+		Description: "The channel for want-match notifications of members using `notify channel`.",
+	},
+	{
+		Name:        SettingConfigRoles,
+		Type:        SettingRoles,
+		Description: "Roles that may change bot configuration. Discord admins always can.",
+	},
+	{
+		Name:        SettingOfficerRoles,
+		Type:        SettingRoles,
+		Description: "Roles that may run officer commands (bank and loot management).",
+	},
+	{
+		Name:        SettingMemberRoles,
+		Type:        SettingRoles,
+		Description: "Roles that may use the bot at all. Empty means everyone.",
+	},
+	{
+		Name:        SettingBannedRoles,
+		Type:        SettingRoles,
+		Description: "Roles that the bot ignores entirely.",
+	},
+	{
+		Name:        SettingCommandChannels,
+		Type:        SettingChannels,
+		Description: "Channels the bot accepts commands in. Empty means every channel.",
+	},
+	{
+		Name:        SettingIgnoredChannels,
+		Type:        SettingChannels,
+		Description: "Channels the bot never responds in.",
+	},
+	{
+		Name:        SettingReplyChannel,
+		Type:        SettingChannel,
+		Description: "Channel that command replies are sent to. Empty means the channel the command came from.",
+	},
+	{
+		Name:        SettingAutoCorrect,
+		Type:        SettingBool,
+		Default:     "false",
+		Description: "Whether a misspelled character, item or subcommand name with exactly one close match is used as that match instead of only suggesting it.",
+	},
+}
+
+// SettingDefs returns the definitions of all guild settings, in display order
+func SettingDefs() []SettingDef {
+	defs := make([]SettingDef, len(settingDefs))
+	copy(defs, settingDefs)
+	return defs
+}
+
+// LookupSetting returns the definition of the named guild setting
+func LookupSetting(name string) (SettingDef, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, d := range settingDefs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return SettingDef{}, false
+}
+
+// GuildSettings is the configuration settings set for a guild
+type GuildSettings struct {
+	values map[string]string
+}
+
+// NewGuildSettings creates a GuildSettings from stored setting values; unknown
+// names are kept so that settings are not lost across version changes
+func NewGuildSettings(values map[string]string) GuildSettings {
+	s := GuildSettings{values: map[string]string{}}
+	for k, v := range values {
+		if v != "" {
+			s.values[strings.ToLower(k)] = v
+		}
+	}
+	return s
+}
+
+// Values returns the explicitly set (non-default) setting values
+func (s GuildSettings) Values() map[string]string {
+	vals := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		vals[k] = v
+	}
+	return vals
+}
+
+// IsSet returns whether the named setting has been explicitly set
+func (s GuildSettings) IsSet(name string) bool {
+	_, ok := s.values[strings.ToLower(name)]
+	return ok
+}
+
+// Get returns the value of the requested setting, or its default if unset
+func (s GuildSettings) Get(name string) (string, error) {
+	d, ok := LookupSetting(name)
+	if !ok {
+		return "", ErrBadSetting
+	}
+
+	if v, ok := s.values[d.Name]; ok {
+		return v, nil
+	}
+	return d.Default, nil
+}
+
+// Set validates and sets the value of the requested setting
+func (s *GuildSettings) Set(name, val string) error {
+	d, ok := LookupSetting(name)
+	if !ok {
+		return ErrBadSetting
+	}
+
+	val, err := d.Normalize(val)
+	if err != nil {
+		return err
+	}
+
+	if s.values == nil {
+		s.values = map[string]string{}
+	}
+
+	if val == "" {
+		delete(s.values, d.Name)
+	} else {
+		s.values[d.Name] = val
+	}
+	return nil
+}
+
+// Reset returns the requested setting to its default
+func (s *GuildSettings) Reset(name string) error {
+	d, ok := LookupSetting(name)
+	if !ok {
+		return ErrBadSetting
+	}
+
+	delete(s.values, d.Name)
+	return nil
+}
+
+func (s GuildSettings) get(name string) string {
+	v, _ := s.Get(name)
+	return v
+}
+
+// String returns the value of a setting, or "" if it is unknown
+func (s GuildSettings) String(name string) string {
+	return s.get(name)
+}
+
+// Int returns the value of an int setting, or 0 if it is unset or unknown
+func (s GuildSettings) Int(name string) int {
+	i, _ := strconv.Atoi(s.get(name))
+	return i
+}
+
+// Bool returns the value of a bool setting, or false if it is unset or unknown
+func (s GuildSettings) Bool(name string) bool {
+	b, _ := strconv.ParseBool(s.get(name))
+	return b
+}
+
+// Duration returns the value of a duration setting, or 0 if it is unset or unknown
+func (s GuildSettings) Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(s.get(name))
+	return d
+}
+
+// IDs returns the value of a channel or role (list) setting as a list of ids
+func (s GuildSettings) IDs(name string) []string {
+	return splitIDs(s.get(name))
+}
+
+// ControlSequence returns the command prefix configured for the guild
+func (s GuildSettings) ControlSequence() string {
+	return s.get(SettingControlSequence)
+}
+
+// LootSystem returns the loot ranking system configured for the guild
+func (s GuildSettings) LootSystem() string {
+	return s.get(SettingLootSystem)
+}
+
+// NotificationChannel returns the channel id configured for want notifications
+func (s GuildSettings) NotificationChannel() string {
+	return s.get(SettingNotificationChannel)
+}
+
+// AutoCorrect returns whether misspelled names with a single close match are corrected
+func (s GuildSettings) AutoCorrect() bool {
+	return s.Bool(SettingAutoCorrect)
+}
+
+// PrettyString returns a multi-line string representation of the guild settings
+func (s GuildSettings) PrettyString() string {
+	width := 0
+	for _, d := range settingDefs {
+		if len(d.Name) > width {
+			width = len(d.Name)
+		}
+	}
+
+	lines := make([]string, 0, len(settingDefs))
+	for _, d := range settingDefs {
+		val := s.get(d.Name)
+		line := fmt.Sprintf("%-*s '%s'", width+1, d.Name+":", val)
+		if !s.IsSet(d.Name) {
+			line += " (default)"
+		}
+		lines = append(lines, line)
+	}
+
+	var unknown []string
+	for k := range s.values {
+		if _, ok := LookupSetting(k); !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		lines = append(lines, fmt.Sprintf("%-*s '%s' (unknown)", width+1, k+":", s.values[k]))
+	}
+
+	return fmt.Sprintf("```\n%s\n```", strings.Join(lines, "\n"))
+}
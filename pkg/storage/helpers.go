@@ -25,3 +25,126 @@ func GetSettings(gapi GuildAPI, gid snowflake.Snowflake) (s GuildSettings, err e
 	s = bGuild.GetSettings()
 	return
 }
+
+// Counts reports how many users and characters are stored, and how many
+// guilds those users have used the bot in
+//
+// NOTE: this cannot be called while another transaction is open
+func Counts(uapi UserAPI) (users, characters, guilds int, err error) {
+	t, err := uapi.NewTransaction(false)
+	if err != nil {
+		return
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	seen := map[string]bool{}
+	for _, user := range t.GetUsers() {
+		users++
+		characters += len(user.GetCharacters())
+		for _, gid := range user.GetGuilds() {
+			seen[gid] = true
+		}
+	}
+	guilds = len(seen)
+
+	return
+}
+
+// RecordGuild notes that a stored user has used the bot in a guild, so that
+// guild privacy and guild-wide features (like loot) include them there; users
+// with nothing stored are left alone
+//
+// NOTE: this cannot be called while another transaction is open
+func RecordGuild(uapi UserAPI, uid, gid snowflake.Snowflake) error {
+	return changeGuilds(uapi, uid, gid, true)
+}
+
+// ForgetGuild notes that a user has left a guild
+//
+// NOTE: this cannot be called while another transaction is open
+func ForgetGuild(uapi UserAPI, uid, gid snowflake.Snowflake) error {
+	return changeGuilds(uapi, uid, gid, false)
+}
+
+func changeGuilds(uapi UserAPI, uid, gid snowflake.Snowflake, member bool) error {
+	if gid == 0 {
+		return nil
+	}
+
+	// most commands come from users whose guilds are already up to date, so
+	// check that before taking the write lock
+	changed, err := guildsChange(uapi, uid, gid, member)
+	if err != nil || !changed {
+		return err
+	}
+
+	t, err := uapi.NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	user, err := t.GetUser(uid.ToString())
+	if err == ErrUserNotExist {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to find user")
+	}
+
+	if member {
+		user.AddGuild(gid.ToString())
+	} else {
+		user.RemoveGuild(gid.ToString())
+	}
+
+	err = t.SaveUser(user)
+	if err != nil {
+		return errors.Wrap(err, "could not save user")
+	}
+
+	return t.Commit()
+}
+
+func guildsChange(uapi UserAPI, uid, gid snowflake.Snowflake, member bool) (bool, error) {
+	t, err := uapi.NewTransaction(false)
+	if err != nil {
+		return false, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	user, err := t.GetUser(uid.ToString())
+	if err == ErrUserNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "unable to find user")
+	}
+
+	return user.InGuild(gid.ToString()) != member, nil
+}
+
+func privacyAllows(privacy string, user User, guild string) bool {
+	switch privacy {
+	case PrivacyPublic:
+		return true
+	case PrivacyGuild:
+		if guild == "" || guild == "0" {
+			return false
+		}
+		// users from before guilds were recorded are treated as members of
+		// every guild, rather than hiding all of their characters
+		return !user.GuildsRecorded() || user.InGuild(guild)
+	default:
+		return false
+	}
+}
+
+// CanViewCharacter reports whether a character can be shown to someone other
+// than its owner, from within the given guild
+func CanViewCharacter(user User, char Character, guild string) bool {
+	if user.GetPrivacy() == PrivacyCharacter {
+		return privacyAllows(char.GetPrivacy(), user, guild)
+	}
+	return privacyAllows(user.GetPrivacy(), user, guild)
+}
@@ -0,0 +1,58 @@
+package storage
+
+//go:generate protoc --go_out=. --proto_path=. ./lootapi.proto
+
+import (
+	"time"
+)
+
+// Valid loot systems for the lootsystem guild setting
+const (
+	LootSystemDKP      = "dkp"
+	LootSystemRotation = "rotation"
+)
+
+// LootAward is a record of a loot drop being handed to a guild member
+type LootAward struct {
+	Time      time.Time
+	Awarder   string
+	User      string
+	Character string
+	Item      string
+	Cost      int64
+}
+
+// LootAPI is the api for managing loot distribution transactions
+type LootAPI interface {
+	NewTransaction(writable bool) (LootAPITx, error)
+}
+
+// LootAPITx is the api for managing loot tables within a transaction
+type LootAPITx interface {
+	Commit() error
+	Rollback() error
+
+	GetLootTable(guild string) (LootTable, error)
+	AddLootTable(guild string) (LootTable, error)
+	SaveLootTable(table LootTable) error
+
+	AddAward(guild string, award LootAward) error
+	GetAwards(guild string, limit int) ([]LootAward, error)
+
+	// UserTx returns a UserAPITx that shares this transaction, so that user
+	// and loot changes are committed (or rolled back) together
+	UserTx() UserAPITx
+}
+
+// LootTable is the api for managing a particular guild's loot points
+type LootTable interface {
+	GetGuild() string
+	GetMembers() []string
+	GetPoints(user string) int64
+	GetLastAward(user string) time.Time
+
+	AddPoints(user string, amt int64)
+	RecordAward(user string, at time.Time, cost int64)
+
+	Serialize() ([]byte, error)
+}
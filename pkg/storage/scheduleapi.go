@@ -0,0 +1,48 @@
+package storage
+
+//go:generate protoc --go_out=. --proto_path=. ./scheduleapi.proto
+
+import (
+	"time"
+)
+
+// Reminder is a one-off reminder about a character's needed item
+type Reminder struct {
+	ID        uint64
+	Character string
+	Item      string
+	Due       time.Time
+}
+
+// ScheduleAPI is the api for managing user schedule transactions
+type ScheduleAPI interface {
+	NewTransaction(writable bool) (ScheduleAPITx, error)
+}
+
+// ScheduleAPITx is the api for managing user schedules within a transaction
+type ScheduleAPITx interface {
+	Commit() error
+	Rollback() error
+
+	GetSchedule(user string) (Schedule, error)
+	GetSchedules() []Schedule
+	AddSchedule(user string) (Schedule, error)
+	SaveSchedule(schedule Schedule) error
+
+	// UserTx returns a UserAPITx that shares this transaction
+	UserTx() UserAPITx
+}
+
+// Schedule is the api for managing a particular user's digests and reminders
+type Schedule interface {
+	GetUser() string
+	GetDigestInterval() time.Duration
+	GetNextDigest() time.Time
+	GetReminders() []Reminder
+
+	SetDigest(interval time.Duration, next time.Time)
+	AddReminder(character, item string, due time.Time) Reminder
+	PopDueReminders(now time.Time) []Reminder
+
+	Serialize() ([]byte, error)
+}
@@ -1 +1,86 @@
 package storage
+
+import "testing"
+
+func TestDecrNeeded(t *testing.T) {
+	kinds := []struct {
+		name  string
+		incr  func(c Character, name string, amt uint64)
+		decr  func(c Character, name string, amt uint64) DecrResult
+		count func(c Character, name string) (uint64, bool)
+	}{
+		{
+			name: "item",
+			incr: Character.IncrNeededItem,
+			decr: Character.DecrNeededItem,
+			count: func(c Character, name string) (uint64, bool) {
+				i, err := c.GetNeededItem(name)
+				if err != nil {
+					return 0, false
+				}
+				return i.Count(), true
+			},
+		},
+		{
+			name: "skill",
+			incr: Character.IncrNeededSkill,
+			decr: Character.DecrNeededSkill,
+			count: func(c Character, name string) (uint64, bool) {
+				s, err := c.GetNeededSkill(name)
+				if err != nil {
+					return 0, false
+				}
+				return s.Points(), true
+			},
+		},
+		{
+			name: "transmute",
+			incr: Character.IncrNeededTransmute,
+			decr: Character.DecrNeededTransmute,
+			count: func(c Character, name string) (uint64, bool) {
+				tr, err := c.GetNeededTransmute(name)
+				if err != nil {
+					return 0, false
+				}
+				return tr.Count(), true
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		need      uint64 // 0 means the character does not have the need
+		decrName  string
+		amt       uint64
+		want      DecrResult
+		wantCount uint64
+		wantKept  bool
+	}{
+		{name: "exact", need: 3, decrName: "Iron Ore", amt: 3, want: DecrResult{Found: true, Removed: true}},
+		{name: "over", need: 3, decrName: "Iron Ore", amt: 5, want: DecrResult{Found: true, Removed: true, Overflow: 2}},
+		{name: "partial", need: 3, decrName: "Iron Ore", amt: 1, want: DecrResult{Found: true, Remaining: 2}, wantCount: 2, wantKept: true},
+		{name: "missing", need: 3, decrName: "Rubedite", amt: 4, want: DecrResult{Overflow: 4}, wantCount: 3, wantKept: true},
+		{name: "zero", need: 3, decrName: "Iron Ore", amt: 0, want: DecrResult{Found: true, Remaining: 3}, wantCount: 3, wantKept: true},
+		{name: "missing zero", decrName: "Iron Ore", amt: 0, want: DecrResult{}},
+	}
+
+	for _, k := range kinds {
+		for _, tt := range tests {
+			t.Run(k.name+"/"+tt.name, func(t *testing.T) {
+				c := &boltCharacter{protoCharacter: &ProtoCharacter{Name: "Bob"}}
+				if tt.need > 0 {
+					k.incr(c, "Iron Ore", tt.need)
+				}
+
+				if got := k.decr(c, tt.decrName, tt.amt); got != tt.want {
+					t.Errorf("decr(%q, %d) = %+v, want %+v", tt.decrName, tt.amt, got, tt.want)
+				}
+
+				ct, kept := k.count(c, "Iron Ore")
+				if kept != tt.wantKept || ct != tt.wantCount {
+					t.Errorf("need left = %d (kept %v), want %d (kept %v)", ct, kept, tt.wantCount, tt.wantKept)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/metrics"
+)
+
+// openWriters counts the write transactions begun (or waiting to begin) in
+// this process; bolt allows only one at a time, so any more have to wait
+var openWriters int32
+
+// txTimer reports a transaction's duration to the metrics when it is
+// committed or rolled back
+type txTimer struct {
+	api      string
+	writable bool
+	start    time.Time
+	done     bool
+}
+
+// beginTx starts a bolt transaction for the named api, timing it for the metrics
+func beginTx(db *bolt.DB, api string, writable bool) (*bolt.Tx, *txTimer, error) {
+	if writable && atomic.AddInt32(&openWriters, 1) > 1 {
+		metrics.StorageTxConflict(api)
+	}
+
+	tx, err := db.Begin(writable)
+	if err != nil {
+		if writable {
+			atomic.AddInt32(&openWriters, -1)
+		}
+		return nil, nil, err
+	}
+
+	return tx, &txTimer{api: api, writable: writable, start: time.Now()}, nil
+}
+
+// finish records the transaction's duration the first time it is called; a
+// nil timer (for a transaction shared from another api) does nothing
+func (t *txTimer) finish(result string) {
+	if t == nil || t.done {
+		return
+	}
+	t.done = true
+
+	if t.writable {
+		atomic.AddInt32(&openWriters, -1)
+	}
+	metrics.StorageTx(t.api, t.writable, result, time.Since(t.start))
+}
+
+// commitTx commits tx and records it with the timer
+func commitTx(tx *bolt.Tx, t *txTimer) error {
+	err := tx.Commit()
+	if err != nil {
+		t.finish(metrics.TxError)
+	} else {
+		t.finish(metrics.TxCommit)
+	}
+	return err
+}
+
+// rollbackTx rolls tx back (if it is still open) and records it with the timer
+func rollbackTx(tx *bolt.Tx, t *txTimer) error {
+	err := tx.Rollback()
+	t.finish(metrics.TxRollback)
+	if err != nil && err != bolt.ErrTxClosed {
+		return err
+	}
+	return nil
+}
@@ -1,70 +1,128 @@
-package storage
-
-//go:generate protoc --go_out=. --proto_path=. ./userapi.proto
-
-// UserAPI is the api for managing users transactions
-type UserAPI interface {
-	NewTransaction(writable bool) (UserAPITx, error)
-}
-
-// UserAPITx is the api for managing users within a transaction
-type UserAPITx interface {
-	Commit() error
-	Rollback() error
-
-	GetUser(name string) (User, error)
-	GetUsers() []User
-
-	AddUser(name string) (User, error)
-	SaveUser(user User) error
-}
-
-// User is the api for managing a particular user
-type User interface {
-	GetName() string
-	GetCharacter(name string) (Character, error)
-	GetCharacters() []Character
-
-	SetName(name string)
-	AddCharacter(name string) Character
-	DeleteCharacter(name string)
-
-	Serialize() ([]byte, error)
-}
-
-// Character is the api for managing a user's particular character
-type Character interface {
-	GetName() string
-	GetNeededSkill(name string) (Skill, error)
-	GetNeededSkills() []Skill
-	GetNeededItem(name string) (Item, error)
-	GetNeededItems() []Item
-	GetNeededTransmute(name string) (Transmute, error)
-	GetNeededTransmutes() []Transmute
-
-	SetName(name string)
-	IncrNeededSkill(name string, amt uint64)
-	DecrNeededSkill(name string, amt uint64)
-	IncrNeededItem(name string, amt uint64)
-	DecrNeededItem(name string, amt uint64)
-	IncrNeededTransmute(name string, amt uint64)
-	DecrNeededTransmute(name string, amt uint64)
-}
-
-// Skill is the api for managing a character's skill entry
-type Skill interface {
-	Name() string
-	Points() uint64
-}
-
-// Item is the api for managing a character's item entry
-type Item interface {
-	Name() string
-	Count() uint64
-}
-
-// Transmute is the api for managing a character's transmute entry
-type Transmute interface {
-	Name() string
-	Count() uint64
-}
+package storage
+
+//go:generate protoc --go_out=. --proto_path=. ./userapi.proto
+
+import (
+	"time"
+)
+
+// Valid privacy settings; an empty setting means PrivacyGuild. PrivacyCharacter
+// is only valid for users, and defers to each character's own setting
+const (
+	PrivacyPublic    = "public"
+	PrivacyGuild     = "guild"
+	PrivacyPrivate   = "private"
+	PrivacyCharacter = "character"
+)
+
+// Valid user notification modes; an empty mode means NotifyOff
+const (
+	NotifyOff     = "off"
+	NotifyDM      = "dm"
+	NotifyChannel = "channel"
+)
+
+// UserAPI is the api for managing users transactions
+type UserAPI interface {
+	NewTransaction(writable bool) (UserAPITx, error)
+}
+
+// UserAPITx is the api for managing users within a transaction
+type UserAPITx interface {
+	Commit() error
+	Rollback() error
+
+	GetUser(name string) (User, error)
+	GetUsers() []User
+
+	AddUser(name string) (User, error)
+	SaveUser(user User) error
+}
+
+// User is the api for managing a particular user
+type User interface {
+	GetName() string
+	GetCharacter(name string) (Character, error)
+	GetCharacters() []Character
+	GetGuilds() []string
+	InGuild(guild string) bool
+	GuildsRecorded() bool
+	GetNotifyMode() string
+	GetLastNotified(item string) time.Time
+	GetPrivacy() string
+	GetDMReplies() bool
+
+	SetName(name string)
+	AddGuild(guild string)
+	RemoveGuild(guild string)
+	SetNotifyMode(mode string)
+	SetLastNotified(item string, at time.Time)
+	SetPrivacy(privacy string)
+	SetDMReplies(dm bool)
+	AddCharacter(name string) Character
+	DeleteCharacter(name string)
+
+	Serialize() ([]byte, error)
+}
+
+// Character is the api for managing a user's particular character
+type Character interface {
+	GetName() string
+	GetNeededSkill(name string) (Skill, error)
+	GetNeededSkills() []Skill
+	GetNeededItem(name string) (Item, error)
+	GetNeededItems() []Item
+	GetNeededTransmute(name string) (Transmute, error)
+	GetNeededTransmutes() []Transmute
+	GetSurplusItem(name string) (Item, error)
+	GetSurplusItems() []Item
+	GetPrivacy() string
+
+	SetName(name string)
+	SetPrivacy(privacy string)
+	IncrNeededSkill(name string, amt uint64)
+	DecrNeededSkill(name string, amt uint64) DecrResult
+	IncrNeededItem(name string, amt uint64)
+	DecrNeededItem(name string, amt uint64) DecrResult
+	IncrNeededTransmute(name string, amt uint64)
+	DecrNeededTransmute(name string, amt uint64) DecrResult
+	IncrSurplusItem(name string, amt uint64)
+	DecrSurplusItem(name string, amt uint64)
+	SetNeededSkill(name string, amt uint64)
+	SetNeededItem(name string, amt uint64)
+	SetNeededTransmute(name string, amt uint64)
+	ClearNeededSkills()
+	ClearNeededItems()
+	ClearNeededTransmutes()
+}
+
+// DecrResult is the outcome of reducing a character's need by some amount
+type DecrResult struct {
+	// Found is whether the character had the need at all
+	Found bool
+	// Removed is whether the need was fully met and so removed
+	Removed bool
+	// Remaining is how much is still needed
+	Remaining uint64
+	// Overflow is how much more than was needed was taken off (all of it, when
+	// the need was not found)
+	Overflow uint64
+}
+
+// Skill is the api for managing a character's skill entry
+type Skill interface {
+	Name() string
+	Points() uint64
+}
+
+// Item is the api for managing a character's item entry
+type Item interface {
+	Name() string
+	Count() uint64
+}
+
+// Transmute is the api for managing a character's transmute entry
+type Transmute interface {
+	Name() string
+	Count() uint64
+}
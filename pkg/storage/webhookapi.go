@@ -0,0 +1,77 @@
+package storage
+
+//go:generate protoc --go_out=. --proto_path=. ./webhookapi.proto
+
+import (
+	"time"
+)
+
+// Webhook is a guild's subscription of a url to some kinds of event
+type Webhook struct {
+	ID      uint64
+	URL     string
+	Secret  string
+	Events  []string
+	Created time.Time
+	Creator string
+}
+
+// Wants reports whether the webhook is subscribed to the event
+func (w Webhook) Wants(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event payload waiting to be sent to a webhook
+type WebhookDelivery struct {
+	ID          uint64
+	Guild       string
+	HookID      uint64
+	Event       string
+	Payload     []byte
+	Attempts    int
+	NextAttempt time.Time
+	Created     time.Time
+	LastError   string
+}
+
+// WebhookAPI is the api for managing webhook transactions
+type WebhookAPI interface {
+	NewTransaction(writable bool) (WebhookAPITx, error)
+}
+
+// WebhookAPITx is the api for managing guild webhooks and the delivery queue
+// within a transaction
+type WebhookAPITx interface {
+	Commit() error
+	Rollback() error
+
+	GetWebhooks(guild string) (Webhooks, error)
+	AddWebhooks(guild string) (Webhooks, error)
+	SaveWebhooks(hooks Webhooks) error
+
+	// Enqueue stores a new delivery, setting its ID
+	Enqueue(d *WebhookDelivery) error
+	// DueDeliveries returns up to limit deliveries whose next attempt is not
+	// after now, oldest first
+	DueDeliveries(now time.Time, limit int) ([]WebhookDelivery, error)
+	SaveDelivery(d WebhookDelivery) error
+	DeleteDelivery(id uint64) error
+	QueueLength() int
+}
+
+// Webhooks is the api for managing a particular guild's webhooks
+type Webhooks interface {
+	GetGuild() string
+	GetWebhooks() []Webhook
+	GetWebhook(id uint64) (Webhook, error)
+
+	AddWebhook(url, secret string, events []string, creator string, at time.Time) Webhook
+	RemoveWebhook(id uint64) error
+
+	Serialize() ([]byte, error)
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// guildLogLimit is how many bank ledger entries and loot awards the guild page shows
+const guildLogLimit = 50
+
+type guildView struct {
+	ID         string
+	Members    int
+	Characters int
+
+	Items      []countView
+	Skills     []countView
+	Transmutes []countView
+
+	Bank      []countView
+	BankTotal uint64
+	Ledger    []storage.LedgerEntry
+
+	Points []pointsView
+	Awards []storage.LootAward
+}
+
+type pointsView struct {
+	User   string
+	Points int64
+}
+
+// guildPage shows officers what the guild's members need altogether, the bank
+// and the loot history. Officer access is checked when the login link is made,
+// so it lasts for the session.
+func (d *dashboard) guildPage(w http.ResponseWriter, l login) {
+	if !l.officer || l.guildID == "" {
+		d.render(w, http.StatusForbidden, "message", pageData{
+			Login:   &l,
+			Title:   "Officers only",
+			Message: "Send the bot `!web` from the guild as an officer to see this page.",
+		})
+		return
+	}
+
+	g := &guildView{ID: l.guildID}
+
+	if err := d.guildNeeds(g); err != nil {
+		d.serverError(w, l, err)
+		return
+	}
+
+	if err := d.guildBank(g); err != nil {
+		d.serverError(w, l, err)
+		return
+	}
+
+	if err := d.guildLoot(g); err != nil {
+		d.serverError(w, l, err)
+		return
+	}
+
+	d.render(w, http.StatusOK, "guild", pageData{Login: &l, Title: "Guild", Guild: g})
+}
+
+// guildNeeds adds up what the guild's members need, leaving out the
+// characters whose privacy settings hide them from the guild
+func (d *dashboard) guildNeeds(g *guildView) error {
+	t, err := d.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	items, skills, trans := map[string]*countView{}, map[string]*countView{}, map[string]*countView{}
+	add := func(counts map[string]*countView, name string, ct uint64) {
+		c, ok := counts[name]
+		if !ok {
+			c = &countView{Name: name}
+			counts[name] = c
+		}
+		c.Count += ct
+		c.Chars++
+	}
+
+	for _, u := range t.GetUsers() {
+		if !u.InGuild(g.ID) {
+			continue
+		}
+		g.Members++
+
+		for _, c := range u.GetCharacters() {
+			if !storage.CanViewCharacter(u, c, g.ID) {
+				continue
+			}
+			g.Characters++
+
+			for _, i := range c.GetNeededItems() {
+				add(items, i.Name(), i.Count())
+			}
+			for _, s := range c.GetNeededSkills() {
+				add(skills, s.Name(), s.Points())
+			}
+			for _, tr := range c.GetNeededTransmutes() {
+				add(trans, tr.Name(), tr.Count())
+			}
+		}
+	}
+
+	g.Items, g.Skills, g.Transmutes = mostNeeded(items), mostNeeded(skills), mostNeeded(trans)
+	return nil
+}
+
+// mostNeeded lists the counts, largest first
+func mostNeeded(counts map[string]*countView) []countView {
+	cs := make([]countView, 0, len(counts))
+	for _, c := range counts {
+		cs = append(cs, *c)
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Count != cs[j].Count {
+			return cs[i].Count > cs[j].Count
+		}
+		return strings.ToLower(cs[i].Name) < strings.ToLower(cs[j].Name)
+	})
+	return cs
+}
+
+func (d *dashboard) guildBank(g *guildView) error {
+	t, err := d.deps.BankAPI().NewTransaction(false)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bank, err := t.AddBank(g.ID) // add or get empty (don't save)
+	if err != nil {
+		return errors.Wrap(err, "unable to find bank")
+	}
+
+	for _, i := range bank.GetItems() {
+		g.Bank = append(g.Bank, countView{Name: i.Name(), Count: i.Count()})
+		g.BankTotal += i.Count()
+	}
+	sortCounts(g.Bank)
+
+	g.Ledger, err = t.GetLedger(g.ID, guildLogLimit)
+	return errors.Wrap(err, "unable to read bank ledger")
+}
+
+func (d *dashboard) guildLoot(g *guildView) error {
+	t, err := d.deps.LootAPI().NewTransaction(false)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	table, err := t.AddLootTable(g.ID) // add or get empty (don't save)
+	if err != nil {
+		return errors.Wrap(err, "unable to find loot table")
+	}
+
+	for _, user := range table.GetMembers() {
+		g.Points = append(g.Points, pointsView{User: user, Points: table.GetPoints(user)})
+	}
+	sort.SliceStable(g.Points, func(i, j int) bool { return g.Points[i].Points > g.Points[j].Points })
+
+	g.Awards, err = t.GetAwards(g.ID, guildLogLimit)
+	return errors.Wrap(err, "unable to read loot history")
+}
@@ -0,0 +1,283 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// formError is a problem with what was submitted, shown back to the user
+type formError string
+
+func (e formError) Error() string { return string(e) }
+
+func badForm(format string, args ...interface{}) error {
+	return formError(fmt.Sprintf(format, args...))
+}
+
+type characterView struct {
+	Name       string
+	Privacy    string
+	Items      []countView
+	Skills     []countView
+	Transmutes []countView
+}
+
+type countView struct {
+	Name  string
+	Count uint64
+	Chars int
+}
+
+func newCharacterView(c storage.Character) characterView {
+	cv := characterView{Name: c.GetName(), Privacy: c.GetPrivacy()}
+
+	for _, i := range c.GetNeededItems() {
+		cv.Items = append(cv.Items, countView{Name: i.Name(), Count: i.Count()})
+	}
+	for _, s := range c.GetNeededSkills() {
+		cv.Skills = append(cv.Skills, countView{Name: s.Name(), Count: s.Points()})
+	}
+	for _, t := range c.GetNeededTransmutes() {
+		cv.Transmutes = append(cv.Transmutes, countView{Name: t.Name(), Count: t.Count()})
+	}
+
+	sortCounts(cv.Items)
+	sortCounts(cv.Skills)
+	sortCounts(cv.Transmutes)
+
+	return cv
+}
+
+func sortCounts(cs []countView) {
+	sort.Slice(cs, func(i, j int) bool { return strings.ToLower(cs[i].Name) < strings.ToLower(cs[j].Name) })
+}
+
+// memberPage shows the logged in user's characters and their needs
+func (d *dashboard) memberPage(w http.ResponseWriter, l login, formErr string) {
+	t, err := d.deps.UserAPI().NewTransaction(false)
+	if err != nil {
+		d.serverError(w, l, err)
+		return
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(l.userID) // add or get empty (don't save)
+	if err != nil {
+		d.serverError(w, l, err)
+		return
+	}
+
+	data := pageData{Login: &l, Title: "Your characters", Error: formErr}
+	for _, c := range bUser.GetCharacters() {
+		data.Characters = append(data.Characters, newCharacterView(c))
+	}
+	sort.Slice(data.Characters, func(i, j int) bool {
+		return strings.ToLower(data.Characters[i].Name) < strings.ToLower(data.Characters[j].Name)
+	})
+
+	status := http.StatusOK
+	if formErr != "" {
+		status = http.StatusBadRequest
+	}
+	d.render(w, status, "member", data)
+}
+
+// memberAction runs a form submission, showing the member page again with the
+// problem if there was one, or redirecting back to it if not
+func (d *dashboard) memberAction(w http.ResponseWriter, r *http.Request, l login, f func(r *http.Request, l login) error) {
+	err := f(r, l)
+
+	switch e := errors.Cause(err).(type) {
+	case nil:
+		http.Redirect(w, r, prefix, http.StatusSeeOther)
+	case formError:
+		d.memberPage(w, l, e.Error())
+	default:
+		if e == storage.ErrCharacterNotExist {
+			d.memberPage(w, l, "You do not have a character with that name.")
+			return
+		}
+		d.serverError(w, l, err)
+	}
+}
+
+func (d *dashboard) serverError(w http.ResponseWriter, l login, err error) {
+	_ = level.Error(d.deps.Logger()).Log("message", "error handling dashboard request", "user_id", l.userID, "err", err)
+	d.render(w, http.StatusInternalServerError, "message", pageData{Login: &l, Title: "Something went wrong", Message: "Please try again later."})
+}
+
+// createCharacter adds a character, like `!char create`
+func (d *dashboard) createCharacter(r *http.Request, l login) error {
+	charName := strings.TrimSpace(r.PostFormValue("name"))
+	if charName == "" {
+		return badForm("The character needs a name.")
+	}
+
+	t, err := d.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.AddUser(l.userID) // add or get
+	if err != nil {
+		return errors.Wrap(err, "could not create character")
+	}
+
+	_, err = bUser.GetCharacter(charName)
+	if err != storage.ErrCharacterNotExist {
+		if err != nil {
+			return errors.Wrap(err, "could not verify character does not exist")
+		}
+
+		return badForm("You already have a character named %s.", charName)
+	}
+
+	char := bUser.AddCharacter(charName)
+	if l.guildID != "" {
+		bUser.AddGuild(l.guildID)
+	}
+
+	if err = t.SaveUser(bUser); err != nil {
+		return errors.Wrap(err, "could not save new character")
+	}
+
+	if err = t.Commit(); err != nil {
+		return errors.Wrap(err, "could not save new character")
+	}
+	d.publish(l, bUser, char, webhook.EventCharacterCreated, webhook.CharacterData{User: bUser.GetName(), Character: char.GetName()})
+
+	return nil
+}
+
+// deleteCharacter removes a character, like `!char delete`
+func (d *dashboard) deleteCharacter(r *http.Request, l login) error {
+	t, err := d.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(l.userID)
+	if err == storage.ErrUserNotExist {
+		return storage.ErrCharacterNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	char, err := bUser.GetCharacter(r.PostFormValue("character"))
+	if err != nil {
+		return err
+	}
+
+	bUser.DeleteCharacter(char.GetName())
+	if err = t.SaveUser(bUser); err != nil {
+		return errors.Wrap(err, "could not delete character")
+	}
+
+	return errors.Wrap(t.Commit(), "could not delete character")
+}
+
+// setNeed sets how many of an item, skill point or transmute a character
+// needs; a count of 0 removes the need
+func (d *dashboard) setNeed(r *http.Request, l login) error {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	if name == "" {
+		return badForm("What is needed needs a name.")
+	}
+
+	count, err := strconv.ParseUint(strings.TrimSpace(r.PostFormValue("count")), 10, 64)
+	if err != nil {
+		return badForm("The count must be a whole number, 0 or more.")
+	}
+
+	t, err := d.deps.UserAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	bUser, err := t.GetUser(l.userID)
+	if err == storage.ErrUserNotExist {
+		return storage.ErrCharacterNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	char, err := bUser.GetCharacter(r.PostFormValue("character"))
+	if err != nil {
+		return err
+	}
+
+	var kind string
+	var before uint64
+	switch r.PostFormValue("kind") {
+	case "items":
+		kind = "item"
+		if item, err := char.GetNeededItem(name); err == nil {
+			before = item.Count()
+		}
+		char.SetNeededItem(name, count)
+	case "skills":
+		kind = "skill"
+		if skill, err := char.GetNeededSkill(name); err == nil {
+			before = skill.Points()
+		}
+		char.SetNeededSkill(name, count)
+	case "transmutes":
+		kind = "transmute"
+		if trans, err := char.GetNeededTransmute(name); err == nil {
+			before = trans.Count()
+		}
+		char.SetNeededTransmute(name, count)
+	default:
+		return badForm("Pick items, skill points or transmutes.")
+	}
+
+	if err = t.SaveUser(bUser); err != nil {
+		return errors.Wrap(err, "could not save needs")
+	}
+
+	if err = t.Commit(); err != nil {
+		return errors.Wrap(err, "could not save needs")
+	}
+
+	if count > before {
+		d.publish(l, bUser, char, webhook.EventNeedAdded, webhook.NeedData{
+			User:      bUser.GetName(),
+			Character: char.GetName(),
+			Kind:      kind,
+			Name:      name,
+			Count:     count - before,
+		})
+	}
+
+	return nil
+}
+
+// publish sends a webhook event about a character to the guild the user
+// logged in from, unless the character is hidden from it
+func (d *dashboard) publish(l login, bUser storage.User, char storage.Character, event string, data interface{}) {
+	if l.guildID == "" || !storage.CanViewCharacter(bUser, char, l.guildID) {
+		return
+	}
+
+	gid, err := snowflake.FromString(l.guildID)
+	if err != nil {
+		return
+	}
+	d.deps.Webhooks().Publish(gid, event, data)
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// login is who a login link or session is for
+type login struct {
+	userID  string
+	guildID string
+	officer bool
+
+	csrf    string
+	expires time.Time
+}
+
+// sessionStore keeps the unused login links and the logged in sessions. They
+// are only kept in memory, so a restart logs everyone out.
+type sessionStore struct {
+	mu       sync.Mutex
+	links    map[string]login
+	sessions map[string]login
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		links:    map[string]login{},
+		sessions: map[string]login{},
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// prune forgets the expired links and sessions; the caller must hold the lock
+func (s *sessionStore) prune(now time.Time) {
+	for k, l := range s.links {
+		if now.After(l.expires) {
+			delete(s.links, k)
+		}
+	}
+	for k, l := range s.sessions {
+		if now.After(l.expires) {
+			delete(s.sessions, k)
+		}
+	}
+}
+
+// addLink creates a login link token for l, valid for ttl
+func (s *sessionStore) addLink(l login, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	l.expires = now.Add(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+	s.links[token] = l
+	return token, nil
+}
+
+// linkValid reports whether a login link token can still be redeemed
+func (s *sessionStore) linkValid(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[token]
+	return ok && time.Now().Before(l.expires)
+}
+
+// redeem uses up a login link token, starting a session valid for ttl
+func (s *sessionStore) redeem(token string, ttl time.Duration) (string, bool) {
+	session, err := newToken()
+	if err != nil {
+		return "", false
+	}
+	csrf, err := newToken()
+	if err != nil {
+		return "", false
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[token]
+	delete(s.links, token)
+	if !ok || now.After(l.expires) {
+		return "", false
+	}
+
+	l.csrf = csrf
+	l.expires = now.Add(ttl)
+	s.sessions[session] = l
+	return session, true
+}
+
+func (s *sessionStore) get(session string) (login, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.sessions[session]
+	if !ok || time.Now().After(l.expires) {
+		return login{}, false
+	}
+	return l, true
+}
+
+func (s *sessionStore) remove(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, session)
+}
+
+// csrfOK checks the token a form was submitted with against the session's
+func (l login) csrfOK(token string) bool {
+	return l.csrf != "" && subtle.ConstantTimeCompare([]byte(l.csrf), []byte(token)) == 1
+}
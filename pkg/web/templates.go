@@ -0,0 +1,138 @@
+package web
+
+// pageTemplates are the dashboard pages; each page template renders the
+// whole document
+const pageTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - have-want-bot</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 60em; padding: 0 1em 2em; color: #222; }
+nav { display: flex; gap: 1em; align-items: center; border-bottom: 1px solid #ccc; padding: .5em 0; margin-bottom: 1em; }
+nav form { margin-left: auto; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { text-align: left; padding: .2em .6em; border-bottom: 1px solid #eee; }
+td.num { text-align: right; }
+section { border: 1px solid #ddd; border-radius: 4px; padding: 0 1em 1em; margin-bottom: 1em; }
+.error { background: #fdd; border: 1px solid #f99; padding: .5em 1em; }
+form.inline { display: inline; }
+input[type=number] { width: 5em; }
+</style>
+</head>
+<body>
+{{if .Login}}<nav>
+<a href="/web/">Your characters</a>
+{{if .Officer}}<a href="/web/guild">Guild</a>{{end}}
+<form method="post" action="/web/logout"><input type="hidden" name="csrf" value="{{.CSRF}}"><button>Log out</button></form>
+</nav>{{end}}
+<h1>{{.Title}}</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "message"}}{{template "header" .}}
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "login"}}{{template "header" .}}
+<form method="post" action="/web/login">
+<input type="hidden" name="token" value="{{.Token}}">
+<button>Log in to have-want-bot</button>
+</form>
+{{template "footer" .}}{{end}}
+
+{{define "needs"}}{{$csrf := .CSRF}}{{$char := .Char}}{{$kind := .Kind}}
+{{if .Counts}}<table>
+<tr><th>{{.Heading}}</th><th>Count</th><th></th></tr>
+{{range .Counts}}<tr>
+<td>{{.Name}}</td>
+<td><form class="inline" method="post" action="/web/needs/set">
+<input type="hidden" name="csrf" value="{{$csrf}}">
+<input type="hidden" name="character" value="{{$char}}">
+<input type="hidden" name="kind" value="{{$kind}}">
+<input type="hidden" name="name" value="{{.Name}}">
+<input type="number" name="count" min="0" value="{{.Count}}"> <button>Set</button>
+</form></td>
+<td><form class="inline" method="post" action="/web/needs/set">
+<input type="hidden" name="csrf" value="{{$csrf}}">
+<input type="hidden" name="character" value="{{$char}}">
+<input type="hidden" name="kind" value="{{$kind}}">
+<input type="hidden" name="name" value="{{.Name}}">
+<input type="hidden" name="count" value="0"> <button>Got it</button>
+</form></td>
+</tr>{{end}}
+</table>{{end}}
+{{end}}
+
+{{define "member"}}{{template "header" .}}{{$csrf := .CSRF}}
+{{range .Characters}}{{$char := .Name}}<section>
+<h2>{{.Name}}</h2>
+{{template "needs" (needs $csrf $char "items" "Items" .Items)}}
+{{template "needs" (needs $csrf $char "skills" "Skill points" .Skills)}}
+{{template "needs" (needs $csrf $char "transmutes" "Transmutes" .Transmutes)}}
+{{if not (or .Items .Skills .Transmutes)}}<p>Nothing needed.</p>{{end}}
+<form method="post" action="/web/needs/set">
+<input type="hidden" name="csrf" value="{{$csrf}}">
+<input type="hidden" name="character" value="{{.Name}}">
+Need <input type="number" name="count" min="1" value="1">
+<select name="kind"><option value="items">items</option><option value="skills">skill points</option><option value="transmutes">transmutes</option></select>
+of <input name="name" required placeholder="name">
+<button>Add</button>
+</form>
+<form method="post" action="/web/characters/delete" onsubmit="return confirm('Delete {{.Name}}?')">
+<input type="hidden" name="csrf" value="{{$csrf}}">
+<input type="hidden" name="character" value="{{.Name}}">
+<p><button>Delete {{.Name}}</button></p>
+</form>
+</section>{{else}}<p>You have no characters yet.</p>{{end}}
+<section>
+<h2>New character</h2>
+<form method="post" action="/web/characters/create">
+<input type="hidden" name="csrf" value="{{$csrf}}">
+<input name="name" required placeholder="character name"> <button>Create</button>
+</form>
+</section>
+{{template "footer" .}}{{end}}
+
+{{define "counts"}}<table>
+<tr><th>Name</th><th>Count</th><th>Characters</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{.Chars}}</td></tr>{{else}}<tr><td colspan="3">Nothing needed.</td></tr>{{end}}
+</table>{{end}}
+
+{{define "guild"}}{{template "header" .}}{{with .Guild}}
+<p>{{.Members}} members with {{.Characters}} visible characters.</p>
+<section><h2>Needed items</h2>{{template "counts" .Items}}</section>
+<section><h2>Needed skill points</h2>{{template "counts" .Skills}}</section>
+<section><h2>Needed transmutes</h2>{{template "counts" .Transmutes}}</section>
+<section>
+<h2>Bank ({{.BankTotal}} items)</h2>
+<table>
+<tr><th>Item</th><th>Count</th></tr>
+{{range .Bank}}<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td></tr>{{else}}<tr><td colspan="2">The bank is empty.</td></tr>{{end}}
+</table>
+<h3>Ledger</h3>
+<table>
+<tr><th>When (UTC)</th><th>What</th><th>Item</th><th>Count</th><th>By</th><th>To</th></tr>
+{{range .Ledger}}<tr><td>{{.Time.UTC.Format "2006-01-02 15:04"}}</td><td>{{.Kind}}</td><td>{{.Item}}</td><td class="num">{{.Count}}</td><td>{{.Actor}}</td><td>{{if .User}}{{.User}} ({{.Character}}){{end}}</td></tr>{{else}}<tr><td colspan="6">No entries.</td></tr>{{end}}
+</table>
+</section>
+<section>
+<h2>Loot points</h2>
+<table>
+<tr><th>Member</th><th>Points</th></tr>
+{{range .Points}}<tr><td>{{.User}}</td><td class="num">{{.Points}}</td></tr>{{else}}<tr><td colspan="2">No members.</td></tr>{{end}}
+</table>
+<h3>History</h3>
+<table>
+<tr><th>When (UTC)</th><th>Item</th><th>To</th><th>Cost</th><th>By</th></tr>
+{{range .Awards}}<tr><td>{{.Time.UTC.Format "2006-01-02 15:04"}}</td><td>{{.Item}}</td><td>{{.User}} ({{.Character}})</td><td class="num">{{.Cost}}</td><td>{{.Awarder}}</td></tr>{{else}}<tr><td colspan="5">No awards.</td></tr>{{end}}
+</table>
+</section>
+{{end}}{{template "footer" .}}{{end}}
+`
@@ -0,0 +1,256 @@
+package web
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/webhook"
+)
+
+// prefix is where the dashboard is served
+const prefix = "/web/"
+
+const sessionCookie = "hw_session"
+
+type dependencies interface {
+	Logger() log.Logger
+	UserAPI() storage.UserAPI
+	BankAPI() storage.BankAPI
+	LootAPI() storage.LootAPI
+	Webhooks() webhook.Dispatcher
+}
+
+// Dashboard is the web ui: members view and edit their characters and needs,
+// and officers see what the guild needs, the bank and the loot history
+type Dashboard interface {
+	http.Handler
+
+	// LoginLink creates a one-time link that logs the user in to the
+	// dashboard; officer should only be set if they are an officer of the guild
+	LoginLink(userID, guildID string, officer bool) (string, error)
+}
+
+// Options is how to set where the dashboard is reached and how long logins last
+type Options struct {
+	// BaseURL is the address the dashboard is reached at from outside, for the login links
+	BaseURL    string
+	LinkTTL    time.Duration
+	SessionTTL time.Duration
+}
+
+type dashboard struct {
+	deps     dependencies
+	baseURL  string
+	secure   bool
+	linkTTL  time.Duration
+	sessTTL  time.Duration
+	sessions *sessionStore
+}
+
+// NewDashboard creates a new Dashboard
+func NewDashboard(deps dependencies, opts Options) (Dashboard, error) {
+	u, err := url.Parse(opts.BaseURL)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("bad dashboard url %q", opts.BaseURL)
+	}
+
+	d := &dashboard{
+		deps:     deps,
+		baseURL:  strings.TrimSuffix(opts.BaseURL, "/"),
+		secure:   u.Scheme == "https",
+		linkTTL:  opts.LinkTTL,
+		sessTTL:  opts.SessionTTL,
+		sessions: newSessionStore(),
+	}
+
+	if d.linkTTL <= 0 {
+		d.linkTTL = 15 * time.Minute
+	}
+	if d.sessTTL <= 0 {
+		d.sessTTL = 12 * time.Hour
+	}
+
+	return d, nil
+}
+
+func (d *dashboard) LoginLink(userID, guildID string, officer bool) (string, error) {
+	token, err := d.sessions.addLink(login{
+		userID:  userID,
+		guildID: guildID,
+		officer: officer,
+	}, d.linkTTL)
+	if err != nil {
+		return "", errors.Wrap(err, "could not create login link")
+	}
+
+	return d.baseURL + prefix + "login?token=" + url.QueryEscape(token), nil
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Redirect(w, r, prefix, http.StatusFound)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	if path == "login" {
+		d.login(w, r)
+		return
+	}
+
+	l, ok := d.session(r)
+	if !ok {
+		d.render(w, http.StatusUnauthorized, "message", pageData{
+			Title:   "Logged out",
+			Message: "Send the bot `!web` to get a new login link.",
+		})
+		return
+	}
+
+	if r.Method == http.MethodPost && !l.csrfOK(r.PostFormValue("csrf")) {
+		d.render(w, http.StatusForbidden, "message", pageData{Title: "Expired form", Message: "Reload the page and try again."})
+		return
+	}
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		d.memberPage(w, l, "")
+	case path == "characters/create" && r.Method == http.MethodPost:
+		d.memberAction(w, r, l, d.createCharacter)
+	case path == "characters/delete" && r.Method == http.MethodPost:
+		d.memberAction(w, r, l, d.deleteCharacter)
+	case path == "needs/set" && r.Method == http.MethodPost:
+		d.memberAction(w, r, l, d.setNeed)
+	case path == "guild" && r.Method == http.MethodGet:
+		d.guildPage(w, l)
+	case path == "logout" && r.Method == http.MethodPost:
+		d.logout(w, r)
+	default:
+		d.render(w, http.StatusNotFound, "message", pageData{Login: &l, Title: "Not found"})
+	}
+}
+
+// login redeems a login link. The link itself only shows a button, and the
+// token is used up when that is pressed: discord fetches links sent in
+// messages to preview them, which would otherwise use the token.
+func (d *dashboard) login(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+
+	if r.Method != http.MethodPost {
+		if !d.sessions.linkValid(token) {
+			d.render(w, http.StatusUnauthorized, "message", pageData{
+				Title:   "Link expired",
+				Message: "That login link has been used or has expired. Send the bot `!web` to get a new one.",
+			})
+			return
+		}
+
+		d.render(w, http.StatusOK, "login", pageData{Title: "Log in", Token: token})
+		return
+	}
+
+	session, ok := d.sessions.redeem(token, d.sessTTL)
+	if !ok {
+		d.render(w, http.StatusUnauthorized, "message", pageData{
+			Title:   "Link expired",
+			Message: "That login link has been used or has expired. Send the bot `!web` to get a new one.",
+		})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     prefix,
+		MaxAge:   int(d.sessTTL / time.Second),
+		HttpOnly: true,
+		Secure:   d.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, prefix, http.StatusSeeOther)
+}
+
+func (d *dashboard) logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		d.sessions.remove(c.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     prefix,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   d.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	d.render(w, http.StatusOK, "message", pageData{Title: "Logged out", Message: "Send the bot `!web` to log in again."})
+}
+
+func (d *dashboard) session(r *http.Request) (login, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return login{}, false
+	}
+	return d.sessions.get(c.Value)
+}
+
+func (d *dashboard) render(w http.ResponseWriter, status int, name string, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		_ = level.Error(d.deps.Logger()).Log("message", "could not render dashboard page", "page", name, "err", err)
+	}
+}
+
+// pageData is what the page templates are given
+type pageData struct {
+	Login   *login
+	Title   string
+	Message string
+	Error   string
+	Token   string
+
+	Characters []characterView
+	Guild      *guildView
+}
+
+// CSRF is the token the page's forms are submitted with
+func (p pageData) CSRF() string {
+	if p.Login == nil {
+		return ""
+	}
+	return p.Login.csrf
+}
+
+// Officer reports whether to link to the guild page
+func (p pageData) Officer() bool {
+	return p.Login != nil && p.Login.officer && p.Login.guildID != ""
+}
+
+// needsView is one kind of need of a character, with the values its forms need
+type needsView struct {
+	CSRF    string
+	Char    string
+	Kind    string
+	Heading string
+	Counts  []countView
+}
+
+var templates = template.Must(template.New("pages").Funcs(template.FuncMap{
+	"needs": func(csrf, char, kind, heading string, counts []countView) needsView {
+		return needsView{CSRF: csrf, Char: char, Kind: kind, Heading: heading, Counts: counts}
+	},
+}).Parse(pageTemplates))
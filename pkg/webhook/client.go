@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrURLRequired is the error returned when a webhook url is missing or not http(s)
+var ErrURLRequired = errors.New("an http:// or https:// url is required")
+
+// ErrBlockedAddress is the error returned when a webhook url points at an
+// address that is not on the public internet
+var ErrBlockedAddress = errors.New("webhooks can only be sent to public internet addresses")
+
+// blockedNets are the address ranges webhooks may not be sent to: loopback,
+// private, shared, link-local (including cloud metadata services), and the
+// other special-purpose ranges
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// publicIP reports whether ip is an address webhooks may be sent to
+func publicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL reports whether raw is a url webhooks may be sent to. Host names
+// are checked again when each request connects, since what they resolve to
+// can change.
+func CheckURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrURLRequired
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, ErrBlockedAddress
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return nil, ErrBlockedAddress
+	}
+
+	return u, nil
+}
+
+// RedactURL shortens a webhook url to its scheme and host, since the rest
+// often carries a token, for showing where others can see it
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+
+	if (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	}
+	return fmt.Sprintf("%s://%s/…", u.Scheme, u.Host)
+}
+
+// checkDial refuses connections to non-public addresses; it runs after the
+// host name is resolved, so it also covers names that resolve to them
+func checkDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return errors.Wrap(ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// newClient creates the http client for delivering webhooks. It connects only
+// to public addresses, ignores proxy settings (which would hide the address),
+// and does not follow redirects.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: checkDial,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,468 @@
+// Package webhook sends guild events to the urls a guild has subscribed to
+// them with `config-hw webhook add`.
+//
+// Each event is a JSON payload POSTed to the url with these headers:
+//
+//	X-HaveWant-Event:     the event name, like need.added
+//	X-HaveWant-Delivery:  an id for the delivery, the same on each retry
+//	X-HaveWant-Timestamp: unix seconds when the request was signed
+//	X-HaveWant-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// keyed with the webhook's secret. Events are queued in the database, so they
+// survive restarts, and failed deliveries are retried with exponential
+// backoff until they succeed or run out of attempts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gsmcwhirter/discord-bot-lib/snowflake"
+	"github.com/gsmcwhirter/go-util/deferutil"
+	"github.com/pkg/errors"
+
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/metrics"
+	"github.com/gsmcwhirter/discord-have-want-bot/pkg/storage"
+)
+
+// The events a webhook can subscribe to
+const (
+	EventNeedAdded        = "need.added"
+	EventNeedFulfilled    = "need.fulfilled"
+	EventCharacterCreated = "character.created"
+	EventTradeCompleted   = "trade.completed"
+)
+
+// Events lists every event a webhook can subscribe to
+func Events() []string {
+	return []string{EventNeedAdded, EventNeedFulfilled, EventCharacterCreated, EventTradeCompleted}
+}
+
+// IsEvent reports whether name is an event a webhook can subscribe to
+func IsEvent(name string) bool {
+	for _, e := range Events() {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedData is the data of need.added and need.fulfilled events. Count is how
+// much was added, or how much was received to fulfill the need.
+type NeedData struct {
+	User      string `json:"user"`
+	Character string `json:"character"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Count     uint64 `json:"count"`
+}
+
+// CharacterData is the data of character.created events
+type CharacterData struct {
+	User      string `json:"user"`
+	Character string `json:"character"`
+}
+
+// TradeData is the data of trade.completed events: From gave Count of Item
+// to Character, with Actor carrying out the trade
+type TradeData struct {
+	From      string `json:"from"`
+	Actor     string `json:"actor"`
+	User      string `json:"user"`
+	Character string `json:"character"`
+	Item      string `json:"item"`
+	Count     uint64 `json:"count"`
+}
+
+// payload is the body of every webhook request
+type payload struct {
+	ID    string      `json:"id"`
+	Event string      `json:"event"`
+	Guild string      `json:"guild"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+type dependencies interface {
+	Logger() log.Logger
+	WebhookAPI() storage.WebhookAPI
+}
+
+// Dispatcher queues guild events for their webhooks and delivers them
+type Dispatcher interface {
+	// Publish queues an event for the guild's webhooks subscribed to it; it
+	// should be called after the change it describes has been committed
+	Publish(gid snowflake.Snowflake, event string, data interface{})
+	Run(ctx context.Context) error
+}
+
+// Options is how to configure a Dispatcher
+type Options struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryBase    time.Duration
+	RetryMax     time.Duration
+	BatchSize    int
+}
+
+type dispatcher struct {
+	deps   dependencies
+	opts   Options
+	client *http.Client
+	wake   chan struct{}
+}
+
+// NewDispatcher creates a new Dispatcher; events are queued by Publish and
+// delivered by Run
+func NewDispatcher(deps dependencies, opts Options) Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = 30 * time.Second
+	}
+	if opts.RetryMax <= 0 {
+		opts.RetryMax = 6 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+
+	return &dispatcher{
+		deps:   deps,
+		opts:   opts,
+		client: newClient(opts.Timeout),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// NewSecret makes a random secret for signing a webhook's requests
+func NewSecret() (string, error) {
+	return randomHex(32)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "could not generate random bytes")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign computes the X-HaveWant-Signature header of a request body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *dispatcher) Publish(gid snowflake.Snowflake, event string, data interface{}) {
+	if gid == 0 {
+		return
+	}
+
+	logger := log.With(d.deps.Logger(), "guild_id", gid.ToString(), "event", event)
+	if err := d.enqueue(gid.ToString(), event, data); err != nil {
+		_ = level.Error(logger).Log("message", "could not queue webhook event", "err", err)
+		return
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// subscribed returns the guild's webhooks that want the event
+func (d *dispatcher) subscribed(guild, event string) ([]storage.Webhook, error) {
+	t, err := d.deps.WebhookAPI().NewTransaction(false)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	hooks, err := t.GetWebhooks(guild)
+	if err == storage.ErrWebhooksNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []storage.Webhook
+	for _, h := range hooks.GetWebhooks() {
+		if h.Wants(event) {
+			subs = append(subs, h)
+		}
+	}
+	return subs, nil
+}
+
+// enqueue stores a delivery for each webhook subscribed to the event; most
+// guilds have none, so this only takes the write lock when there is one
+func (d *dispatcher) enqueue(guild, event string, data interface{}) error {
+	subs, err := d.subscribed(guild, event)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	body, err := json.Marshal(payload{
+		ID:    id,
+		Event: event,
+		Guild: guild,
+		Time:  now.UTC(),
+		Data:  data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not encode webhook payload")
+	}
+
+	t, err := d.deps.WebhookAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	for _, h := range subs {
+		err = t.Enqueue(&storage.WebhookDelivery{
+			Guild:       guild,
+			HookID:      h.ID,
+			Event:       event,
+			Payload:     body,
+			NextAttempt: now,
+			Created:     now,
+		})
+		if err != nil {
+			return errors.Wrap(err, "could not queue webhook delivery")
+		}
+	}
+
+	return t.Commit()
+}
+
+// Run delivers queued events until the context is cancelled; anything not yet
+// delivered stays queued for the next run
+func (d *dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+type dueDelivery struct {
+	delivery storage.WebhookDelivery
+	hook     storage.Webhook
+	found    bool
+}
+
+// due reads the deliveries ready to be attempted, along with their webhooks
+func (d *dispatcher) due(now time.Time) ([]dueDelivery, error) {
+	t, err := d.deps.WebhookAPI().NewTransaction(false)
+	if err != nil {
+		return nil, err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	deliveries, err := t.DueDeliveries(now, d.opts.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	dues := make([]dueDelivery, 0, len(deliveries))
+	for _, dl := range deliveries {
+		dd := dueDelivery{delivery: dl}
+		if hooks, err := t.GetWebhooks(dl.Guild); err == nil {
+			dd.hook, err = hooks.GetWebhook(dl.HookID)
+			dd.found = err == nil
+		}
+		dues = append(dues, dd)
+	}
+	return dues, nil
+}
+
+func (d *dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		dues, err := d.due(time.Now())
+		if err != nil {
+			_ = level.Error(d.deps.Logger()).Log("message", "could not read webhook queue", "err", err)
+			return
+		}
+
+		for _, dd := range dues {
+			if ctx.Err() != nil {
+				return
+			}
+			d.attempt(ctx, dd)
+		}
+
+		if len(dues) < d.opts.BatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome: delivered (or
+// undeliverable) events leave the queue, and failures are retried later
+func (d *dispatcher) attempt(ctx context.Context, dd dueDelivery) {
+	dl := dd.delivery
+	logger := log.With(d.deps.Logger(), "guild_id", dl.Guild, "webhook_id", dl.HookID, "event", dl.Event, "delivery_id", dl.ID)
+
+	if !dd.found {
+		metrics.WebhookDelivery(metrics.WebhookDropped)
+		_ = level.Info(logger).Log("message", "dropping webhook event for removed webhook")
+		d.finish(logger, dl.ID)
+		return
+	}
+
+	status, err := d.send(ctx, dd.hook, dl)
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		metrics.WebhookDelivery(metrics.WebhookSent)
+		_ = level.Debug(logger).Log("message", "delivered webhook event", "status", status)
+		d.finish(logger, dl.ID)
+		return
+	case ctx.Err() != nil:
+		return // shutting down; try again next run without counting this attempt
+	case err == nil && status == http.StatusGone:
+		metrics.WebhookDelivery(metrics.WebhookDropped)
+		_ = level.Warn(logger).Log("message", "webhook url is gone; dropping event", "url", dd.hook.URL)
+		d.finish(logger, dl.ID)
+		return
+	case err == nil:
+		err = fmt.Errorf("status %d", status)
+	}
+
+	dl.Attempts++
+	dl.LastError = err.Error()
+	if dl.Attempts >= d.opts.MaxAttempts {
+		metrics.WebhookDelivery(metrics.WebhookDropped)
+		_ = level.Warn(logger).Log("message", "webhook event undeliverable; dropping it", "attempts", dl.Attempts, "url", dd.hook.URL, "err", err)
+		d.finish(logger, dl.ID)
+		return
+	}
+
+	metrics.WebhookDelivery(metrics.WebhookRetry)
+	dl.NextAttempt = time.Now().Add(d.backoff(dl.Attempts))
+	_ = level.Info(logger).Log("message", "webhook delivery failed; will retry", "attempts", dl.Attempts, "next_attempt", dl.NextAttempt, "err", err)
+
+	if err := d.save(dl); err != nil {
+		_ = level.Error(logger).Log("message", "could not reschedule webhook delivery", "err", err)
+	}
+}
+
+// backoff is how long to wait after a delivery's nth failed attempt
+func (d *dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.RetryBase
+	for i := 1; i < attempts && wait < d.opts.RetryMax; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.RetryMax {
+		wait = d.opts.RetryMax
+	}
+	return wait
+}
+
+func (d *dispatcher) send(ctx context.Context, hook storage.Webhook, dl storage.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "could not create request")
+	}
+	req = req.WithContext(ctx)
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "have-want-bot webhooks")
+	req.Header.Set("X-HaveWant-Event", dl.Event)
+	req.Header.Set("X-HaveWant-Delivery", strconv.FormatUint(dl.ID, 10))
+	req.Header.Set("X-HaveWant-Timestamp", ts)
+	req.Header.Set("X-HaveWant-Signature", Sign(hook.Secret, ts, dl.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
+
+func (d *dispatcher) finish(logger log.Logger, id uint64) {
+	t, err := d.deps.WebhookAPI().NewTransaction(true)
+	if err == nil {
+		defer deferutil.CheckDefer(t.Rollback)
+		err = t.DeleteDelivery(id)
+		if err == nil {
+			err = t.Commit()
+		}
+	}
+
+	if err != nil {
+		_ = level.Error(logger).Log("message", "could not remove webhook delivery from the queue", "err", err)
+	}
+}
+
+func (d *dispatcher) save(dl storage.WebhookDelivery) error {
+	t, err := d.deps.WebhookAPI().NewTransaction(true)
+	if err != nil {
+		return err
+	}
+	defer deferutil.CheckDefer(t.Rollback)
+
+	if err = t.SaveDelivery(dl); err != nil {
+		return err
+	}
+	return t.Commit()
+}
+
+type nopDispatcher struct{}
+
+// NewNopDispatcher creates a Dispatcher that discards every event
+func NewNopDispatcher() Dispatcher {
+	return nopDispatcher{}
+}
+
+func (nopDispatcher) Publish(snowflake.Snowflake, string, interface{}) {}
+
+func (nopDispatcher) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}